@@ -37,12 +37,8 @@ type Template struct {
 	Packages  map[string]*PackageAlias
 }
 
-// TODO - We really need a convenient Template data structure
-// that lets us easily access elements.
-// t.Resources["MyResource"].Properties["MyProp"]
-//
-// Add a Model attribute to the struct and an Init function to populate it.
-// t.Model.Resources
+// See Model for a typed view of the template sections, which is easier
+// to work with than walking t.Node directly.
 
 // Map returns the template as a map[string]interface{}
 func (t Template) Map() map[string]interface{} {
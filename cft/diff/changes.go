@@ -0,0 +1,111 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+)
+
+// ChangeMode is the kind of change in a Change
+type ChangeMode string
+
+const (
+	// ChangeAdded means the value is new
+	ChangeAdded ChangeMode = "added"
+
+	// ChangeRemoved means the value no longer exists
+	ChangeRemoved ChangeMode = "removed"
+
+	// ChangeChanged means the value was modified
+	ChangeChanged ChangeMode = "changed"
+
+	// ChangeMoved means a resource has a new logical id
+	ChangeMoved ChangeMode = "moved"
+)
+
+// Change is a single difference between two templates
+type Change struct {
+	// Path is a JSON pointer to the value that changed,
+	// for example /Resources/Bucket/Properties/BucketName
+	Path string `json:"path" yaml:"path"`
+
+	Mode ChangeMode `json:"mode" yaml:"mode"`
+
+	// Old is the original value, or nil if the value was added
+	Old interface{} `json:"old" yaml:"old"`
+
+	// New is the new value, or nil if the value was removed
+	New interface{} `json:"new" yaml:"new"`
+
+	// From is the original path of a moved resource
+	From string `json:"from,omitempty" yaml:"from,omitempty"`
+
+	// Replacement is true if the change forces the resource to be
+	// replaced. See MarkReplacements.
+	Replacement bool `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s", c.Mode, c.Path)
+}
+
+// Changes returns a flat list of the changes in a Diff, ordered by path.
+// Unchanged values are not included. A map or list that was added or
+// removed is a single change, rather than one change for each of its elements.
+func Changes(d Diff) []Change {
+	return appendChanges(make([]Change, 0), d, "")
+}
+
+// Pointer returns a JSON pointer (RFC 6901) for a path made of
+// map keys and slice indexes
+func Pointer(path ...interface{}) string {
+	buf := strings.Builder{}
+	for _, p := range path {
+		buf.WriteString("/")
+		switch v := p.(type) {
+		case string:
+			buf.WriteString(escapePointer(v))
+		default:
+			buf.WriteString(fmt.Sprint(v))
+		}
+	}
+	return buf.String()
+}
+
+func escapePointer(s string) string {
+	s = strings.ReplaceAll(s, "~", "~0")
+	return strings.ReplaceAll(s, "/", "~1")
+}
+
+func appendChanges(changes []Change, d Diff, path string) []Change {
+	switch v := d.(type) {
+	case dmap:
+		keys := v.keys()
+		sort.Strings(keys)
+		for _, k := range keys {
+			changes = appendChanges(changes, v[k], path+Pointer(k))
+		}
+	case slice:
+		for i, item := range v {
+			changes = appendChanges(changes, item, path+Pointer(i))
+		}
+	case moved:
+		changes = append(changes, Change{Path: path, Mode: ChangeMoved,
+			From: Pointer(string(cft.Resources), v.from)})
+		changes = appendChanges(changes, v.diff, path)
+	case value:
+		switch v.mode {
+		case Added:
+			changes = append(changes, Change{Path: path, Mode: ChangeAdded, New: v.val})
+		case Removed:
+			changes = append(changes, Change{Path: path, Mode: ChangeRemoved, Old: v.val})
+		case Changed:
+			changes = append(changes, Change{Path: path, Mode: ChangeChanged, Old: v.old, New: v.val})
+		}
+	default:
+		panic(fmt.Errorf("unexpected type '%T'", d))
+	}
+	return changes
+}
@@ -0,0 +1,62 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	old := map[string]interface{}{
+		"Resources": map[string]interface{}{
+			"Bucket": map[string]interface{}{
+				"Type": "AWS::S3::Bucket",
+				"Properties": map[string]interface{}{
+					"BucketName": "foo",
+					"Tags":       []interface{}{"a", "b"},
+				},
+			},
+			"Old": map[string]interface{}{"Type": "AWS::SNS::Topic"},
+		},
+		"Outputs": map[string]interface{}{
+			"a/b~c": "x",
+		},
+	}
+
+	new := map[string]interface{}{
+		"Resources": map[string]interface{}{
+			"Bucket": map[string]interface{}{
+				"Type": "AWS::S3::Bucket",
+				"Properties": map[string]interface{}{
+					"BucketName": "bar",
+					"Tags":       []interface{}{"a"},
+				},
+			},
+		},
+		"Outputs": map[string]interface{}{
+			"a/b~c": "x",
+			"New":   "y",
+		},
+	}
+
+	expected := []Change{
+		{Path: "/Outputs/New", Mode: ChangeAdded, New: "y"},
+		{Path: "/Resources/Bucket/Properties/BucketName", Mode: ChangeChanged, Old: "foo", New: "bar"},
+		{Path: "/Resources/Bucket/Properties/Tags/1", Mode: ChangeRemoved, Old: "b"},
+		{Path: "/Resources/Old", Mode: ChangeRemoved, Old: map[string]interface{}{"Type": "AWS::SNS::Topic"}},
+	}
+
+	actual := Changes(CompareMaps(old, new))
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v\n!=\n%v", actual, expected)
+	}
+
+	if len(Changes(CompareMaps(old, old))) != 0 {
+		t.Errorf("expected no changes")
+	}
+}
+
+func TestPointer(t *testing.T) {
+	if p := Pointer("Resources", "a/b~c", 0); p != "/Resources/a~1b~0c/0" {
+		t.Errorf("unexpected pointer %s", p)
+	}
+}
@@ -12,42 +12,87 @@ func New(a, b cft.Template) Diff {
 	return CompareMaps(a.Map(), b.Map())
 }
 
+// comparer compares values. The zero value compares lists by position.
+type comparer struct {
+	// unordered returns true if the list at path should be compared as
+	// a set. The path is made of map keys only, without list indexes.
+	unordered func(path []string) bool
+}
+
 func compareValues(old, new interface{}) Diff {
+	return comparer{}.values(old, new, nil)
+}
+
+func (c comparer) values(old, new interface{}, path []string) Diff {
 	if reflect.TypeOf(old) != reflect.TypeOf(new) {
 
 		// In YAML there is no difference between "" and null
 		if old == "" && new == nil {
-			return value{new, Unchanged}
+			return value{new, Unchanged, nil}
 		}
 
-		return value{new, Changed}
+		return value{new, Changed, old}
 	}
 
 	switch v := old.(type) {
 	case []interface{}:
-		return compareSlices(v, new.([]interface{}))
+		if c.unordered != nil && c.unordered(path) {
+			return compareSets(v, new.([]interface{}))
+		}
+		return c.slices(v, new.([]interface{}), path)
 	case map[string]interface{}:
-		return CompareMaps(v, new.(map[string]interface{}))
+		return c.maps(v, new.(map[string]interface{}), path)
 	default:
 		if !reflect.DeepEqual(old, new) {
-			return value{new, Changed}
+			return value{new, Changed, old}
 		}
 	}
 
-	return value{old, Unchanged}
+	return value{old, Unchanged, nil}
 }
 
-func compareSlices(old, new []interface{}) Diff {
+func (c comparer) slices(old, new []interface{}, path []string) Diff {
 	max := int(math.Max(float64(len(old)), float64(len(new))))
 	d := make(slice, max)
 
 	for i := 0; i < max; i++ {
 		if i >= len(old) {
-			d[i] = value{new[i], Added}
+			d[i] = value{new[i], Added, nil}
 		} else if i >= len(new) {
-			d[i] = value{old[i], Removed}
+			d[i] = value{old[i], Removed, nil}
 		} else {
-			d[i] = compareValues(old[i], new[i])
+			d[i] = c.values(old[i], new[i], path)
+		}
+	}
+
+	return d
+}
+
+// compareSets compares lists where the order of the elements does not matter.
+// Elements in new come first, in order, followed by removed elements.
+func compareSets(old, new []interface{}) Diff {
+	d := make(slice, 0)
+	used := make([]bool, len(old))
+
+	for _, n := range new {
+		found := false
+		for i, o := range old {
+			if !used[i] && reflect.DeepEqual(o, n) {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+		if found {
+			d = append(d, value{n, Unchanged, nil})
+		} else {
+			d = append(d, value{n, Added, nil})
+		}
+	}
+
+	for i, o := range old {
+		if !used[i] {
+			d = append(d, value{o, Removed, nil})
 		}
 	}
 
@@ -55,21 +100,25 @@ func compareSlices(old, new []interface{}) Diff {
 }
 
 func CompareMaps(old, new map[string]interface{}) Diff {
+	return comparer{}.maps(old, new, nil)
+}
+
+func (c comparer) maps(old, new map[string]interface{}, path []string) Diff {
 	d := make(dmap)
 
 	// New and updated keys
 	for key, val := range new {
 		if _, ok := old[key]; !ok {
-			d[key] = value{val, Added}
+			d[key] = value{val, Added, nil}
 		} else {
-			d[key] = compareValues(old[key], val)
+			d[key] = c.values(old[key], val, append(path[:len(path):len(path)], key))
 		}
 	}
 
 	// Removed keys
 	for key, val := range old {
 		if _, ok := new[key]; !ok {
-			d[key] = value{val, Removed}
+			d[key] = value{val, Removed, nil}
 		}
 	}
 
@@ -28,6 +28,9 @@ const (
 
 	// Unchanged represents a value that has not changed
 	Unchanged Mode = "="
+
+	// Moved represents a resource with a new logical id. See Semantic.
+	Moved Mode = "~"
 )
 
 func (m Mode) String() string {
@@ -56,6 +59,9 @@ type Diff interface {
 type value struct {
 	val  interface{}
 	mode Mode
+
+	// old is the previous value when mode is Changed
+	old interface{}
 }
 
 // Mode returns the value's mode
@@ -192,6 +198,10 @@ func GetResourceActions(d Diff) map[string]ActionType {
 					actions[rname] = None
 				case Changed:
 					actions[rname] = Update
+				case Moved:
+					// CloudFormation replaces a resource with a new logical id
+					actions[resource.(moved).from] = Delete
+					actions[rname] = Create
 				}
 			}
 		}
@@ -21,6 +21,11 @@ func (m dmap) Format(long bool) string {
 	return formatMap(m, []interface{}{}, long)
 }
 
+// Format returns a pretty-printed representation of the moved resource
+func (m moved) Format(long bool) string {
+	return formatDiff(m.diff, []interface{}{}, long)
+}
+
 // Format returns a pretty-printed representation of the value
 func (v value) Format(long bool) string {
 	buf := strings.Builder{}
@@ -55,6 +60,8 @@ func formatDiff(d Diff, path []interface{}, long bool) string {
 		return formatMap(v, path, long)
 	case value:
 		return v.Format(long)
+	case moved:
+		return v.Format(long)
 	default:
 		panic(fmt.Errorf("unexpected type '%T'", d))
 	}
@@ -98,6 +105,15 @@ func formatMap(m dmap, path []interface{}, long bool) string {
 
 		output.WriteString(fmt.Sprintf("%s %s:", m, k))
 
+		if mv, ok := v.(moved); ok {
+			output.WriteString(fmt.Sprintf(" (from %s)", mv.from))
+			if !long && mv.diff.Mode() == Unchanged {
+				output.WriteString("\n")
+				continue
+			}
+			v = mv.diff
+		}
+
 		if !long && (m == Removed || m == Unchanged) {
 			output.WriteString(" " + stubValue(v.(value)) + "\n")
 		} else {
@@ -0,0 +1,60 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRenameChained(t *testing.T) {
+	renames := map[string]string{"A": "B", "B": "C"}
+
+	in := map[string]interface{}{
+		"Resources": map[string]interface{}{
+			"A": map[string]interface{}{"Type": "AWS::SNS::Topic"},
+			"B": map[string]interface{}{
+				"Type":      "AWS::SQS::Queue",
+				"DependsOn": "A",
+			},
+			"Sub": map[string]interface{}{
+				"Type": "AWS::SNS::Subscription",
+				"Properties": map[string]interface{}{
+					"Endpoint": map[string]interface{}{
+						"Fn::Sub": []interface{}{
+							"${X}/${A}",
+							map[string]interface{}{
+								"X": map[string]interface{}{"Ref": "A"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	expected := map[string]interface{}{
+		"Resources": map[string]interface{}{
+			"B": map[string]interface{}{"Type": "AWS::SNS::Topic"},
+			"C": map[string]interface{}{
+				"Type":      "AWS::SQS::Queue",
+				"DependsOn": "B",
+			},
+			"Sub": map[string]interface{}{
+				"Type": "AWS::SNS::Subscription",
+				"Properties": map[string]interface{}{
+					"Endpoint": map[string]interface{}{
+						"Fn::Sub": []interface{}{
+							"${X}/${B}",
+							map[string]interface{}{
+								"X": map[string]interface{}{"Ref": "B"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if out := rename(in, renames); !reflect.DeepEqual(out, expected) {
+		t.Errorf("unexpected rename:\n%v\n%v", out, expected)
+	}
+}
@@ -0,0 +1,182 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+)
+
+// StatefulTypes are resource types that hold data that is lost
+// when the resource is replaced
+var StatefulTypes = []string{
+	"AWS::DynamoDB::GlobalTable",
+	"AWS::DynamoDB::Table",
+	"AWS::EFS::FileSystem",
+	"AWS::RDS::DBCluster",
+	"AWS::RDS::DBInstance",
+	"AWS::S3::Bucket",
+}
+
+// IsStateful returns true if replacing a resource of this type loses data
+func IsStateful(typeName string) bool {
+	return slices.Contains(StatefulTypes, typeName)
+}
+
+// CreateOnlyFunc returns the createOnlyProperties from the registry schema
+// for a resource type, in the schema format, like /properties/BucketName
+type CreateOnlyFunc func(typeName string) []string
+
+// Replacement is a resource update that forces CloudFormation
+// to create a new resource and delete the old one
+type Replacement struct {
+	LogicalId string
+	TypeName  string
+
+	// Paths are the changes that force the replacement, relative
+	// to the resource, like /Properties/BucketName
+	Paths []string
+
+	// Stateful is true if data will be lost, see IsStateful
+	Stateful bool
+
+	// MovedFrom is the old logical id if the resource was renamed.
+	// See Semantic.
+	MovedFrom string
+}
+
+// GetReplacements returns the updated resources in d that will be
+// replaced, because a property that can only be set on creation has
+// changed, or the resource type has changed. Resources are sorted by
+// logical id.
+func GetReplacements(d Diff, createOnly CreateOnlyFunc) []Replacement {
+	retval := make([]Replacement, 0)
+
+	dm, ok := d.(dmap)
+	if !ok {
+		return retval
+	}
+	resources, ok := dm[string(cft.Resources)].(dmap)
+	if !ok {
+		return retval
+	}
+
+	for _, logicalId := range resources.keys() {
+		rd := resources[logicalId]
+
+		r, _ := rd.Value().(map[string]interface{})
+		typeName, _ := r["Type"].(string)
+
+		if m, ok := rd.(moved); ok {
+			// CloudFormation replaces a resource with a new logical id
+			retval = append(retval, Replacement{
+				LogicalId: logicalId,
+				TypeName:  typeName,
+				Paths:     make([]string, 0),
+				Stateful:  IsStateful(typeName),
+				MovedFrom: m.from,
+			})
+			continue
+		}
+
+		if rd.Mode() != Involved {
+			continue
+		}
+
+		patterns := []string{"/Type"}
+		for _, p := range createOnly(typeName) {
+			if name, found := strings.CutPrefix(p, "/properties/"); found {
+				patterns = append(patterns, "/Properties/"+name)
+			}
+		}
+
+		paths := make([]string, 0)
+		for _, c := range Changes(rd) {
+			for _, p := range patterns {
+				if affects(c, p) {
+					paths = append(paths, c.Path)
+					break
+				}
+			}
+		}
+
+		if len(paths) > 0 {
+			retval = append(retval, Replacement{
+				LogicalId: logicalId,
+				TypeName:  typeName,
+				Paths:     paths,
+				Stateful:  IsStateful(typeName),
+			})
+		}
+	}
+
+	sort.Slice(retval, func(i, j int) bool {
+		return retval[i].LogicalId < retval[j].LogicalId
+	})
+
+	return retval
+}
+
+// MarkReplacements sets Replacement on changes that force a replacement.
+// Change paths are from the template root.
+func MarkReplacements(changes []Change, replacements []Replacement) {
+	for i, c := range changes {
+		for _, r := range replacements {
+			prefix := Pointer(string(cft.Resources), r.LogicalId)
+			for _, p := range r.Paths {
+				if c.Path == prefix+p {
+					changes[i].Replacement = true
+				}
+			}
+		}
+	}
+}
+
+// affects returns true if the change is to the value at pattern, or
+// to something inside it, or replaces a map or list that contains it.
+// A * in the pattern matches any single key or list index.
+func affects(c Change, pattern string) bool {
+	a := strings.Split(c.Path, "/")
+	b := strings.Split(pattern, "/")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if b[i] != "*" && a[i] != b[i] {
+			return false
+		}
+	}
+	if len(a) >= len(b) {
+		return true
+	}
+	rest := b[len(a):]
+	return contains(c.Old, rest) || contains(c.New, rest)
+}
+
+// contains returns true if there is a value at path inside v
+func contains(v interface{}, path []string) bool {
+	if len(path) == 0 {
+		return v != nil
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if path[0] == "*" {
+			for _, child := range t {
+				if contains(child, path[1:]) {
+					return true
+				}
+			}
+			return false
+		}
+		child, ok := t[path[0]]
+		return ok && contains(child, path[1:])
+	case []interface{}:
+		for i, child := range t {
+			if path[0] == "*" || path[0] == fmt.Sprint(i) {
+				if contains(child, path[1:]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package diff_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/diff"
+	"github.com/aws-cloudformation/rain/cft/parse"
+)
+
+func TestGetReplacements(t *testing.T) {
+	a, err := parse.String(`
+Resources:
+  Table:
+    Type: AWS::DynamoDB::Table
+    Properties:
+      TableName: a
+      BillingMode: PROVISIONED
+  Topic:
+    Type: AWS::SNS::Topic
+    Properties:
+      DisplayName: x
+  Queue:
+    Type: AWS::SQS::Queue
+  Old:
+    Type: AWS::S3::Bucket
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := parse.String(`
+Resources:
+  Table:
+    Type: AWS::DynamoDB::Table
+    Properties:
+      TableName: b
+      BillingMode: PAY_PER_REQUEST
+  Topic:
+    Type: AWS::SNS::Topic
+    Properties:
+      DisplayName: y
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      FifoQueue: true
+  New:
+    Type: AWS::S3::Bucket
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createOnly := func(typeName string) []string {
+		switch typeName {
+		case "AWS::DynamoDB::Table":
+			return []string{"/properties/TableName"}
+		case "AWS::SNS::Topic":
+			return []string{"/properties/TopicName"}
+		case "AWS::SQS::Queue":
+			return []string{"/properties/FifoQueue", "/properties/QueueName"}
+		}
+		return nil
+	}
+
+	d := diff.Semantic(a, b, diff.SemanticOptions{})
+	actual := diff.GetReplacements(d, createOnly)
+
+	expected := []diff.Replacement{
+		{LogicalId: "New", TypeName: "AWS::S3::Bucket", Paths: []string{}, Stateful: true, MovedFrom: "Old"},
+		{LogicalId: "Queue", TypeName: "AWS::SQS::Queue", Paths: []string{"/Properties"}},
+		{LogicalId: "Table", TypeName: "AWS::DynamoDB::Table", Paths: []string{"/Properties/TableName"}, Stateful: true},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%+v\n!=\n%+v", actual, expected)
+	}
+
+	changes := diff.Changes(d)
+	diff.MarkReplacements(changes, actual)
+	for _, c := range changes {
+		replaced := c.Path == "/Resources/Table/Properties/TableName" || c.Path == "/Resources/Queue/Properties"
+		if c.Replacement != replaced {
+			t.Errorf("unexpected Replacement for %s", c.Path)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+)
+
+// SemanticOptions configures Semantic
+type SemanticOptions struct {
+	// Unordered returns true if a list property of a resource type can be
+	// compared as a set, which is the case for properties that have
+	// insertionOrder set to false in the registry schema.
+	// The path is made of property names, without list indexes.
+	// If Unordered is nil, all lists are compared by position.
+	Unordered func(typeName string, path []string) bool
+}
+
+// moved represents a resource that has a new logical id
+type moved struct {
+	from string
+	diff Diff
+}
+
+// Mode returns Moved
+func (m moved) Mode() Mode {
+	return Moved
+}
+
+// Value returns the new value of the resource
+func (m moved) Value() interface{} {
+	return m.diff.Value()
+}
+
+// String returns a string representation of the move
+func (m moved) String() string {
+	return fmt.Sprintf("%s%s:%s", m.Mode(), m.from, m.diff)
+}
+
+// Semantic returns a Diff that ignores differences that don't change
+// what CloudFormation would deploy.
+//
+// Renamed resources are matched by type and properties, taking into account
+// that references to them have been renamed too, and are reported as Moved
+// instead of being removed and added. Lists that are unordered according
+// to opts are compared as sets, and DependsOn and Fn::GetAtt short forms
+// are normalized.
+//
+// Short form intrinsics like !Sub are expected to have been converted by
+// parse.NormalizeNode, which parse.File and parse.String already do.
+func Semantic(a, b cft.Template, opts SemanticOptions) Diff {
+	old := normalize(a.Map()).(map[string]interface{})
+	new := normalize(b.Map()).(map[string]interface{})
+
+	renames := findRenames(old, new)
+	old = rename(old, renames).(map[string]interface{})
+
+	// Look up the resource type for unordered lists
+	newResources, _ := new[string(cft.Resources)].(map[string]interface{})
+	c := comparer{}
+	if opts.Unordered != nil {
+		c.unordered = func(path []string) bool {
+			if len(path) < 4 || path[0] != string(cft.Resources) || path[2] != "Properties" {
+				return false
+			}
+			r, _ := newResources[path[1]].(map[string]interface{})
+			typeName, _ := r["Type"].(string)
+			return typeName != "" && opts.Unordered(typeName, path[3:])
+		}
+	}
+
+	d := c.maps(old, new, nil)
+
+	if resources, ok := d.(dmap)[string(cft.Resources)].(dmap); ok {
+		for from, to := range renames {
+			resources[to] = moved{from: from, diff: resources[to]}
+		}
+	}
+
+	return d
+}
+
+// normalize returns a copy of v with equivalent forms made the same
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{})
+		for k, val := range t {
+			val = normalize(val)
+			switch k {
+			case "Fn::GetAtt":
+				// !GetAtt A.B is the same as [A, B]
+				if s, ok := val.(string); ok {
+					if parts := strings.SplitN(s, ".", 2); len(parts) == 2 {
+						val = []interface{}{parts[0], parts[1]}
+					}
+				}
+			case "DependsOn":
+				// DependsOn: A is the same as [A]
+				if s, ok := val.(string); ok {
+					val = []interface{}{s}
+				}
+			}
+			out[k] = val
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = normalize(val)
+		}
+		return out
+	}
+	return v
+}
+
+// findRenames matches resources that were removed from old with resources
+// that were added to new, if they have the same type and properties after
+// applying the other renames. Returns a map of old names to new names.
+func findRenames(old, new map[string]interface{}) map[string]string {
+	renames := make(map[string]string)
+
+	oldResources, _ := old[string(cft.Resources)].(map[string]interface{})
+	newResources, _ := new[string(cft.Resources)].(map[string]interface{})
+
+	removed := make([]string, 0)
+	for name := range oldResources {
+		if _, ok := newResources[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+
+	added := make([]string, 0)
+	for name := range newResources {
+		if _, ok := oldResources[name]; !ok {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+
+	matched := make(map[string]bool)
+
+	// A resource might only match once the resources it refers to are
+	// matched, so keep going until nothing else matches
+	for found := true; found; {
+		found = false
+		for _, from := range removed {
+			if _, ok := renames[from]; ok {
+				continue
+			}
+			for _, to := range added {
+				if matched[to] {
+					continue
+				}
+				renames[from] = to
+				if sameResource(oldResources[from], newResources[to], renames) {
+					matched[to] = true
+					found = true
+					break
+				}
+				delete(renames, from)
+			}
+		}
+	}
+
+	return renames
+}
+
+// sameResource returns true if the resources have the same type and properties
+func sameResource(old, new interface{}, renames map[string]string) bool {
+	o, _ := old.(map[string]interface{})
+	n, _ := new.(map[string]interface{})
+	if o == nil || n == nil || o["Type"] != n["Type"] {
+		return false
+	}
+	return reflect.DeepEqual(rename(o["Properties"], renames), n["Properties"])
+}
+
+var subVariable = regexp.MustCompile(`\$\{([^!}][^}]*)\}`)
+
+// renameSub renames logical ids in a Sub string, unless they are
+// variables defined in the Sub
+func renameSub(s string, renames map[string]string, vars map[string]interface{}) string {
+	return subVariable.ReplaceAllStringFunc(s, func(match string) string {
+		name := match[2 : len(match)-1]
+		parts := strings.SplitN(name, ".", 2)
+		if _, ok := vars[parts[0]]; ok {
+			return match
+		}
+		to, ok := renames[parts[0]]
+		if !ok {
+			return match
+		}
+		parts[0] = to
+		return "${" + strings.Join(parts, ".") + "}"
+	})
+}
+
+// rename returns a copy of v with logical ids renamed, including
+// Resources keys, Ref, Fn::GetAtt, Fn::Sub and DependsOn
+func rename(v interface{}, renames map[string]string) interface{} {
+	if len(renames) == 0 {
+		return v
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{})
+		for k, val := range t {
+			out[k] = renameValue(k, val, renames)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = rename(val, renames)
+		}
+		return out
+	}
+	return v
+}
+
+// renameValue renames the value of the map entry k. Each value is only
+// rewritten once, so that chained renames like A to B and B to C work.
+func renameValue(k string, val interface{}, renames map[string]string) interface{} {
+	switch k {
+	case "Ref":
+		if s, ok := val.(string); ok {
+			return renameId(s, renames)
+		}
+	case "Fn::GetAtt":
+		if l, ok := val.([]interface{}); ok && len(l) > 0 {
+			out := rename(l, renames).([]interface{})
+			if s, ok := l[0].(string); ok {
+				out[0] = renameId(s, renames)
+			}
+			return out
+		}
+		if s, ok := val.(string); ok {
+			name, attr, found := strings.Cut(s, ".")
+			if found {
+				return renameId(name, renames) + "." + attr
+			}
+		}
+	case "Fn::Sub":
+		switch sub := val.(type) {
+		case string:
+			return renameSub(sub, renames, nil)
+		case []interface{}:
+			if len(sub) == 2 {
+				vars, _ := sub[1].(map[string]interface{})
+				if s, ok := sub[0].(string); ok {
+					return []interface{}{renameSub(s, renames, vars), rename(sub[1], renames)}
+				}
+			}
+		}
+	case "DependsOn":
+		switch d := val.(type) {
+		case string:
+			return renameId(d, renames)
+		case []interface{}:
+			deps := make([]interface{}, len(d))
+			for i, dep := range d {
+				if s, ok := dep.(string); ok {
+					deps[i] = renameId(s, renames)
+				} else {
+					deps[i] = dep
+				}
+			}
+			return deps
+		}
+	case string(cft.Resources):
+		if resources, ok := val.(map[string]interface{}); ok {
+			renamed := make(map[string]interface{})
+			for name, r := range resources {
+				renamed[renameId(name, renames)] = rename(r, renames)
+			}
+			return renamed
+		}
+	}
+	return rename(val, renames)
+}
+
+// renameId returns the new name for a logical id, or the id if it was not renamed
+func renameId(name string, renames map[string]string) string {
+	if to, ok := renames[name]; ok && to != "" {
+		return to
+	}
+	return name
+}
@@ -0,0 +1,191 @@
+package diff_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/diff"
+	"github.com/aws-cloudformation/rain/cft/parse"
+)
+
+func TestSemantic(t *testing.T) {
+	a, err := parse.String(`
+Resources:
+  OldBucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: foo
+  Policy:
+    Type: AWS::S3::BucketPolicy
+    DependsOn: OldBucket
+    Properties:
+      Bucket: !Ref OldBucket
+      PolicyDocument:
+        Resource: !Sub ${OldBucket.Arn}/*
+  Group:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      GroupDescription: test
+      SecurityGroupIngress:
+        - CidrIp: 10.0.0.0/16
+        - CidrIp: 10.1.0.0/16
+Outputs:
+  Arn:
+    Value: !GetAtt OldBucket.Arn
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := parse.String(`
+Resources:
+  NewBucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: foo
+  Policy:
+    Type: AWS::S3::BucketPolicy
+    DependsOn: [NewBucket]
+    Properties:
+      Bucket: !Ref NewBucket
+      PolicyDocument:
+        Resource: !Sub ${NewBucket.Arn}/*
+  Group:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      GroupDescription: test
+      SecurityGroupIngress:
+        - CidrIp: 10.1.0.0/16
+        - CidrIp: 10.0.0.0/16
+Outputs:
+  Arn:
+    Value:
+      Fn::GetAtt: NewBucket.Arn
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unordered := func(typeName string, path []string) bool {
+		return typeName == "AWS::EC2::SecurityGroup" && slices.Equal(path, []string{"SecurityGroupIngress"})
+	}
+
+	d := diff.Semantic(a, b, diff.SemanticOptions{Unordered: unordered})
+
+	changes := diff.Changes(d)
+	if len(changes) != 1 {
+		t.Fatalf("expected a single change, got %v", changes)
+	}
+	c := changes[0]
+	if c.Mode != diff.ChangeMoved || c.Path != "/Resources/NewBucket" || c.From != "/Resources/OldBucket" {
+		t.Errorf("unexpected change %v", c)
+	}
+
+	actions := diff.GetResourceActions(d)
+	if actions["OldBucket"] != diff.Delete || actions["NewBucket"] != diff.Create ||
+		actions["Group"] != diff.None {
+		t.Errorf("unexpected actions %v", actions)
+	}
+
+	expected := "(|) Resources:\n(~)   NewBucket: (from OldBucket)\n"
+	if formatted := d.Format(false); formatted != expected {
+		t.Errorf("unexpected format %q, expected %q", formatted, expected)
+	}
+
+	// Without Semantic, everything is a change
+	if len(diff.Changes(diff.New(a, b))) < 5 {
+		t.Errorf("expected the plain diff to have more changes")
+	}
+}
+
+func TestSemanticSets(t *testing.T) {
+	a, _ := parse.String(`
+Resources:
+  Group:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      SecurityGroupIngress:
+        - CidrIp: 10.0.0.0/16
+        - CidrIp: 10.1.0.0/16
+`)
+	b, _ := parse.String(`
+Resources:
+  Group:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      SecurityGroupIngress:
+        - CidrIp: 10.2.0.0/16
+        - CidrIp: 10.0.0.0/16
+`)
+	d := diff.Semantic(a, b, diff.SemanticOptions{
+		Unordered: func(string, []string) bool { return true },
+	})
+	changes := diff.Changes(d)
+	if len(changes) != 2 ||
+		changes[0].Mode != diff.ChangeAdded || changes[0].Path != "/Resources/Group/Properties/SecurityGroupIngress/0" ||
+		changes[1].Mode != diff.ChangeRemoved || changes[1].Path != "/Resources/Group/Properties/SecurityGroupIngress/2" {
+		t.Errorf("unexpected changes %v", changes)
+	}
+}
+
+func TestSemanticRenameReferences(t *testing.T) {
+	a, err := parse.String(`
+Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+    Properties:
+      TopicName: first
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      QueueName: second
+  Subscription:
+    Type: AWS::SNS::Subscription
+    DependsOn: Queue
+    Properties:
+      TopicArn: !Ref Topic
+      Endpoint: !Sub
+        - ${Arn}/${Topic}
+        - Arn: !GetAtt Queue.Arn
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := parse.String(`
+Resources:
+  NewTopic:
+    Type: AWS::SNS::Topic
+    Properties:
+      TopicName: first
+  NewQueue:
+    Type: AWS::SQS::Queue
+    Properties:
+      QueueName: second
+  Subscription:
+    Type: AWS::SNS::Subscription
+    DependsOn: NewQueue
+    Properties:
+      TopicArn: !Ref NewTopic
+      Endpoint: !Sub
+        - ${Arn}/${NewTopic}
+        - Arn: !GetAtt NewQueue.Arn
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := diff.Semantic(a, b, diff.SemanticOptions{})
+	changes := diff.Changes(d)
+	if len(changes) != 2 {
+		t.Errorf("expected two renames, got %v", changes)
+	}
+	for _, c := range changes {
+		if c.Mode != diff.ChangeMoved {
+			t.Errorf("unexpected change %v", c)
+		}
+	}
+	if actions := diff.GetResourceActions(d); actions["Subscription"] != diff.None {
+		t.Errorf("expected Subscription to be unchanged, got %v", actions)
+	}
+}
@@ -0,0 +1,43 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+)
+
+// Cidr implements Fn::Cidr. It returns count consecutive subnets of
+// ipBlock, each with cidrBits host bits.
+func Cidr(ipBlock string, count int, cidrBits int) ([]string, error) {
+	prefix, err := netip.ParsePrefix(ipBlock)
+	if err != nil {
+		return nil, err
+	}
+	prefix = prefix.Masked()
+
+	addrBits := prefix.Addr().BitLen()
+	newBits := addrBits - cidrBits
+	if cidrBits <= 0 || newBits < prefix.Bits() {
+		return nil, fmt.Errorf("cidrBits %d is too large for %s", cidrBits, ipBlock)
+	}
+	if count < 1 || count > 256 {
+		return nil, fmt.Errorf("count must be between 1 and 256, got %d", count)
+	}
+	available := new(big.Int).Lsh(big.NewInt(1), uint(newBits-prefix.Bits()))
+	if big.NewInt(int64(count)).Cmp(available) > 0 {
+		return nil, fmt.Errorf("%s does not have room for %d subnets with %d bits", ipBlock, count, cidrBits)
+	}
+
+	base := new(big.Int).SetBytes(prefix.Addr().AsSlice())
+	step := new(big.Int).Lsh(big.NewInt(1), uint(cidrBits))
+	size := addrBits / 8
+
+	retval := make([]string, 0)
+	for i := 0; i < count; i++ {
+		n := new(big.Int).Add(base, new(big.Int).Mul(step, big.NewInt(int64(i))))
+		b := n.FillBytes(make([]byte, size))
+		addr, _ := netip.AddrFromSlice(b)
+		retval = append(retval, netip.PrefixFrom(addr, newBits).String())
+	}
+	return retval, nil
+}
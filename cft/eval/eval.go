@@ -0,0 +1,358 @@
+// Package eval evaluates CloudFormation intrinsic functions locally,
+// without making any calls to AWS.
+//
+// Supported:
+//
+//	Ref (Parameters, pseudo parameters, resources via Values)
+//	Fn::GetAtt (via Values)
+//	Fn::Sub
+//	Fn::If, Fn::Equals, Fn::And, Fn::Or, Fn::Not, Condition
+//	Fn::FindInMap
+//	Fn::Select, Fn::Split, Fn::Join
+//	Fn::Cidr
+//	Fn::GetAZs (stubbed, returns a, b, c zones for the region)
+//	Fn::Base64
+//	Fn::Length, Fn::ToJsonString (AWS::LanguageExtensions)
+//
+// Anything that can't be resolved, like a GetAtt for a resource that has
+// not been deployed, or Fn::ImportValue, is left in place unless
+// Options.Strict is set, in which case it is an error.
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"github.com/aws-cloudformation/rain/internal/node"
+	"github.com/aws-cloudformation/rain/plugins/deployconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnresolved is returned (wrapped) in strict mode when a value
+// can't be determined locally
+var ErrUnresolved = errors.New("unresolved")
+
+type unresolvedError struct {
+	what string
+}
+
+func (u *unresolvedError) Error() string {
+	return fmt.Sprintf("unable to resolve %s", u.what)
+}
+
+func (u *unresolvedError) Is(target error) bool {
+	return target == ErrUnresolved
+}
+
+func unresolved(format string, a ...any) error {
+	return &unresolvedError{what: fmt.Sprintf(format, a...)}
+}
+
+// Values supplies values that are only known after resources are deployed
+type Values interface {
+	// Ref returns the value of a Ref to a resource
+	Ref(logicalId string) (string, error)
+
+	// GetAtt returns the value of a resource attribute
+	GetAtt(logicalId string, attribute string) (string, error)
+}
+
+// KnownValues is a map implementation of Values.
+// Refs are keyed by logical id, and attributes by LogicalId.Attribute
+type KnownValues map[string]string
+
+// Ref returns the value of a Ref to a resource
+func (k KnownValues) Ref(logicalId string) (string, error) {
+	if v, ok := k[logicalId]; ok {
+		return v, nil
+	}
+	return "", unresolved("Ref %s", logicalId)
+}
+
+// GetAtt returns the value of a resource attribute
+func (k KnownValues) GetAtt(logicalId string, attribute string) (string, error) {
+	if v, ok := k[logicalId+"."+attribute]; ok {
+		return v, nil
+	}
+	return "", unresolved("GetAtt %s.%s", logicalId, attribute)
+}
+
+// Options configures an Evaluator
+type Options struct {
+	// Config holds parameter values. Parameters missing from Config
+	// fall back to the Default in the template, unless NoDefaults is set.
+	Config *deployconfig.DeployConfig
+
+	// NoDefaults leaves Refs to parameters that are not in Config
+	// unresolved, for when the values will only be known on deployment
+	NoDefaults bool
+
+	// Pseudo holds values for pseudo parameters, keyed by the full
+	// name, for example AWS::Region
+	Pseudo map[string]string
+
+	// PseudoFunc is called for pseudo parameters that are not in Pseudo
+	PseudoFunc func(name string) (string, error)
+
+	// Values supplies Refs to resources and GetAtt values
+	Values Values
+
+	// Strict makes anything that can't be resolved an error.
+	// Otherwise unresolved intrinsics are left in the output.
+	Strict bool
+}
+
+// Evaluator evaluates intrinsic functions in the context of a template
+type Evaluator struct {
+	opts       Options
+	template   cft.Template
+	model      *cft.Model
+	conditions map[string]bool
+	evaluating map[string]bool
+}
+
+// New creates an Evaluator for the template
+func New(t cft.Template, opts Options) (*Evaluator, error) {
+	model, err := t.Model()
+	if err != nil {
+		return nil, err
+	}
+	return &Evaluator{
+		opts:       opts,
+		template:   t,
+		model:      model,
+		conditions: make(map[string]bool),
+		evaluating: make(map[string]bool),
+	}, nil
+}
+
+// Node returns a copy of n with intrinsic functions evaluated.
+// The returned node is nil if n evaluates to AWS::NoValue.
+func (e *Evaluator) Node(n *yaml.Node) (*yaml.Node, error) {
+	return e.eval(n)
+}
+
+// Condition evaluates a named condition from the Conditions section
+func (e *Evaluator) Condition(name string) (bool, error) {
+	if v, ok := e.conditions[name]; ok {
+		return v, nil
+	}
+	c, ok := e.model.Conditions[name]
+	if !ok {
+		return false, fmt.Errorf("condition %s not found", name)
+	}
+	if e.evaluating[name] {
+		return false, fmt.Errorf("condition %s refers to itself", name)
+	}
+	e.evaluating[name] = true
+	defer delete(e.evaluating, name)
+
+	// Conditions are always evaluated strictly, since a partial
+	// result is not useful for deciding what to include
+	strict := e.opts.Strict
+	e.opts.Strict = true
+	defer func() { e.opts.Strict = strict }()
+
+	result, err := e.eval(c.Node)
+	if err != nil {
+		return false, err
+	}
+	b, err := toBool(result)
+	if err != nil {
+		return false, fmt.Errorf("condition %s: %v", name, err)
+	}
+	e.conditions[name] = b
+	return b, nil
+}
+
+// eval recursively evaluates a node, returning a new node
+func (e *Evaluator) eval(n *yaml.Node) (*yaml.Node, error) {
+	if n == nil {
+		return nil, nil
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		return e.eval(n.Content[0])
+	case yaml.SequenceNode:
+		retval := &yaml.Node{Kind: n.Kind, Tag: n.Tag, Style: n.Style,
+			Line: n.Line, Column: n.Column,
+			HeadComment: n.HeadComment, LineComment: n.LineComment,
+			FootComment: n.FootComment,
+			Content:     make([]*yaml.Node, 0)}
+		for _, c := range n.Content {
+			v, err := e.eval(c)
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				retval.Content = append(retval.Content, v)
+			}
+		}
+		return retval, nil
+	case yaml.MappingNode:
+		if len(n.Content) == 2 {
+			if fn, ok := functions[n.Content[0].Value]; ok && isArg(n.Content[0].Value, n.Content[1]) {
+				return e.call(n.Content[0].Value, fn, n.Content[1])
+			}
+		}
+		retval := &yaml.Node{Kind: n.Kind, Tag: n.Tag, Style: n.Style,
+			Line: n.Line, Column: n.Column,
+			HeadComment: n.HeadComment, LineComment: n.LineComment,
+			FootComment: n.FootComment,
+			Content:     make([]*yaml.Node, 0)}
+		for i := 0; i < len(n.Content); i += 2 {
+			v, err := e.eval(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				retval.Content = append(retval.Content, node.Clone(n.Content[i]), v)
+			}
+		}
+		return retval, nil
+	default:
+		return node.Clone(n), nil
+	}
+}
+
+// isArg filters out map keys that look like functions but aren't,
+// like an IAM policy statement Condition
+func isArg(name string, arg *yaml.Node) bool {
+	if name == "Condition" {
+		return arg.Kind == yaml.ScalarNode
+	}
+	return true
+}
+
+// call invokes an intrinsic function. If the function can't be
+// resolved and we are not in strict mode, the function is left in
+// place with its arguments evaluated as far as possible.
+func (e *Evaluator) call(name string, fn intrinsic, arg *yaml.Node) (*yaml.Node, error) {
+	v, err := fn(e, arg)
+	if err == nil {
+		return v, nil
+	}
+	if e.opts.Strict || !errors.Is(err, ErrUnresolved) {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	config.Debugf("eval leaving %s in place: %v", name, err)
+
+	partial, perr := e.eval(arg)
+	if perr != nil {
+		return nil, perr
+	}
+	if partial == nil {
+		partial = node.Clone(arg)
+	}
+	return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: name},
+		partial,
+	}}, nil
+}
+
+// param returns the value of a template parameter
+func (e *Evaluator) param(name string) (*yaml.Node, error) {
+	p := e.model.Parameters[name]
+
+	val := ""
+	found := false
+	if e.opts.Config != nil {
+		val, found = e.opts.Config.GetParam(name)
+	}
+	if !found {
+		if e.opts.NoDefaults {
+			return nil, unresolved("parameter %s has no value", name)
+		}
+		d := p.Default()
+		if d == nil {
+			return nil, unresolved("parameter %s has no value", name)
+		}
+		if d.Kind != yaml.ScalarNode {
+			return node.Clone(d), nil
+		}
+		val = d.Value
+	}
+
+	typ := p.Type()
+	if typ == "CommaDelimitedList" || strings.HasPrefix(typ, "List<") {
+		seq := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, s := range strings.Split(val, ",") {
+			seq.Content = append(seq.Content, scalar(s))
+		}
+		return seq, nil
+	}
+	return scalar(val), nil
+}
+
+// pseudo returns the value of a pseudo parameter like AWS::Region
+func (e *Evaluator) pseudo(name string) (string, error) {
+	if v, ok := e.opts.Pseudo[name]; ok {
+		return v, nil
+	}
+	if e.opts.PseudoFunc != nil {
+		return e.opts.PseudoFunc(name)
+	}
+	return "", unresolved("%s", name)
+}
+
+// ref resolves a Ref by name. A nil node means AWS::NoValue.
+func (e *Evaluator) ref(name string) (*yaml.Node, error) {
+	if name == "AWS::NoValue" {
+		return nil, nil
+	}
+	if strings.HasPrefix(name, "AWS::") {
+		v, err := e.pseudo(name)
+		if err != nil {
+			return nil, err
+		}
+		return scalar(v), nil
+	}
+	if _, ok := e.model.Parameters[name]; ok {
+		return e.param(name)
+	}
+	if _, ok := e.model.Resources[name]; ok {
+		if e.opts.Values == nil {
+			return nil, unresolved("Ref %s", name)
+		}
+		v, err := e.opts.Values.Ref(name)
+		if err != nil {
+			return nil, err
+		}
+		return scalar(v), nil
+	}
+	return nil, unresolved("Ref %s, which is not a parameter or resource", name)
+}
+
+// getAtt resolves a resource attribute
+func (e *Evaluator) getAtt(logicalId string, attribute string) (string, error) {
+	if e.opts.Values == nil {
+		return "", unresolved("GetAtt %s.%s", logicalId, attribute)
+	}
+	return e.opts.Values.GetAtt(logicalId, attribute)
+}
+
+func scalar(v string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+}
+
+func boolNode(b bool) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprintf("%t", b)}
+}
+
+func toBool(n *yaml.Node) (bool, error) {
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return false, unresolved("expected a boolean")
+	}
+	switch strings.ToLower(n.Value) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected a boolean, got %s", n.Value)
+}
@@ -0,0 +1,243 @@
+package eval_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/eval"
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/plugins/deployconfig"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+)
+
+const source = `
+Parameters:
+  Env:
+    Type: String
+    Default: dev
+  Subnets:
+    Type: CommaDelimitedList
+    Default: a,b
+
+Conditions:
+  IsProd: !Equals [!Ref Env, prod]
+  IsDev: !Not [!Condition IsProd]
+  Both: !And [!Condition IsDev, !Equals [x, x]]
+
+Mappings:
+  Sizes:
+    dev:
+      Size: small
+    prod:
+      Size: large
+
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Sub "${Env}-${AWS::Region}-bucket"
+      Size: !FindInMap [Sizes, !Ref Env, Size]
+      Versioning: !If [IsProd, Enabled, !Ref AWS::NoValue]
+      Subnet: !Select [1, !Ref Subnets]
+      Joined: !Join ["-", !Ref Subnets]
+      Split: !Split [",", "x,y"]
+      Encoded: !Base64 abc
+      Cidrs: !Cidr ["10.0.0.0/16", 2, 8]
+      Zones: !GetAZs ""
+      Default: !FindInMap [Sizes, test, Size, {DefaultValue: tiny}]
+      Arn: !GetAtt Queue.Arn
+      Partial: !Sub "${Queue.Arn}/${Env}"
+
+  ProdOnly:
+    Type: AWS::SNS::Topic
+    Condition: IsProd
+
+  Queue:
+    Type: AWS::SQS::Queue
+    DependsOn: [ProdOnly, Bucket]
+    Condition: IsDev
+
+Outputs:
+  ProdOut:
+    Condition: IsProd
+    Value: !Ref ProdOnly
+`
+
+func render(t *testing.T, params map[string]string, strict bool) (string, error) {
+	tmpl, err := parse.String(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc := &deployconfig.DeployConfig{}
+	for k, v := range params {
+		dc.Params = append(dc.Params, types.Parameter{
+			ParameterKey: ptr.String(k), ParameterValue: ptr.String(v)})
+	}
+	out, err := eval.Template(tmpl, eval.Options{
+		Config: dc,
+		Pseudo: map[string]string{"AWS::Region": "us-east-1"},
+		Strict: strict,
+	})
+	if err != nil {
+		return "", err
+	}
+	return format.String(out, format.Options{}), nil
+}
+
+func TestTemplateDev(t *testing.T) {
+	out, err := render(t, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"BucketName: dev-us-east-1-bucket",
+		"Size: small",
+		"Subnet: b",
+		"Joined: a-b",
+		"- 10.0.0.0/24",
+		"- 10.0.1.0/24",
+		"- us-east-1a",
+		"Encoded: YWJj",
+		"Default: tiny",
+		"Arn: !GetAtt Queue.Arn",
+		"Partial: !Sub ${Queue.Arn}/dev",
+		"DependsOn:\n      - Bucket",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("expected %q in:\n%s", e, out)
+		}
+	}
+
+	unexpected := []string{"ProdOnly", "Versioning", "Conditions", "Condition:", "ProdOut"}
+	for _, u := range unexpected {
+		if strings.Contains(out, u) {
+			t.Errorf("did not expect %q in:\n%s", u, out)
+		}
+	}
+}
+
+func TestTemplateProd(t *testing.T) {
+	out, err := render(t, map[string]string{"Env": "prod"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{"Versioning: Enabled", "Size: large", "ProdOnly:", "Value: !Ref ProdOnly"} {
+		if !strings.Contains(out, e) {
+			t.Errorf("expected %q in:\n%s", e, out)
+		}
+	}
+	if strings.Contains(out, "Queue:") {
+		t.Errorf("did not expect Queue in:\n%s", out)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	_, err := render(t, nil, true)
+	if !errors.Is(err, eval.ErrUnresolved) {
+		t.Fatalf("expected an unresolved error, got %v", err)
+	}
+}
+
+func TestKnownValues(t *testing.T) {
+	tmpl, err := parse.String(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := eval.New(tmpl, eval.Options{
+		Pseudo: map[string]string{"AWS::Region": "us-east-1"},
+		Values: eval.KnownValues{"Queue": "q", "Queue.Arn": "arn:q"},
+		Strict: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := tmpl.GetResource("Bucket")
+	v, err := e.Node(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := parse.Node(v)
+	s := format.String(out, format.Options{})
+	if !strings.Contains(s, "Partial: arn:q/dev") {
+		t.Errorf("unexpected output:\n%s", s)
+	}
+}
+
+func TestCidr(t *testing.T) {
+	cases := []struct {
+		block    string
+		count    int
+		bits     int
+		expected []string
+	}{
+		{"192.168.0.0/24", 6, 5, []string{"192.168.0.0/27", "192.168.0.32/27",
+			"192.168.0.64/27", "192.168.0.96/27", "192.168.0.128/27", "192.168.0.160/27"}},
+		{"2001:db8::/56", 2, 64, []string{"2001:db8::/64", "2001:db8:0:1::/64"}},
+	}
+	for _, c := range cases {
+		got, err := eval.Cidr(c.block, c.count, c.bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, ",") != strings.Join(c.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", c.block, c.expected, got)
+		}
+	}
+
+	if _, err := eval.Cidr("10.0.0.0/24", 3, 7); err == nil {
+		t.Errorf("expected an error for too many subnets")
+	}
+}
+
+func TestLanguageExtensions(t *testing.T) {
+	tmpl, err := parse.String(`
+Parameters:
+  Names:
+    Type: CommaDelimitedList
+    Default: a,b,c
+Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+    Properties:
+      Count:
+        Fn::Length: !Ref Names
+      Literal:
+        Fn::Length: [1, 2]
+      Json:
+        Fn::ToJsonString:
+          Name: !Select [0, !Ref Names]
+          Items: [1, true, "x"]
+      Unresolved:
+        Fn::ToJsonString:
+          Arn: !GetAtt Queue.Arn
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := eval.New(tmpl, eval.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := tmpl.GetResource("Topic")
+	v, err := e.Node(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := parse.Node(v)
+	s := format.String(out, format.Options{})
+	for _, expected := range []string{
+		"Count: 3",
+		"Literal: 2",
+		`Json: '{"Name":"a","Items":[1,true,"x"]}'`,
+		"Fn::ToJsonString:\n      Arn: !GetAtt Queue.Arn",
+	} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("expected %q in:\n%s", expected, s)
+		}
+	}
+}
@@ -0,0 +1,635 @@
+package eval
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"gopkg.in/yaml.v3"
+)
+
+// intrinsic evaluates a function, given its unevaluated argument
+type intrinsic func(e *Evaluator, arg *yaml.Node) (*yaml.Node, error)
+
+var functions map[string]intrinsic
+
+func init() {
+	functions = map[string]intrinsic{
+		"Ref":              evalRef,
+		"Condition":        evalCondition,
+		"Fn::GetAtt":       evalGetAtt,
+		"Fn::Sub":          evalSub,
+		"Fn::If":           evalIf,
+		"Fn::Equals":       evalEquals,
+		"Fn::And":          evalAnd,
+		"Fn::Or":           evalOr,
+		"Fn::Not":          evalNot,
+		"Fn::FindInMap":    evalFindInMap,
+		"Fn::Select":       evalSelect,
+		"Fn::Split":        evalSplit,
+		"Fn::Join":         evalJoin,
+		"Fn::Cidr":         evalCidr,
+		"Fn::GetAZs":       evalGetAZs,
+		"Fn::Base64":       evalBase64,
+		"Fn::ImportValue":  evalImportValue,
+		"Fn::Length":       evalLength,
+		"Fn::ToJsonString": evalToJsonString,
+	}
+}
+
+// args evaluates a sequence argument and checks the number of elements
+func (e *Evaluator) args(arg *yaml.Node, min int, max int) ([]*yaml.Node, error) {
+	v, err := e.eval(arg)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil || v.Kind != yaml.SequenceNode {
+		if v != nil && v.Kind == yaml.MappingNode {
+			return nil, unresolved("arguments")
+		}
+		return nil, errors.New("expected a list of arguments")
+	}
+	if len(v.Content) < min || len(v.Content) > max {
+		return nil, fmt.Errorf("expected %d to %d arguments, got %d", min, max, len(v.Content))
+	}
+	return v.Content, nil
+}
+
+// str returns the value of a node that must be a resolved scalar
+func str(n *yaml.Node) (string, error) {
+	if n == nil {
+		return "", errors.New("unexpected AWS::NoValue")
+	}
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return n.Value, nil
+	case yaml.MappingNode:
+		return "", unresolved("nested function")
+	}
+	return "", errors.New("expected a scalar")
+}
+
+// strs returns the values of a node that must be a list of resolved scalars
+func strs(n *yaml.Node) ([]string, error) {
+	if n == nil {
+		return nil, errors.New("unexpected AWS::NoValue")
+	}
+	if n.Kind == yaml.MappingNode {
+		return nil, unresolved("nested function")
+	}
+	if n.Kind != yaml.SequenceNode {
+		return nil, errors.New("expected a list")
+	}
+	retval := make([]string, 0)
+	for _, c := range n.Content {
+		s, err := str(c)
+		if err != nil {
+			return nil, err
+		}
+		retval = append(retval, s)
+	}
+	return retval, nil
+}
+
+// hasIntrinsic returns true if anything in n is still an unresolved function
+func hasIntrinsic(n *yaml.Node) bool {
+	if n == nil {
+		return false
+	}
+	if n.Kind == yaml.MappingNode && len(n.Content) == 2 {
+		if _, ok := functions[n.Content[0].Value]; ok {
+			return true
+		}
+	}
+	for _, c := range n.Content {
+		if hasIntrinsic(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func evalRef(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	v, err := e.eval(arg)
+	if err != nil {
+		return nil, err
+	}
+	name, err := str(v)
+	if err != nil {
+		return nil, err
+	}
+	return e.ref(name)
+}
+
+func evalCondition(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	b, err := e.Condition(arg.Value)
+	if err != nil {
+		return nil, err
+	}
+	return boolNode(b), nil
+}
+
+func evalGetAtt(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	v, err := e.eval(arg)
+	if err != nil {
+		return nil, err
+	}
+	var logicalId, attribute string
+	switch v.Kind {
+	case yaml.ScalarNode:
+		var found bool
+		logicalId, attribute, found = strings.Cut(v.Value, ".")
+		if !found {
+			return nil, fmt.Errorf("unexpected GetAtt %s", v.Value)
+		}
+	case yaml.SequenceNode:
+		if len(v.Content) != 2 {
+			return nil, errors.New("expected two arguments")
+		}
+		if logicalId, err = str(v.Content[0]); err != nil {
+			return nil, err
+		}
+		if attribute, err = str(v.Content[1]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, unresolved("GetAtt arguments")
+	}
+	s, err := e.getAtt(logicalId, attribute)
+	if err != nil {
+		return nil, err
+	}
+	return scalar(s), nil
+}
+
+// evalSub expands variables in a Sub string. In non-strict mode the
+// variables that can't be resolved are left in a new Fn::Sub.
+func evalSub(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	var sub string
+	vars := make(map[string]*yaml.Node)
+	varNames := make([]string, 0)
+
+	switch arg.Kind {
+	case yaml.ScalarNode:
+		sub = arg.Value
+	case yaml.SequenceNode:
+		if len(arg.Content) != 2 || arg.Content[1].Kind != yaml.MappingNode {
+			return nil, errors.New("expected a string and a map of variables")
+		}
+		sub = arg.Content[0].Value
+		m := arg.Content[1]
+		for i := 0; i < len(m.Content); i += 2 {
+			v, err := e.eval(m.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			vars[m.Content[i].Value] = v
+			varNames = append(varNames, m.Content[i].Value)
+		}
+	default:
+		return nil, errors.New("expected a string or a list")
+	}
+
+	words, err := parse.ParseSub(sub, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := strings.Builder{}
+	partial := strings.Builder{}
+	var firstErr error
+	usedVars := make(map[string]bool)
+
+	// escape makes a literal string safe to put back into a Sub
+	escape := func(s string) string {
+		return strings.ReplaceAll(s, "${", "${!")
+	}
+
+	for _, word := range words {
+		var val string
+		var err error
+		original := word.W
+		switch word.T {
+		case parse.STR:
+			resolved.WriteString(word.W)
+			partial.WriteString(escape(word.W))
+			continue
+		case parse.AWS:
+			original = "AWS::" + word.W
+			val, err = e.pseudo(original)
+		case parse.RAIN:
+			original = "Rain::" + word.W
+			err = unresolved("%s", original)
+		case parse.REF:
+			if v, ok := vars[word.W]; ok {
+				usedVars[word.W] = true
+				val, err = str(v)
+			} else {
+				var n *yaml.Node
+				n, err = e.ref(word.W)
+				if err == nil {
+					val, err = str(n)
+				}
+			}
+		case parse.GETATT:
+			if v, ok := vars[word.W]; ok {
+				usedVars[word.W] = true
+				val, err = str(v)
+			} else {
+				left, right, _ := strings.Cut(word.W, ".")
+				val, err = e.getAtt(left, right)
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, ErrUnresolved) {
+				return nil, fmt.Errorf("%s: %v", original, err)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			partial.WriteString("${" + original + "}")
+			continue
+		}
+		resolved.WriteString(val)
+		partial.WriteString(escape(val))
+	}
+
+	if firstErr == nil {
+		return scalar(resolved.String()), nil
+	}
+	if e.opts.Strict {
+		return nil, firstErr
+	}
+
+	// Leave a Sub in place with whatever could not be resolved
+	out := &yaml.Node{Kind: yaml.MappingNode}
+	var subArg *yaml.Node
+	remaining := &yaml.Node{Kind: yaml.MappingNode}
+	for _, name := range varNames {
+		if v := vars[name]; usedVars[name] && v != nil && v.Kind != yaml.ScalarNode {
+			remaining.Content = append(remaining.Content, scalar(name), v)
+		}
+	}
+	if len(remaining.Content) > 0 {
+		subArg = &yaml.Node{Kind: yaml.SequenceNode,
+			Content: []*yaml.Node{scalar(partial.String()), remaining}}
+	} else {
+		subArg = scalar(partial.String())
+	}
+	out.Content = append(out.Content, scalar("Fn::Sub"), subArg)
+	return out, nil
+}
+
+func evalIf(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	if arg.Kind != yaml.SequenceNode || len(arg.Content) != 3 {
+		return nil, errors.New("expected a condition name and two values")
+	}
+	b, err := e.Condition(arg.Content[0].Value)
+	if err != nil {
+		return nil, err
+	}
+	if b {
+		return e.eval(arg.Content[1])
+	}
+	return e.eval(arg.Content[2])
+}
+
+func evalEquals(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	args, err := e.args(arg, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	if hasIntrinsic(args[0]) || hasIntrinsic(args[1]) {
+		return nil, unresolved("Fn::Equals arguments")
+	}
+	return boolNode(equal(args[0], args[1])), nil
+}
+
+// equal compares two resolved nodes by value
+func equal(a *yaml.Node, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind != b.Kind || len(a.Content) != len(b.Content) {
+		return false
+	}
+	if a.Kind == yaml.ScalarNode {
+		return a.Value == b.Value
+	}
+	for i := range a.Content {
+		if !equal(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Evaluator) bools(arg *yaml.Node) ([]bool, error) {
+	args, err := e.args(arg, 1, 10)
+	if err != nil {
+		return nil, err
+	}
+	retval := make([]bool, 0)
+	for _, a := range args {
+		b, err := toBool(a)
+		if err != nil {
+			return nil, err
+		}
+		retval = append(retval, b)
+	}
+	return retval, nil
+}
+
+func evalAnd(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	bs, err := e.bools(arg)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bs {
+		if !b {
+			return boolNode(false), nil
+		}
+	}
+	return boolNode(true), nil
+}
+
+func evalOr(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	bs, err := e.bools(arg)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bs {
+		if b {
+			return boolNode(true), nil
+		}
+	}
+	return boolNode(false), nil
+}
+
+func evalNot(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	bs, err := e.bools(arg)
+	if err != nil {
+		return nil, err
+	}
+	if len(bs) != 1 {
+		return nil, errors.New("expected one condition")
+	}
+	return boolNode(!bs[0]), nil
+}
+
+func evalFindInMap(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	args, err := e.args(arg, 3, 4)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 3)
+	for i := 0; i < 3; i++ {
+		if keys[i], err = str(args[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	var defaultValue *yaml.Node
+	if len(args) == 4 {
+		_, defaultValue, _ = getMapValue(args[3], "DefaultValue")
+	}
+
+	if m, ok := e.model.Mappings[keys[0]]; ok {
+		if v := m.Lookup(keys[1], keys[2]); v != nil {
+			return e.eval(v)
+		}
+	}
+	if defaultValue != nil {
+		return defaultValue, nil
+	}
+	return nil, fmt.Errorf("%s.%s.%s not found in Mappings", keys[0], keys[1], keys[2])
+}
+
+func getMapValue(n *yaml.Node, key string) (*yaml.Node, *yaml.Node, bool) {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil, nil, false
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i], n.Content[i+1], true
+		}
+	}
+	return nil, nil, false
+}
+
+func evalSelect(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	args, err := e.args(arg, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	s, err := str(args[0])
+	if err != nil {
+		return nil, err
+	}
+	idx, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index %s", s)
+	}
+	list := args[1]
+	if list.Kind == yaml.MappingNode {
+		return nil, unresolved("Fn::Select list")
+	}
+	if list.Kind != yaml.SequenceNode {
+		return nil, errors.New("expected a list")
+	}
+	if idx < 0 || idx >= len(list.Content) {
+		return nil, fmt.Errorf("index %d out of range", idx)
+	}
+	return list.Content[idx], nil
+}
+
+func evalSplit(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	args, err := e.args(arg, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	delim, err := str(args[0])
+	if err != nil {
+		return nil, err
+	}
+	s, err := str(args[1])
+	if err != nil {
+		return nil, err
+	}
+	retval := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, part := range strings.Split(s, delim) {
+		retval.Content = append(retval.Content, scalar(part))
+	}
+	return retval, nil
+}
+
+func evalJoin(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	args, err := e.args(arg, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	delim, err := str(args[0])
+	if err != nil {
+		return nil, err
+	}
+	parts, err := strs(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return scalar(strings.Join(parts, delim)), nil
+}
+
+func evalCidr(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	args, err := e.args(arg, 3, 3)
+	if err != nil {
+		return nil, err
+	}
+	vals := make([]string, 3)
+	for i, a := range args {
+		if vals[i], err = str(a); err != nil {
+			return nil, err
+		}
+	}
+	count, err := strconv.Atoi(vals[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid count %s", vals[1])
+	}
+	bits, err := strconv.Atoi(vals[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cidrBits %s", vals[2])
+	}
+	cidrs, err := Cidr(vals[0], count, bits)
+	if err != nil {
+		return nil, err
+	}
+	retval := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, c := range cidrs {
+		retval.Content = append(retval.Content, scalar(c))
+	}
+	return retval, nil
+}
+
+// evalGetAZs is a stub that returns three zones for the region,
+// since the real answer depends on the account
+func evalGetAZs(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	v, err := e.eval(arg)
+	if err != nil {
+		return nil, err
+	}
+	region, err := str(v)
+	if err != nil {
+		return nil, err
+	}
+	if region == "" {
+		if region, err = e.pseudo("AWS::Region"); err != nil {
+			return nil, err
+		}
+	}
+	retval := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, z := range []string{"a", "b", "c"} {
+		retval.Content = append(retval.Content, scalar(region+z))
+	}
+	return retval, nil
+}
+
+func evalBase64(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	v, err := e.eval(arg)
+	if err != nil {
+		return nil, err
+	}
+	s, err := str(v)
+	if err != nil {
+		return nil, err
+	}
+	return scalar(base64.StdEncoding.EncodeToString([]byte(s))), nil
+}
+
+// evalImportValue can't be resolved without looking at other stacks
+func evalImportValue(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	return nil, unresolved("Fn::ImportValue")
+}
+
+// evalLength implements Fn::Length from the AWS::LanguageExtensions transform
+func evalLength(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	v, err := e.eval(arg)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, errors.New("unexpected AWS::NoValue")
+	}
+	switch v.Kind {
+	case yaml.SequenceNode:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(len(v.Content))}, nil
+	case yaml.MappingNode:
+		return nil, unresolved("Fn::Length list")
+	}
+	return nil, errors.New("expected a list")
+}
+
+// evalToJsonString implements Fn::ToJsonString from the AWS::LanguageExtensions transform.
+// Keys are kept in the order they are in the template.
+func evalToJsonString(e *Evaluator, arg *yaml.Node) (*yaml.Node, error) {
+	v, err := e.eval(arg)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, errors.New("unexpected AWS::NoValue")
+	}
+	if v.Kind != yaml.MappingNode && v.Kind != yaml.SequenceNode {
+		return nil, errors.New("expected an object or a list")
+	}
+	if hasIntrinsic(v) {
+		return nil, unresolved("Fn::ToJsonString value")
+	}
+	s, err := toJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	return scalar(s), nil
+}
+
+// toJSON writes a node as compact JSON
+func toJSON(n *yaml.Node) (string, error) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		parts := make([]string, 0)
+		for i := 0; i < len(n.Content); i += 2 {
+			k, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return "", err
+			}
+			v, err := toJSON(n.Content[i+1])
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, string(k)+":"+v)
+		}
+		return "{" + strings.Join(parts, ",") + "}", nil
+	case yaml.SequenceNode:
+		parts := make([]string, 0)
+		for _, c := range n.Content {
+			v, err := toJSON(c)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, v)
+		}
+		return "[" + strings.Join(parts, ",") + "]", nil
+	case yaml.ScalarNode:
+		switch n.Tag {
+		case "!!int", "!!float", "!!bool":
+			return n.Value, nil
+		case "!!null":
+			return "null", nil
+		}
+		b, err := json.Marshal(n.Value)
+		return string(b), err
+	}
+	return "", fmt.Errorf("unexpected node kind %v", n.Kind)
+}
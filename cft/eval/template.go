@@ -0,0 +1,146 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/internal/node"
+	"gopkg.in/yaml.v3"
+)
+
+// Template returns a copy of t with conditions applied and intrinsic
+// functions in Resources and Outputs evaluated.
+//
+// Resources and Outputs with a false Condition are removed, along with
+// DependsOn entries that point to removed resources. The Conditions
+// section is removed if every condition could be evaluated.
+func Template(t cft.Template, opts Options) (cft.Template, error) {
+	e, err := New(t, opts)
+	if err != nil {
+		return t, err
+	}
+	return e.Template()
+}
+
+// Template renders the evaluator's template. See the package level Template.
+func (e *Evaluator) Template() (cft.Template, error) {
+	retval := cft.Template{
+		Node:      node.Clone(e.template.Node),
+		Constants: e.template.Constants,
+		Packages:  e.template.Packages,
+	}
+	model, err := retval.Model()
+	if err != nil {
+		return retval, err
+	}
+
+	allResolved := true
+
+	// include decides if an element with a Condition should be kept
+	include := func(el *cft.Element, condition string) (bool, error) {
+		if condition == "" {
+			return true, nil
+		}
+		b, err := e.Condition(condition)
+		if err != nil {
+			if e.opts.Strict || !errors.Is(err, ErrUnresolved) {
+				return false, fmt.Errorf("%s: %w", el.Name, err)
+			}
+			allResolved = false
+			return true, nil
+		}
+		if b {
+			el.Remove("Condition")
+		}
+		return b, nil
+	}
+
+	removed := make([]string, 0)
+	for _, name := range model.Names(cft.Resources) {
+		r := model.Resources[name]
+		keep, err := include(&r.Element, r.Condition())
+		if err != nil {
+			return retval, err
+		}
+		if !keep {
+			model.RemoveResource(name)
+			removed = append(removed, name)
+		}
+	}
+
+	for _, name := range model.Names(cft.Resources) {
+		r := model.Resources[name]
+		if err := e.evalElement(&r.Element); err != nil {
+			return retval, err
+		}
+		pruneDependsOn(r, removed)
+	}
+
+	for _, name := range model.Names(cft.Outputs) {
+		o := model.Outputs[name]
+		keep, err := include(&o.Element, o.Condition())
+		if err != nil {
+			return retval, err
+		}
+		if !keep {
+			model.RemoveOutput(name)
+			continue
+		}
+		if err := e.evalElement(&o.Element); err != nil {
+			return retval, err
+		}
+	}
+
+	// Make sure all conditions can be evaluated, even if unused
+	for _, name := range model.Names(cft.Conditions) {
+		if _, err := e.Condition(name); err != nil {
+			if e.opts.Strict || !errors.Is(err, ErrUnresolved) {
+				return retval, err
+			}
+			allResolved = false
+		}
+	}
+	if allResolved {
+		node.RemoveFromMap(retval.Node.Content[0], string(cft.Conditions))
+	}
+	if outputs, err := retval.GetSection(cft.Outputs); err == nil && len(outputs.Content) == 0 {
+		node.RemoveFromMap(retval.Node.Content[0], string(cft.Outputs))
+	}
+
+	return retval, nil
+}
+
+// evalElement replaces the element's node contents with the evaluated version
+func (e *Evaluator) evalElement(el *cft.Element) error {
+	v, err := e.eval(el.Node)
+	if err != nil {
+		return fmt.Errorf("%s: %w", el.Name, err)
+	}
+	if v == nil {
+		v = &yaml.Node{Kind: yaml.MappingNode}
+	}
+	*el.Node = *v
+	return nil
+}
+
+// pruneDependsOn removes DependsOn entries for resources that were removed
+func pruneDependsOn(r *cft.ModelResource, removed []string) {
+	d := r.Get("DependsOn")
+	if d == nil {
+		return
+	}
+	if d.Kind == yaml.ScalarNode {
+		if slices.Contains(removed, d.Value) {
+			r.Remove("DependsOn")
+		}
+		return
+	}
+	d.Content = slices.DeleteFunc(d.Content, func(n *yaml.Node) bool {
+		return slices.Contains(removed, n.Value)
+	})
+	if len(d.Content) == 0 {
+		r.Remove("DependsOn")
+	}
+}
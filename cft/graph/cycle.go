@@ -0,0 +1,198 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Edge is a link from a node to a node that it depends on
+type Edge struct {
+	From Node
+	To   Node
+
+	// Types are the kinds of references from From to To, sorted
+	Types []EdgeType
+}
+
+// OnlyDependsOn returns true if the link only comes from DependsOn,
+// and not from a Ref, Fn::GetAtt or Fn::Sub, so removing the DependsOn
+// removes the link
+func (e Edge) OnlyDependsOn() bool {
+	return len(e.Types) == 1 && e.Types[0] == DependsOn
+}
+
+func (e Edge) String() string {
+	if len(e.Types) == 0 {
+		return fmt.Sprintf("%s -> %s", e.From, e.To)
+	}
+	types := make([]string, 0)
+	for _, t := range e.Types {
+		types = append(types, string(t))
+	}
+	return fmt.Sprintf("%s -(%s)-> %s", e.From, strings.Join(types, ","), e.To)
+}
+
+// Cycle is a chain of edges where each edge starts at the node
+// that the previous one points to, and the last edge points to
+// the first node
+type Cycle []Edge
+
+func (c Cycle) String() string {
+	parts := make([]string, 0)
+	for _, e := range c {
+		parts = append(parts, e.From.String())
+	}
+	if len(c) > 0 {
+		parts = append(parts, c[0].From.String())
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// Suggestion explains how the cycle could be broken. DependsOn edges can be
+// removed without changing anything else; otherwise one of the references
+// has to go, for example by moving it into a separate resource.
+func (c Cycle) Suggestion() string {
+	for _, e := range c {
+		if e.OnlyDependsOn() {
+			return fmt.Sprintf("remove %s from the DependsOn of %s", e.To.Name, e.From.Name)
+		}
+	}
+	return "remove one of the references, for example by moving it into a separate resource"
+}
+
+// edge returns the edge from one node to another
+func (g Graph) edge(from, to Node) Edge {
+	types := make([]EdgeType, 0)
+	for t := range g.nodes[from][to] {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i] < types[j]
+	})
+	return Edge{From: from, To: to, Types: types}
+}
+
+// Cycles returns the circular dependencies in the graph, which CloudFormation
+// rejects. There is one cycle for each group of nodes that depend on each other,
+// which is the shortest cycle through the first node in the group.
+func (g Graph) Cycles() []Cycle {
+	retval := make([]Cycle, 0)
+
+	for _, component := range g.components() {
+		start := component[0]
+		if _, self := g.nodes[start][start]; len(component) == 1 && !self {
+			continue
+		}
+
+		in := make(map[Node]bool)
+		for _, n := range component {
+			in[n] = true
+		}
+
+		if c := g.shortestCycle(start, in); c != nil {
+			retval = append(retval, c)
+		}
+	}
+
+	sort.Slice(retval, func(i, j int) bool {
+		return retval[i][0].From.String() < retval[j][0].From.String()
+	})
+
+	return retval
+}
+
+// shortestCycle finds the shortest path from start back to itself,
+// only going through nodes that are in the same component
+func (g Graph) shortestCycle(start Node, in map[Node]bool) Cycle {
+	prev := make(map[Node]Node)
+	queue := []Node{start}
+	seen := map[Node]bool{start: true}
+
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+
+		for _, to := range g.Get(from) {
+			if !in[to] {
+				continue
+			}
+
+			if to == start {
+				// Walk back to the start to get the chain of edges
+				retval := Cycle{g.edge(from, start)}
+				for n := from; n != start; n = prev[n] {
+					retval = append(Cycle{g.edge(prev[n], n)}, retval...)
+				}
+				return retval
+			}
+
+			if !seen[to] {
+				seen[to] = true
+				prev[to] = from
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	return nil
+}
+
+// components returns the strongly connected components of the graph,
+// using Tarjan's algorithm. Nodes in each component are sorted.
+func (g Graph) components() [][]Node {
+	index := 0
+	indexes := make(map[Node]int)
+	lowlinks := make(map[Node]int)
+	onStack := make(map[Node]bool)
+	stack := make([]Node, 0)
+	retval := make([][]Node, 0)
+
+	var connect func(n Node)
+	connect = func(n Node) {
+		indexes[n] = index
+		lowlinks[n] = index
+		index++
+		stack = append(stack, n)
+		onStack[n] = true
+
+		for _, to := range g.Get(n) {
+			if _, ok := indexes[to]; !ok {
+				connect(to)
+				lowlinks[n] = min(lowlinks[n], lowlinks[to])
+			} else if onStack[to] {
+				lowlinks[n] = min(lowlinks[n], indexes[to])
+			}
+		}
+
+		if lowlinks[n] == indexes[n] {
+			component := make([]Node, 0)
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == n {
+					break
+				}
+			}
+			sort.Slice(component, func(i, j int) bool {
+				return component[i].String() < component[j].String()
+			})
+			retval = append(retval, component)
+		}
+	}
+
+	nodes := make([]Node, len(g.order))
+	copy(nodes, g.order)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].String() < nodes[j].String()
+	})
+	for _, n := range nodes {
+		if _, ok := indexes[n]; !ok {
+			connect(n)
+		}
+	}
+
+	return retval
+}
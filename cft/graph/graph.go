@@ -26,46 +26,84 @@ func (n Node) String() string {
 	return fmt.Sprintf("%s/%s", n.Type, n.Name)
 }
 
-// Graph represents a directed, acyclic graph with ordered nodes
+// Reference is a reference from a node to a name that
+// could not be found in the template
+type Reference struct {
+	From Node
+	Name string
+	Type EdgeType
+}
+
+// EdgeType is the kind of reference that links two nodes
+type EdgeType string
+
+const (
+	// Ref is a Ref, or a ${Name} in a Fn::Sub
+	Ref EdgeType = "ref"
+
+	// GetAtt is a Fn::GetAtt, or a ${Name.Attribute} in a Fn::Sub
+	GetAtt EdgeType = "getatt"
+
+	// DependsOn is a resource's DependsOn
+	DependsOn EdgeType = "dependsOn"
+
+	// Condition is a resource or output Condition, a condition used
+	// in Fn::If, or a condition used by another condition
+	Condition EdgeType = "condition"
+
+	// ImportValue is a Fn::ImportValue of an export from another stack
+	ImportValue EdgeType = "importValue"
+)
+
+// Graph represents a directed graph with ordered nodes.
+// A valid template is acyclic, see Cycles.
 type Graph struct {
-	nodes map[Node]map[Node]bool
-	order []Node
+	// nodes holds the types of each link
+	nodes      map[Node]map[Node]map[EdgeType]bool
+	order      []Node
+	unresolved []Reference
 }
 
 // Empty returns a new, empty graph
 func Empty() Graph {
 	return Graph{
-		nodes: make(map[Node]map[Node]bool),
+		nodes: make(map[Node]map[Node]map[EdgeType]bool),
 		order: make([]Node, 0),
 	}
 }
 
 // New returns a Graph representing the connections
 // between elements in the provided template.
-// The type of each item in the graph is Node
+// The type of each item in the graph is Node.
+//
+// Besides Parameters, Resources and Outputs, the graph has nodes for
+// Conditions, and for Imports, which are the export names used with
+// Fn::ImportValue.
 func New(t cft.Template) Graph {
 	// Map out parameter and resource names so we know which is which
 	entities := make(map[string]string)
+	conditions := make(map[string]bool)
 	for typeName, entity := range t.Map() {
-		if typeName != "Parameters" && typeName != "Resources" {
+		if typeName != "Parameters" && typeName != "Resources" && typeName != "Conditions" {
 			continue
 		}
 
 		if entityTree, ok := entity.(map[string]interface{}); ok {
 			for entityName := range entityTree {
-				entities[entityName] = typeName
+				if typeName == "Conditions" {
+					conditions[entityName] = true
+				} else {
+					entities[entityName] = typeName
+				}
 			}
 		}
 	}
 
 	// Now find the deps
-	graph := Graph{
-		nodes: make(map[Node]map[Node]bool),
-		order: make([]Node, 0),
-	}
+	graph := Empty()
 
 	for typeName, entity := range t.Map() {
-		if typeName != "Resources" && typeName != "Outputs" {
+		if typeName != "Resources" && typeName != "Outputs" && typeName != "Conditions" {
 			continue
 		}
 
@@ -74,22 +112,49 @@ func New(t cft.Template) Graph {
 				from := Node{typeName, fromName}
 				graph.Link(from)
 
-				resource := res.(map[string]interface{})
-				for _, toName := range getRefs(resource) {
-					toName = strings.Split(toName, ".")[0]
+				resource, ok := res.(map[string]interface{})
+				if !ok {
+					// Fn::ForEach loops are not expanded here
+					continue
+				}
 
-					toType, ok := entities[toName]
+				refs := getRefs(resource)
+				if typeName == "Conditions" {
+					refs = append(refs, findConditionRefs(resource)...)
+				} else if c, ok := resource["Condition"].(string); ok {
+					refs = append(refs, ref{c, Condition})
+				}
+
+				for _, r := range refs {
+					toName := strings.Split(r.name, ".")[0]
 
-					if !ok {
-						if strings.HasPrefix(toName, "AWS::") {
-							toType = "Parameters"
-						} else {
-							config.Debugf("template has unresolved dependency '%s' at %s: %s", toName, typeName, fromName)
+					var toType string
+					switch r.kind {
+					case Condition:
+						toType = "Conditions"
+						if !conditions[toName] {
+							config.Debugf("template has unresolved condition '%s' at %s: %s", toName, typeName, fromName)
+							graph.unresolved = append(graph.unresolved, Reference{From: from, Name: toName, Type: r.kind})
 							continue
 						}
+					case ImportValue:
+						// The export is in another stack
+						toType = "Imports"
+						toName = r.name
+					default:
+						toType, ok = entities[toName]
+						if !ok {
+							if strings.HasPrefix(toName, "AWS::") {
+								toType = "Parameters"
+							} else {
+								config.Debugf("template has unresolved dependency '%s' at %s: %s", toName, typeName, fromName)
+								graph.unresolved = append(graph.unresolved, Reference{From: from, Name: toName, Type: r.kind})
+								continue
+							}
+						}
 					}
 
-					graph.Link(from, Node{toType, toName})
+					graph.LinkType(r.kind, from, Node{toType, toName})
 				}
 			}
 		}
@@ -98,6 +163,21 @@ func New(t cft.Template) Graph {
 	return graph
 }
 
+// Unresolved returns references to names that are not
+// Parameters, Resources or Conditions in the template
+func (g Graph) Unresolved() []Reference {
+	retval := make([]Reference, len(g.unresolved))
+	copy(retval, g.unresolved)
+	sort.Slice(retval, func(i, j int) bool {
+		a, b := retval[i], retval[j]
+		if a.From != b.From {
+			return fmt.Sprint(a.From) < fmt.Sprint(b.From)
+		}
+		return a.Name < b.Name
+	})
+	return retval
+}
+
 func (g *Graph) String() string {
 	out := strings.Builder{}
 
@@ -115,18 +195,31 @@ func (g *Graph) String() string {
 
 func (g *Graph) add(item Node) {
 	if _, ok := g.nodes[item]; !ok {
-		g.nodes[item] = make(map[Node]bool)
+		g.nodes[item] = make(map[Node]map[EdgeType]bool)
 		g.order = append(g.order, item)
 	}
 }
 
-// link creates a connection between two nodes in the graph
+// Link creates a connection between two nodes in the graph,
+// without an edge type
 func (g *Graph) Link(item Node, links ...Node) {
 	g.add(item)
 
 	for _, to := range links {
 		g.add(to)
-		g.nodes[item][to] = true
+		if g.nodes[item][to] == nil {
+			g.nodes[item][to] = make(map[EdgeType]bool)
+		}
+	}
+}
+
+// LinkType creates a connection of the given type between two nodes.
+// Nodes can be linked by more than one type.
+func (g *Graph) LinkType(t EdgeType, item Node, links ...Node) {
+	g.Link(item, links...)
+
+	for _, to := range links {
+		g.nodes[item][to][t] = true
 	}
 }
 
@@ -174,10 +267,13 @@ func (g Graph) Nodes() []Node {
 }
 
 // Get returns all nodes that are connected to the item that you pass in.
-func (g Graph) Get(item Node) []Node {
+// If types are passed in, only nodes linked by one of those types are returned.
+func (g Graph) Get(item Node, types ...EdgeType) []Node {
 	links := make([]Node, 0)
-	for to := range g.nodes[item] {
-		links = append(links, to)
+	for to, kinds := range g.nodes[item] {
+		if hasType(kinds, types) {
+			links = append(links, to)
+		}
 	}
 
 	sort.Slice(links, func(i, j int) bool {
@@ -188,10 +284,11 @@ func (g Graph) Get(item Node) []Node {
 }
 
 // GetReverse returns all nodes that connect to the item that you pass in.
-func (g Graph) GetReverse(item Node) []Node {
+// If types are passed in, only nodes linked by one of those types are returned.
+func (g Graph) GetReverse(item Node, types ...EdgeType) []Node {
 	links := make([]Node, 0)
 	for from, deps := range g.nodes {
-		if _, ok := deps[item]; ok {
+		if kinds, ok := deps[item]; ok && hasType(kinds, types) {
 			links = append(links, from)
 		}
 	}
@@ -202,3 +299,27 @@ func (g Graph) GetReverse(item Node) []Node {
 
 	return links
 }
+
+// Edges returns the links from the item to the nodes it depends on,
+// with their types
+func (g Graph) Edges(item Node) []Edge {
+	retval := make([]Edge, 0)
+	for _, to := range g.Get(item) {
+		retval = append(retval, g.edge(item, to))
+	}
+	return retval
+}
+
+// hasType returns true if there are no types to filter by,
+// or if one of the types is in kinds
+func hasType(kinds map[EdgeType]bool, types []EdgeType) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if kinds[t] {
+			return true
+		}
+	}
+	return false
+}
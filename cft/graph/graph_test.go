@@ -80,3 +80,207 @@ func Example_getReverse() {
 	// [Outputs/BucketArn Outputs/BucketName]
 	// []
 }
+
+func TestUnresolved(t *testing.T) {
+	tmpl, err := parse.String(`
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Ref Missing
+Outputs:
+  Arn:
+    Value: !GetAtt Nope.Arn
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := graph.New(tmpl).Unresolved()
+	if len(refs) != 2 {
+		t.Fatalf("expected 2 unresolved references, got %v", refs)
+	}
+	if refs[0].Name != "Nope" || refs[0].From != (graph.Node{Type: "Outputs", Name: "Arn"}) {
+		t.Errorf("unexpected reference %v", refs[0])
+	}
+	if refs[1].Name != "Missing" {
+		t.Errorf("unexpected reference %v", refs[1])
+	}
+}
+
+func TestCycles(t *testing.T) {
+	tmpl, err := parse.String(`
+Resources:
+  Queue:
+    Type: AWS::SQS::Queue
+    DependsOn: Function
+  Function:
+    Type: AWS::Lambda::Function
+    Properties:
+      Role: !GetAtt Role.Arn
+  Role:
+    Type: AWS::IAM::Role
+    Properties:
+      Policies:
+        - PolicyName: !Sub
+            - "${Prefix}-${Queue.Arn}"
+            - Prefix: queue
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Ref Bucket
+  Topic:
+    Type: AWS::SNS::Topic
+    Properties:
+      TopicName: !Sub
+        - "${Name}"
+        - Name: !Ref Queue
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cycles := graph.New(tmpl).Cycles()
+	if len(cycles) != 2 {
+		t.Fatalf("expected 2 cycles, got %v", cycles)
+	}
+
+	expected := "Resources/Bucket -> Resources/Bucket"
+	if cycles[0].String() != expected {
+		t.Errorf("expected %s, got %s", expected, cycles[0])
+	}
+	if cycles[0].Suggestion() != "remove one of the references, for example by moving it into a separate resource" {
+		t.Errorf("unexpected suggestion %s", cycles[0].Suggestion())
+	}
+
+	expected = "Resources/Function -> Resources/Role -> Resources/Queue -> Resources/Function"
+	if cycles[1].String() != expected {
+		t.Errorf("expected %s, got %s", expected, cycles[1])
+	}
+	if !cycles[1][2].OnlyDependsOn() || cycles[1][0].OnlyDependsOn() {
+		t.Errorf("expected only the Queue edge to be DependsOn: %v", cycles[1])
+	}
+	if cycles[1].Suggestion() != "remove Function from the DependsOn of Queue" {
+		t.Errorf("unexpected suggestion %s", cycles[1].Suggestion())
+	}
+}
+
+func TestNoCycles(t *testing.T) {
+	if cycles := g.Cycles(); len(cycles) != 0 {
+		t.Errorf("expected no cycles, got %v", cycles)
+	}
+}
+
+func TestEdgeTypes(t *testing.T) {
+	tmpl, err := parse.String(`
+Parameters:
+  Env:
+    Type: String
+Conditions:
+  IsProd: !Equals [!Ref Env, prod]
+  IsNotProd: !Not [!Condition IsProd]
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Condition: IsProd
+    Properties:
+      BucketName: !ImportValue SharedBucketName
+  Topic:
+    Type: AWS::SNS::Topic
+    DependsOn: Bucket
+    Properties:
+      TopicName: !If [IsNotProd, !GetAtt Bucket.Arn, !Ref Bucket]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := graph.New(tmpl)
+
+	topic := graph.Node{Type: "Resources", Name: "Topic"}
+	bucket := graph.Node{Type: "Resources", Name: "Bucket"}
+
+	edges := g.Edges(topic)
+	expected := "[Resources/Topic -(condition)-> Conditions/IsNotProd " +
+		"Resources/Topic -(dependsOn,getatt,ref)-> Resources/Bucket]"
+	if fmt.Sprint(edges) != expected {
+		t.Errorf("expected %s, got %v", expected, edges)
+	}
+
+	if got := fmt.Sprint(g.Get(bucket, graph.Condition)); got != "[Conditions/IsProd]" {
+		t.Errorf("unexpected conditions %s", got)
+	}
+	if got := fmt.Sprint(g.Get(bucket, graph.ImportValue)); got != "[Imports/SharedBucketName]" {
+		t.Errorf("unexpected imports %s", got)
+	}
+	if got := fmt.Sprint(g.Get(bucket)); got != "[Conditions/IsProd Imports/SharedBucketName]" {
+		t.Errorf("unexpected links %s", got)
+	}
+
+	isProd := graph.Node{Type: "Conditions", Name: "IsProd"}
+	if got := fmt.Sprint(g.GetReverse(isProd, graph.Condition)); got != "[Conditions/IsNotProd Resources/Bucket]" {
+		t.Errorf("unexpected dependents %s", got)
+	}
+	if got := fmt.Sprint(g.Get(isProd, graph.Ref)); got != "[Parameters/Env]" {
+		t.Errorf("unexpected condition refs %s", got)
+	}
+	if got := fmt.Sprint(g.GetReverse(bucket, graph.DependsOn)); got != "[Resources/Topic]" {
+		t.Errorf("unexpected DependsOn dependents %s", got)
+	}
+}
+
+func TestImpact(t *testing.T) {
+	tmpl, err := parse.String(`
+Resources:
+  Vpc:
+    Type: AWS::EC2::VPC
+  Subnet:
+    Type: AWS::EC2::Subnet
+    Properties:
+      VpcId: !Ref Vpc
+      Tags:
+        - Key: Name
+          Value: !Sub "${Vpc}-subnet"
+  Instance:
+    Type: AWS::EC2::Instance
+    Properties:
+      SubnetId: !GetAtt Subnet.SubnetId
+  Alarm:
+    Type: AWS::CloudWatch::Alarm
+    DependsOn: Vpc
+    Properties:
+      AlarmName: alarm
+Outputs:
+  InstanceId:
+    Value: !Ref Instance
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createOnly := func(typeName string) []string {
+		switch typeName {
+		case "AWS::EC2::Subnet":
+			return []string{"/properties/VpcId"}
+		case "AWS::EC2::Instance":
+			return []string{"/properties/SubnetId"}
+		case "AWS::CloudWatch::Alarm":
+			return []string{"/properties/AlarmName"}
+		}
+		return nil
+	}
+
+	impacts := graph.New(tmpl).Impact(tmpl, graph.Node{Type: "Resources", Name: "Vpc"}, createOnly)
+
+	got := make([]string, 0)
+	for _, i := range impacts {
+		got = append(got, fmt.Sprintf("%s %d %v %v", i.Node, i.Distance, i.Replaced, i.Paths))
+	}
+	expected := []string{
+		"Resources/Alarm 1 false []",
+		"Resources/Subnet 1 true [/Properties/VpcId]",
+		"Resources/Instance 2 true [/Properties/SubnetId]",
+		"Outputs/InstanceId 3 false []",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,183 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+)
+
+// Impact is an element that depends on a node that is changing
+type Impact struct {
+	Node Node
+
+	// TypeName is the resource type, for resources
+	TypeName string
+
+	// Distance is the number of links between the changing node and this one
+	Distance int
+
+	// Replaced is true if the resource will be replaced when the changing
+	// node is replaced, because a property that can only be set when the
+	// resource is created refers to a replaced resource
+	Replaced bool
+
+	// Paths are the properties that force the replacement,
+	// like /Properties/BucketName
+	Paths []string
+}
+
+// Impact returns every element that depends on target, directly or
+// through other elements. The target is assumed to be replaced, which
+// changes its physical id, or for a parameter, that its value changes.
+// Resources that use it in a property that can only be set on creation
+// are replaced too, and so on down the chain.
+//
+// createOnly returns the createOnlyProperties from the registry schema
+// for a resource type, like /properties/BucketName.
+//
+// Elements are sorted by distance from the target and then by name.
+func (g Graph) Impact(t cft.Template, target Node, createOnly func(typeName string) []string) []Impact {
+	resources, _ := t.Map()[string(cft.Resources)].(map[string]interface{})
+
+	// Find all dependents, breadth first
+	distance := map[Node]int{target: 0}
+	queue := []Node{target}
+	dependents := make([]Node, 0)
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, from := range g.GetReverse(n) {
+			if _, ok := distance[from]; ok {
+				continue
+			}
+			distance[from] = distance[n] + 1
+			dependents = append(dependents, from)
+			queue = append(queue, from)
+		}
+	}
+
+	sort.SliceStable(dependents, func(i, j int) bool {
+		a, b := dependents[i], dependents[j]
+		if distance[a] != distance[b] {
+			return distance[a] < distance[b]
+		}
+		return a.String() < b.String()
+	})
+
+	// Replacements spread to dependents until nothing else changes
+	replaced := map[string]bool{target.Name: true}
+	paths := make(map[Node][]string)
+	for changed := true; changed; {
+		changed = false
+		for _, n := range dependents {
+			if n.Type != string(cft.Resources) || replaced[n.Name] {
+				continue
+			}
+			r, _ := resources[n.Name].(map[string]interface{})
+			typeName, _ := r["Type"].(string)
+			if typeName == "" {
+				continue
+			}
+
+			found := make([]string, 0)
+			for _, p := range refPaths(r, "", replaced) {
+				if isCreateOnly(p, createOnly(typeName)) {
+					found = append(found, p)
+				}
+			}
+			if len(found) > 0 {
+				replaced[n.Name] = true
+				paths[n] = found
+				changed = true
+			}
+		}
+	}
+
+	retval := make([]Impact, 0)
+	for _, n := range dependents {
+		impact := Impact{Node: n, Distance: distance[n]}
+		if n.Type == string(cft.Resources) {
+			r, _ := resources[n.Name].(map[string]interface{})
+			impact.TypeName, _ = r["Type"].(string)
+			impact.Replaced = replaced[n.Name]
+			impact.Paths = paths[n]
+		}
+		retval = append(retval, impact)
+	}
+
+	return retval
+}
+
+// refPaths returns the paths of intrinsic functions in v that refer
+// to one of the names. Paths are JSON pointers from v.
+func refPaths(v interface{}, path string, names map[string]bool) []string {
+	retval := make([]string, 0)
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if isIntrinsic(t) {
+			for _, r := range findRefs(t) {
+				if names[r.name] && r.kind != DependsOn {
+					return append(retval, path)
+				}
+			}
+			return retval
+		}
+		keys := make([]string, 0)
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if path == "" && k != "Properties" {
+				// DependsOn, Condition and Metadata don't go to the resource
+				continue
+			}
+			retval = append(retval, refPaths(t[k], path+"/"+k, names)...)
+		}
+	case []interface{}:
+		for i, child := range t {
+			retval = append(retval, refPaths(child, fmt.Sprintf("%s/%d", path, i), names)...)
+		}
+	}
+
+	return retval
+}
+
+// isIntrinsic returns true for a map with a single key like Ref or Fn::Sub
+func isIntrinsic(m map[string]interface{}) bool {
+	if len(m) != 1 {
+		return false
+	}
+	for k := range m {
+		return k == "Ref" || strings.HasPrefix(k, "Fn::")
+	}
+	return false
+}
+
+// isCreateOnly returns true if the path is a createOnly property, or is
+// inside one, or contains one. A * in the schema path matches any key
+// or list index.
+func isCreateOnly(path string, createOnly []string) bool {
+	a := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for _, p := range createOnly {
+		name, found := strings.CutPrefix(p, "/properties/")
+		if !found {
+			continue
+		}
+		b := append([]string{"Properties"}, strings.Split(name, "/")...)
+		matches := true
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if b[i] != "*" && a[i] != b[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
@@ -8,11 +8,19 @@ import (
 	"github.com/aws-cloudformation/rain/internal/config"
 )
 
-func getRefs(t map[string]interface{}) []string {
+// ref is a name that an element refers to, and how it refers to it
+type ref struct {
+	name string
+	kind EdgeType
+}
+
+func getRefs(t map[string]interface{}) []ref {
 	return findRefs(t)
 }
 
-func parseSubString(refs []string, substr string) []string {
+// parseSubString adds the names referenced in a Sub string to refs.
+// Variables that are defined in vars are not references.
+func parseSubString(refs []ref, substr string, vars map[string]interface{}) []ref {
 	words, err := parse.ParseSub(substr, false)
 	if err != nil {
 		config.Debugf("Unable to parse Sub %s: %v", substr, err)
@@ -22,15 +30,17 @@ func parseSubString(refs []string, substr string) []string {
 	for _, word := range words {
 		switch word.T {
 		case parse.AWS:
-			refs = append(refs, fmt.Sprintf("AWS::%s", word.W))
+			refs = append(refs, ref{fmt.Sprintf("AWS::%s", word.W), Ref})
 		case parse.REF:
-			refs = append(refs, word.W)
+			if _, ok := vars[word.W]; !ok {
+				refs = append(refs, ref{word.W, Ref})
+			}
 		case parse.GETATT:
 			left, _, found := strings.Cut(word.W, ".")
 			if !found {
 				config.Debugf("unexpected GetAtt %s", word.W)
-			} else {
-				refs = append(refs, left)
+			} else if _, ok := vars[left]; !ok {
+				refs = append(refs, ref{left, GetAtt})
 			}
 		}
 	}
@@ -40,40 +50,59 @@ func parseSubString(refs []string, substr string) []string {
 	return refs
 }
 
-func findRefs(t map[string]interface{}) []string {
-	refs := make([]string, 0)
+func findRefs(t map[string]interface{}) []ref {
+	refs := make([]ref, 0)
 
 	for key, value := range t {
 		switch key {
 		case "DependsOn":
 			switch v := value.(type) {
 			case string:
-				refs = append(refs, v)
+				refs = append(refs, ref{v, DependsOn})
 			case []interface{}:
 				for _, d := range v {
-					refs = append(refs, d.(string))
+					refs = append(refs, ref{d.(string), DependsOn})
 				}
 			default:
 				config.Debugf("invalid DependsOn: %v, %v", key, value)
 			}
 		case "Ref":
-			refs = append(refs, value.(string))
+			refs = append(refs, ref{value.(string), Ref})
 		case "Fn::GetAtt":
 			switch v := value.(type) {
 			case string:
 				parts := strings.Split(v, ".")
-				refs = append(refs, parts[0])
+				refs = append(refs, ref{parts[0], GetAtt})
 			case []interface{}:
 				if s, ok := v[0].(string); ok {
-					refs = append(refs, s)
+					refs = append(refs, ref{s, GetAtt})
 				}
 			default:
 				fmt.Printf("Malformed GetAtt: %T\n", v)
 			}
+		case "Fn::If":
+			if v, ok := value.([]interface{}); ok && len(v) == 3 {
+				if s, ok := v[0].(string); ok {
+					refs = append(refs, ref{s, Condition})
+				}
+			}
+			for _, tree := range findTrees(value) {
+				refs = append(refs, findRefs(tree)...)
+			}
+		case "Fn::ImportValue":
+			switch v := value.(type) {
+			case string:
+				refs = append(refs, ref{v, ImportValue})
+			default:
+				// The export name is built with intrinsics
+				for _, tree := range findTrees(value) {
+					refs = append(refs, findRefs(tree)...)
+				}
+			}
 		case "Fn::Sub":
 			switch v := value.(type) {
 			case string:
-				refs = parseSubString(refs, v)
+				refs = parseSubString(refs, v, nil)
 			case []interface{}:
 				switch {
 				case len(v) != 2:
@@ -81,10 +110,15 @@ func findRefs(t map[string]interface{}) []string {
 				default:
 					switch parts := v[1].(type) {
 					case map[string]interface{}:
+						if s, ok := v[0].(string); ok {
+							refs = parseSubString(refs, s, parts)
+						}
 						for _, part := range parts {
 							switch p := part.(type) {
 							case map[string]interface{}:
 								refs = append(refs, findRefs(p)...)
+							case string:
+								// A literal value for the variable
 							default:
 								fmt.Printf("Malformed Sub: %T\n", v)
 							}
@@ -106,6 +140,22 @@ func findRefs(t map[string]interface{}) []string {
 	return refs
 }
 
+// findConditionRefs finds conditions used inside a condition,
+// like Fn::And: [{Condition: A}, {Condition: B}]
+func findConditionRefs(t map[string]interface{}) []ref {
+	refs := make([]ref, 0)
+	for key, value := range t {
+		if s, ok := value.(string); ok && key == "Condition" {
+			refs = append(refs, ref{s, Condition})
+			continue
+		}
+		for _, tree := range findTrees(value) {
+			refs = append(refs, findConditionRefs(tree)...)
+		}
+	}
+	return refs
+}
+
 func findTrees(value interface{}) []map[string]interface{} {
 	trees := make([]map[string]interface{}, 0)
 
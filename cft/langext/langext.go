@@ -0,0 +1,431 @@
+// Package langext expands the AWS::LanguageExtensions transform locally,
+// so that tools can see the resources, outputs and conditions that
+// Fn::ForEach loops make without deploying the template.
+//
+// Supported:
+//
+//	Fn::ForEach in Resources, Outputs and Conditions, and in resource properties,
+//	  including nested loops, and ${} and &{} identifiers in keys
+//	Fn::Length
+//	Fn::ToJsonString
+//	Fn::FindInMap with a DefaultValue
+//	Intrinsic functions in DeletionPolicy and UpdateReplacePolicy
+//
+// Loop collections and function arguments that refer to parameters use the
+// values in Options.Config, or the Default in the template, so the expanded
+// template is only correct for those values. With Options.NoDefaults, anything
+// that depends on a parameter that is not in Config is left for the transform.
+package langext
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/eval"
+	"github.com/aws-cloudformation/rain/internal/node"
+	"github.com/aws-cloudformation/rain/internal/s11n"
+	"github.com/aws-cloudformation/rain/plugins/deployconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// Transform is the name of the transform that this package expands
+const Transform = "AWS::LanguageExtensions"
+
+const forEach = "Fn::ForEach::"
+
+var notAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// Options configures Expand
+type Options struct {
+	// Config holds parameter values. Parameters missing from Config
+	// fall back to the Default in the template, unless NoDefaults is set.
+	Config *deployconfig.DeployConfig
+
+	// Pseudo holds values for pseudo parameters, keyed by the full
+	// name, for example AWS::Region
+	Pseudo map[string]string
+
+	// NoDefaults leaves loops and functions that depend on parameters
+	// missing from Config for the transform, instead of using the
+	// parameter's Default. Use this when the template will be deployed
+	// with parameter values that are not known yet.
+	NoDefaults bool
+}
+
+// Uses returns true if the template has the AWS::LanguageExtensions transform
+func Uses(t cft.Template) bool {
+	transform, err := t.GetSection(cft.Transform)
+	if err != nil {
+		return false
+	}
+	if transform.Kind == yaml.SequenceNode {
+		for _, c := range transform.Content {
+			if c.Value == Transform {
+				return true
+			}
+		}
+		return false
+	}
+	return transform.Value == Transform
+}
+
+// Expand returns a copy of t with the AWS::LanguageExtensions transform
+// applied. The transform is removed from the copy, unless something could
+// not be expanded, like a Fn::Length of a list that depends on a resource.
+func Expand(t cft.Template, opts Options) (cft.Template, error) {
+	retval := cft.Template{
+		Node:      node.Clone(t.Node),
+		Constants: t.Constants,
+		Packages:  t.Packages,
+	}
+
+	e, err := eval.New(t, eval.Options{Config: opts.Config, Pseudo: opts.Pseudo, NoDefaults: opts.NoDefaults})
+	if err != nil {
+		return retval, err
+	}
+	x := expander{e: e, complete: true}
+
+	for _, section := range []cft.Section{cft.Conditions, cft.Resources, cft.Outputs} {
+		n, err := retval.GetSection(section)
+		if err != nil {
+			continue
+		}
+		if err := x.walk(n); err != nil {
+			return retval, fmt.Errorf("%s: %w", section, err)
+		}
+	}
+
+	if resources, err := retval.GetSection(cft.Resources); err == nil {
+		for i := 0; i < len(resources.Content); i += 2 {
+			x.policies(resources.Content[i+1])
+		}
+	}
+
+	if x.complete {
+		removeTransform(retval)
+	}
+
+	return retval, nil
+}
+
+type expander struct {
+	e *eval.Evaluator
+
+	// complete is false if anything was left for the transform to do
+	complete bool
+}
+
+// functions are the intrinsic functions that the transform adds or changes
+var functions = []string{"Fn::Length", "Fn::ToJsonString", "Fn::FindInMap"}
+
+// walk expands loops and functions in n and its children, in place
+func (x *expander) walk(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			if err := x.walk(c); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		if len(n.Content) == 2 && slices.Contains(functions, n.Content[0].Value) {
+			if err := x.walk(n.Content[1]); err != nil {
+				return err
+			}
+			x.function(n)
+			return nil
+		}
+
+		content := make([]*yaml.Node, 0)
+		for i := 0; i < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if strings.HasPrefix(key.Value, forEach) {
+				expanded, err := x.loop(key.Value, value)
+				if errors.Is(err, eval.ErrUnresolved) {
+					// Leave the loop for the transform
+					x.complete = false
+					content = append(content, key, value)
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				content = append(content, expanded...)
+				continue
+			}
+			if err := x.walk(value); err != nil {
+				return err
+			}
+			content = append(content, key, value)
+		}
+
+		for i := 0; i < len(content); i += 2 {
+			for j := 0; j < i; j += 2 {
+				if content[i].Value == content[j].Value {
+					return fmt.Errorf("'%s' is used more than once", content[i].Value)
+				}
+			}
+		}
+		n.Content = content
+	}
+	return nil
+}
+
+// function evaluates a Fn::Length, Fn::ToJsonString or Fn::FindInMap with
+// a DefaultValue, and leaves it in place if it can't be resolved
+func (x *expander) function(n *yaml.Node) {
+	name, arg := n.Content[0].Value, n.Content[1]
+	if name == "Fn::FindInMap" && (arg.Kind != yaml.SequenceNode || len(arg.Content) != 4) {
+		// This is a normal FindInMap, which CloudFormation resolves
+		return
+	}
+
+	v, err := x.e.Node(n)
+	if err != nil || v == nil || hasFunction(v, name) {
+		x.complete = false
+		return
+	}
+	*n = *v
+}
+
+// hasFunction returns true if n still has a call to the function
+func hasFunction(n *yaml.Node, name string) bool {
+	if n.Kind == yaml.MappingNode && len(n.Content) == 2 && n.Content[0].Value == name {
+		return true
+	}
+	for _, c := range n.Content {
+		if hasFunction(c, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// loop expands a Fn::ForEach, and returns the keys and values it makes
+func (x *expander) loop(name string, value *yaml.Node) ([]*yaml.Node, error) {
+	if value.Kind != yaml.SequenceNode || len(value.Content) != 3 {
+		return nil, fmt.Errorf("%s: expected a list of an identifier, a collection and an output map", name)
+	}
+	identifier, collection, output := value.Content[0], value.Content[1], value.Content[2]
+	if identifier.Kind != yaml.ScalarNode || identifier.Value == "" {
+		return nil, fmt.Errorf("%s: expected the identifier to be a string", name)
+	}
+	if output.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected the output to be a map", name)
+	}
+
+	items, err := x.collection(collection)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	retval := make([]*yaml.Node, 0)
+	for _, item := range items {
+		body := node.Clone(output)
+		replace(body, identifier.Value, item)
+
+		// Expand nested loops before their keys are merged with other items
+		if err := x.walk(body); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		if mentions(body, identifier.Value) {
+			// Something uses the identifier in a way that replace does not
+			// handle, so the expanded template would not be valid
+			return nil, fmt.Errorf("%s: unable to replace every use of %s: %w",
+				name, identifier.Value, eval.ErrUnresolved)
+		}
+
+		retval = append(retval, body.Content...)
+	}
+
+	return retval, nil
+}
+
+// collection resolves the list that a loop goes through
+func (x *expander) collection(n *yaml.Node) ([]string, error) {
+	v, err := x.e.Node(n)
+	if err != nil {
+		return nil, err
+	}
+	if v != nil && v.Kind == yaml.MappingNode {
+		// An intrinsic function that can't be resolved locally
+		return nil, fmt.Errorf("unable to resolve the collection: %w", eval.ErrUnresolved)
+	}
+	if v == nil || v.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("unable to resolve the collection to a list")
+	}
+	retval := make([]string, 0)
+	for _, c := range v.Content {
+		if c.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("unable to resolve the collection to a list of strings")
+		}
+		retval = append(retval, c.Value)
+	}
+	return retval, nil
+}
+
+// replace replaces the loop identifier with the value of an item. Keys can
+// have ${Identifier}, or &{Identifier} for the value without any characters
+// that are not alphanumeric. Values can use a Ref to the identifier, or
+// either form in a Fn::Sub, or in the logical id of a Ref or Fn::GetAtt,
+// like !GetAtt Topic&{Name}.TopicArn.
+func replace(n *yaml.Node, identifier, value string) {
+	r := strings.NewReplacer(
+		"${"+identifier+"}", value,
+		"&{"+identifier+"}", notAlphanumeric.ReplaceAllString(value, ""))
+
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		switch n.Kind {
+		case yaml.SequenceNode:
+			for _, c := range n.Content {
+				walk(c)
+			}
+		case yaml.MappingNode:
+			if len(n.Content) == 2 {
+				key, arg := n.Content[0].Value, n.Content[1]
+				if key == "Ref" && arg.Kind == yaml.ScalarNode && arg.Value == identifier {
+					*n = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+					return
+				}
+				switch key {
+				case "Fn::Sub":
+					sub(arg, identifier, r)
+				case "Ref", "Fn::GetAtt":
+					logicalId(arg, identifier, value, r)
+					return
+				}
+			}
+			for i := 0; i < len(n.Content); i += 2 {
+				n.Content[i].Value = r.Replace(n.Content[i].Value)
+				walk(n.Content[i+1])
+			}
+		}
+	}
+	walk(n)
+}
+
+// sub replaces the identifier in a Fn::Sub string, unless the
+// Sub has a variable with the same name
+func sub(n *yaml.Node, identifier string, r *strings.Replacer) {
+	s := n
+	if n.Kind == yaml.SequenceNode {
+		if len(n.Content) != 2 {
+			return
+		}
+		if _, v, _ := s11n.GetMapValue(n.Content[1], identifier); v != nil {
+			return
+		}
+		s = n.Content[0]
+	}
+	if s.Kind == yaml.ScalarNode {
+		s.Value = r.Replace(s.Value)
+	}
+}
+
+// logicalId replaces the identifier in the logical id of a Ref or Fn::GetAtt.
+// The logical id can also be a Ref to the identifier, like
+// !GetAtt [!Ref Identifier, Arn]. A Fn::Sub that no longer has any
+// variables becomes a plain string.
+func logicalId(n *yaml.Node, identifier, value string, r *strings.Replacer) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		n.Value = r.Replace(n.Value)
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			logicalId(c, identifier, value, r)
+		}
+	case yaml.MappingNode:
+		if len(n.Content) != 2 || n.Content[1].Kind != yaml.ScalarNode {
+			return
+		}
+		switch n.Content[0].Value {
+		case "Ref":
+			if n.Content[1].Value == identifier {
+				*n = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+			}
+		case "Fn::Sub":
+			s := r.Replace(n.Content[1].Value)
+			n.Content[1].Value = s
+			if !strings.Contains(s, "${") {
+				*n = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+			}
+		}
+	}
+}
+
+// mentions returns true if n still refers to a loop identifier,
+// other than as a variable of a Fn::Sub
+func mentions(n *yaml.Node, identifier string) bool {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return strings.Contains(n.Value, "${"+identifier+"}") ||
+			strings.Contains(n.Value, "&{"+identifier+"}")
+	case yaml.MappingNode:
+		if len(n.Content) == 2 {
+			key, arg := n.Content[0].Value, n.Content[1]
+			if key == "Ref" && arg.Kind == yaml.ScalarNode && arg.Value == identifier {
+				return true
+			}
+			if key == "Fn::Sub" && arg.Kind == yaml.SequenceNode && len(arg.Content) == 2 {
+				if _, v, _ := s11n.GetMapValue(arg.Content[1], identifier); v != nil {
+					return mentions(arg.Content[1], identifier)
+				}
+			}
+		}
+	}
+	for _, c := range n.Content {
+		if mentions(c, identifier) {
+			return true
+		}
+	}
+	return false
+}
+
+// policies evaluates intrinsic functions in a resource's
+// DeletionPolicy and UpdateReplacePolicy
+func (x *expander) policies(resource *yaml.Node) {
+	if resource.Kind != yaml.MappingNode {
+		return
+	}
+	for _, name := range []string{"DeletionPolicy", "UpdateReplacePolicy"} {
+		_, p, _ := s11n.GetMapValue(resource, name)
+		if p == nil || p.Kind != yaml.MappingNode {
+			continue
+		}
+		v, err := x.e.Node(p)
+		if err != nil || v == nil || v.Kind != yaml.ScalarNode {
+			x.complete = false
+			continue
+		}
+		*p = *v
+	}
+}
+
+// removeTransform removes AWS::LanguageExtensions from the Transform section
+func removeTransform(t cft.Template) {
+	root := t.Node.Content[0]
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value != string(cft.Transform) {
+			continue
+		}
+		transform := root.Content[i+1]
+		if transform.Kind == yaml.SequenceNode {
+			transform.Content = slices.DeleteFunc(transform.Content, func(n *yaml.Node) bool {
+				return n.Value == Transform
+			})
+			if len(transform.Content) > 0 {
+				return
+			}
+		} else if transform.Value != Transform {
+			return
+		}
+		root.Content = slices.Delete(root.Content, i, i+2)
+		return
+	}
+}
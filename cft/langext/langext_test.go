@@ -0,0 +1,262 @@
+package langext_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/langext"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/plugins/deployconfig"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+)
+
+const source = `
+Transform: AWS::LanguageExtensions
+Parameters:
+  Topics:
+    Type: CommaDelimitedList
+    Default: a-1,b-2
+Mappings:
+  Sizes:
+    a-1:
+      Size: 10
+Conditions:
+  Fn::ForEach::Conditions:
+    - T
+    - !Ref Topics
+    - Is&{T}: !Equals [!Ref T, a-1]
+Resources:
+  Fn::ForEach::Topics:
+    - T
+    - !Ref Topics
+    - Topic&{T}:
+        Type: AWS::SNS::Topic
+        Properties:
+          TopicName: !Ref T
+          DisplayName: !Sub ${T}-topic
+          Size: !FindInMap [Sizes, !Ref T, Size, {DefaultValue: 5}]
+      Fn::ForEach::Subscriptions:
+        - S
+        - [x, y]
+        - Sub${S}&{T}:
+            Type: AWS::SNS::Subscription
+            Properties:
+              TopicArn: !Ref Topic&{T}
+  Count:
+    Type: AWS::SSM::Parameter
+    Properties:
+      Value:
+        Fn::Length: !Ref Topics
+      Json:
+        Fn::ToJsonString:
+          a: [1, 2]
+Outputs:
+  Fn::ForEach::Outputs:
+    - T
+    - !Ref Topics
+    - Out&{T}:
+        Value: !GetAtt Topic&{T}.TopicArn
+`
+
+func expand(t *testing.T, source string, opts langext.Options) string {
+	t.Helper()
+
+	tmpl, err := parse.String(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !langext.Uses(tmpl) {
+		t.Fatal("expected the template to use the transform")
+	}
+
+	expanded, err := langext.Expand(tmpl, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return format.String(expanded, format.Options{Unsorted: true})
+}
+
+func TestExpand(t *testing.T) {
+	out := expand(t, source, langext.Options{})
+
+	for _, expected := range []string{
+		"Isa1: !Equals",
+		"Isb2: !Equals",
+		"Topica1:",
+		"Topicb2:",
+		"TopicName: a-1",
+		"DisplayName: !Sub b-2-topic",
+		"Size: 10",
+		"Size: 5",
+		"Subxa1:",
+		"Subyb2:",
+		"TopicArn: !Ref Topicb2",
+		"Value: 2",
+		`Json: '{"a":[1,2]}'`,
+		"Outa1:",
+		"Value: !GetAtt Topicb2.TopicArn",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected '%s' in:\n%s", expected, out)
+		}
+	}
+
+	for _, unexpected := range []string{"Fn::ForEach", "Transform", "&{", "DefaultValue"} {
+		if strings.Contains(out, unexpected) {
+			t.Errorf("did not expect '%s' in:\n%s", unexpected, out)
+		}
+	}
+}
+
+func TestExpandConfig(t *testing.T) {
+	out := expand(t, source, langext.Options{
+		Config: &deployconfig.DeployConfig{
+			Params: []types.Parameter{
+				{ParameterKey: ptr.String("Topics"), ParameterValue: ptr.String("c")},
+			},
+		},
+	})
+
+	if !strings.Contains(out, "Topicc:") || strings.Contains(out, "Topica1:") {
+		t.Errorf("expected the loop to use the config value:\n%s", out)
+	}
+}
+
+func TestExpandIncomplete(t *testing.T) {
+	out := expand(t, `
+Transform:
+  - AWS::LanguageExtensions
+  - AWS::Serverless-2016-10-31
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+  Count:
+    Type: AWS::SSM::Parameter
+    Properties:
+      Value:
+        Fn::Length: !GetAtt Bucket.Arns
+`, langext.Options{})
+
+	if !strings.Contains(out, "AWS::LanguageExtensions") {
+		t.Errorf("expected the transform to be kept:\n%s", out)
+	}
+}
+
+func TestExpandDuplicate(t *testing.T) {
+	tmpl, err := parse.String(`
+Transform: AWS::LanguageExtensions
+Resources:
+  Fn::ForEach::Topics:
+    - T
+    - [a, b]
+    - Topic:
+        Type: AWS::SNS::Topic
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := langext.Expand(tmpl, langext.Options{}); err == nil {
+		t.Error("expected an error for a duplicate logical id")
+	}
+}
+
+func expandFile(t *testing.T, path string, opts langext.Options) string {
+	t.Helper()
+
+	tmpl, err := parse.File(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expanded, err := langext.Expand(tmpl, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return format.String(expanded, format.Options{Unsorted: true})
+}
+
+func TestExpandNestedRef(t *testing.T) {
+	out := expandFile(t, "../../test/templates/foreach-ec2.yaml", langext.Options{})
+
+	for _, expected := range []string{
+		"FirstInstance:",
+		"InstanceType: t2.micro",
+		"ThirdInstanceId:",
+		"Value: !Ref ThirdInstance",
+		"Value: !GetAtt ThirdInstance.AvailabilityZone",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected '%s' in:\n%s", expected, out)
+		}
+	}
+
+	for _, unexpected := range []string{"InstanceLogicalId", "Transform"} {
+		if strings.Contains(out, unexpected) {
+			t.Errorf("did not expect '%s' in:\n%s", unexpected, out)
+		}
+	}
+}
+
+func TestExpandUnreplacedIdentifier(t *testing.T) {
+	// The identifier is used in a way that can't be replaced
+	out := expand(t, `
+Transform: AWS::LanguageExtensions
+Resources:
+  Fn::ForEach::Topics:
+    - T
+    - [a, b]
+    - Topic${T}:
+        Type: AWS::SNS::Topic
+        Properties:
+          TopicName: {Ref: {Ref: {Ref: T}}}
+`, langext.Options{})
+
+	if !strings.Contains(out, "Fn::ForEach::Topics") || !strings.Contains(out, "Transform") {
+		t.Errorf("expected the loop and the transform to be kept:\n%s", out)
+	}
+}
+
+func TestExpandNoDefaults(t *testing.T) {
+	path := "../../test/templates/foreach-ec2.yaml"
+
+	out := expandFile(t, path, langext.Options{NoDefaults: true})
+	for _, expected := range []string{
+		"Transform: AWS::LanguageExtensions",
+		"FirstInstance:",
+		"InstanceType: !FindInMap",
+		"- !Ref Environment",
+		"Fn::ForEach::InstanceOutputs",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected '%s' in:\n%s", expected, out)
+		}
+	}
+
+	out = expandFile(t, path, langext.Options{
+		NoDefaults: true,
+		Config: &deployconfig.DeployConfig{
+			Params: []types.Parameter{
+				{ParameterKey: ptr.String("Environment"), ParameterValue: ptr.String("prod")},
+				{ParameterKey: ptr.String("InstancesToManage"), ParameterValue: ptr.String("FirstInstance")},
+			},
+		},
+	})
+	for _, expected := range []string{
+		"InstanceType: t2.large",
+		"InstanceType: t2.2xlarge",
+		"FirstInstanceId:",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected '%s' in:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "Transform") || strings.Contains(out, "SecondInstanceId") {
+		t.Errorf("expected everything to be expanded with the config values:\n%s", out)
+	}
+}
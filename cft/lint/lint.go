@@ -0,0 +1,275 @@
+// Package lint checks templates offline for problems that would cause
+// a deployment to fail, using the registry schemas embedded in rain.
+//
+// Each problem is reported as a Finding with a stable rule code that
+// can be suppressed, in the same way as forecast codes.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/graph"
+	"github.com/aws-cloudformation/rain/internal/aws/cfn"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule codes. Do not change or reuse these, since users refer
+// to them in --ignore
+const (
+	L0001 = "L0001" // Unknown resource type
+	L0002 = "L0002" // Missing required property
+	L0003 = "L0003" // Unknown property
+	L0004 = "L0004" // Value not in enum
+	L0005 = "L0005" // Value does not match pattern
+	L0006 = "L0006" // Type mismatch
+	L0007 = "L0007" // Reference to an undefined name
+	L0008 = "L0008" // Read-only property set in the template
+	L0009 = "L0009" // Value length out of range
+	L0010 = "L0010" // Parameter type does not match the property
+	L0011 = "L0011" // Parameter value is not valid for the property
+	L0012 = "L0012" // Parameter value is not allowed by the parameter
+	L0013 = "L0013" // Circular dependency
+)
+
+// Severity indicates how serious a finding is
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Finding is a single problem found in a template
+type Finding struct {
+	Code      string
+	Severity  Severity
+	LogicalId string
+	TypeName  string
+
+	// Path is the path to the property, like Properties/BucketName
+	Path    string
+	Message string
+	Line    int
+}
+
+func (f Finding) String() string {
+	where := f.LogicalId
+	if f.TypeName != "" {
+		where = fmt.Sprintf("%s %s", f.TypeName, f.LogicalId)
+	}
+	if f.Path != "" {
+		where = fmt.Sprintf("%s %s", where, f.Path)
+	}
+	if f.Line == 0 {
+		// Packaged templates don't have line numbers
+		return fmt.Sprintf("%s %s: %s - %s", f.Code, strings.ToUpper(string(f.Severity)),
+			where, f.Message)
+	}
+	return fmt.Sprintf("%s %s on line %d: %s - %s", f.Code, strings.ToUpper(string(f.Severity)),
+		f.Line, where, f.Message)
+}
+
+// Options configures the linter
+type Options struct {
+	// Ignore is a list of rule codes and resource types to skip
+	Ignore []string
+
+	// Params are the parameter values that will be used to deploy
+	// the template. Parameters that are not set here use their Default.
+	Params map[string]string
+}
+
+// Template checks a template and returns the findings, ordered by line
+func Template(t cft.Template, opts Options) ([]Finding, error) {
+	model, err := t.Model()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{opts: opts, model: model, findings: make([]Finding, 0)}
+
+	for _, logicalId := range model.Names(cft.Resources) {
+		l.resource(model.Resources[logicalId])
+	}
+
+	l.parameters()
+
+	g := graph.New(t)
+	l.references(g)
+	l.cycles(g)
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		return l.findings[i].Line < l.findings[j].Line
+	})
+
+	return l.findings, nil
+}
+
+// Errors returns the number of findings with Error severity
+func Errors(findings []Finding) int {
+	count := 0
+	for _, f := range findings {
+		if f.Severity == Error {
+			count++
+		}
+	}
+	return count
+}
+
+type linter struct {
+	opts     Options
+	model    *cft.Model
+	findings []Finding
+}
+
+// add records a finding unless it is ignored
+func (l *linter) add(f Finding) {
+	if slices.Contains(l.opts.Ignore, f.Code) ||
+		(f.TypeName != "" && slices.Contains(l.opts.Ignore, f.TypeName)) {
+		return
+	}
+	if f.Severity == "" {
+		f.Severity = Error
+	}
+	l.findings = append(l.findings, f)
+}
+
+// typeNameRe matches registry type names like AWS::S3::Bucket.
+// Anything else is probably a Rain module.
+var typeNameRe = regexp.MustCompile(`^[A-Za-z0-9]+::[A-Za-z0-9]+::[A-Za-z0-9]+$`)
+
+// skipType returns true for types that don't have registry schemas
+func skipType(typeName string) bool {
+	return !typeNameRe.MatchString(typeName) ||
+		strings.HasPrefix(typeName, "Custom::") ||
+		strings.HasPrefix(typeName, "AWS::Serverless::") ||
+		typeName == "AWS::CloudFormation::CustomResource" ||
+		typeName == "AWS::CDK::Metadata"
+}
+
+// notRequired lists properties that are marked as required in the
+// registry schema but can't be set in a template
+var notRequired = map[string][]string{
+	"AWS::CloudFormation::Stack": {"StackName"},
+}
+
+func (l *linter) resource(r *cft.ModelResource) {
+	typeNode := r.Get("Type")
+	if typeNode == nil || typeNode.Kind != yaml.ScalarNode {
+		// Probably a Rain module that has not been packaged
+		config.Debugf("lint skipping %s, no scalar Type", r.Name)
+		return
+	}
+	typeName := typeNode.Value
+	if skipType(typeName) {
+		return
+	}
+
+	schema, err := cfn.GetSchema(typeName, cfn.OnlyUseCache)
+	if err != nil {
+		config.Debugf("lint unable to get schema for %s: %v", typeName, err)
+		l.add(Finding{Code: L0001, Severity: Warning, LogicalId: r.Name, TypeName: typeName,
+			Message: fmt.Sprintf("no schema found for type %s", typeName),
+			Line:    typeNode.Line})
+		return
+	}
+
+	v := &validator{l: l, schema: schema, logicalId: r.Name, typeName: typeName}
+	props := r.Properties()
+	if props == nil {
+		props = &yaml.Node{Kind: yaml.MappingNode}
+	}
+	if isIntrinsic(props) {
+		return
+	}
+	required := slices.DeleteFunc(slices.Clone(schema.Required), func(s string) bool {
+		return slices.Contains(notRequired[typeName], s)
+	})
+	v.object("Properties", props, r.Line(), schema.Properties, required, true)
+
+	for _, ro := range schema.ReadOnlyProperties {
+		name := strings.TrimPrefix(ro, "/properties/")
+		if strings.Contains(name, "/") {
+			continue
+		}
+		_, val, ok := getMapValue(props, name)
+		if ok {
+			l.add(Finding{Code: L0008, LogicalId: r.Name, TypeName: typeName,
+				Path:    "Properties/" + name,
+				Message: "read-only property can't be set in a template",
+				Line:    val.Line})
+		}
+	}
+}
+
+// references reports Refs, GetAtts, Sub variables and conditions
+// that don't point to anything in the template
+func (l *linter) references(g graph.Graph) {
+	for _, ref := range g.Unresolved() {
+		line := 0
+		typeName := ""
+		switch ref.From.Type {
+		case string(cft.Resources):
+			if r := l.model.Resources[ref.From.Name]; r != nil {
+				line = r.Line()
+				typeName = r.Type()
+			}
+		case string(cft.Outputs):
+			if o := l.model.Outputs[ref.From.Name]; o != nil {
+				line = o.Line()
+			}
+		}
+		message := fmt.Sprintf("%s is not a parameter or resource in the template", ref.Name)
+		if ref.Type == graph.Condition {
+			message = fmt.Sprintf("%s is not a condition in the template", ref.Name)
+		}
+		l.add(Finding{Code: L0007, LogicalId: ref.From.Name, TypeName: typeName,
+			Message: message, Line: line})
+	}
+}
+
+// cycles reports circular dependencies, with the chain of references
+// and a suggestion for how to break it
+func (l *linter) cycles(g graph.Graph) {
+	for _, c := range g.Cycles() {
+		from := c[0].From
+		line := 0
+		typeName := ""
+		if r := l.model.Resources[from.Name]; r != nil && from.Type == string(cft.Resources) {
+			line = r.Line()
+			typeName = r.Type()
+		}
+		l.add(Finding{Code: L0013, LogicalId: from.Name, TypeName: typeName,
+			Message: fmt.Sprintf("circular dependency %s; %s", c, c.Suggestion()),
+			Line:    line})
+	}
+}
+
+func getMapValue(n *yaml.Node, name string) (*yaml.Node, *yaml.Node, bool) {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil, nil, false
+	}
+	for i := 0; i < len(n.Content); i += 2 {
+		if n.Content[i].Value == name {
+			return n.Content[i], n.Content[i+1], true
+		}
+	}
+	return nil, nil, false
+}
+
+// isIntrinsic returns true if the node is an intrinsic function,
+// which can't be checked against the schema
+func isIntrinsic(n *yaml.Node) bool {
+	if n.Kind != yaml.MappingNode || len(n.Content) != 2 {
+		return false
+	}
+	k := n.Content[0].Value
+	return k == "Ref" || strings.HasPrefix(k, "Fn::") ||
+		strings.HasPrefix(k, "Rain::") || strings.HasPrefix(k, "!Rain::")
+}
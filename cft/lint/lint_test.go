@@ -0,0 +1,228 @@
+package lint_test
+
+import (
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/lint"
+	"github.com/aws-cloudformation/rain/cft/parse"
+)
+
+const source = `
+Parameters:
+  Name:
+    Type: String
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Ref Name
+      AccelerateConfiguration:
+        AccelerationStatus: Fast
+      Foo: bar
+      Arn: arn:aws:s3:::foo
+  Table:
+    Type: AWS::DynamoDB::Table
+    Properties:
+      BillingMode: PAY_PER_REQUEST
+      TableName: x
+      KeySchema: not-a-list
+  Topic:
+    Type: AWS::SNS::Topic
+    Properties:
+      TopicName: !Ref Missing
+  Pail:
+    Type: AWS::S3::Pail
+  Custom:
+    Type: Custom::Thing
+    Properties:
+      Anything: goes
+`
+
+func codes(findings []lint.Finding) map[string]int {
+	retval := make(map[string]int)
+	for _, f := range findings {
+		retval[f.Code]++
+	}
+	return retval
+}
+
+func TestTemplate(t *testing.T) {
+	tmpl, err := parse.String(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := lint.Template(tmpl, lint.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range findings {
+		t.Log(f)
+	}
+
+	expected := map[string]int{
+		lint.L0001: 1, // AWS::S3::Pail
+		lint.L0002: 0,
+		lint.L0003: 1, // Foo
+		lint.L0004: 1, // Fast
+		lint.L0006: 1, // KeySchema
+		lint.L0007: 1, // Missing
+		lint.L0008: 1, // Arn
+	}
+	got := codes(findings)
+	for code, count := range expected {
+		if got[code] != count {
+			t.Errorf("expected %d %s findings, got %d", count, code, got[code])
+		}
+	}
+
+	if lint.Errors(findings) != len(findings)-1 {
+		t.Errorf("expected L0001 to be the only warning")
+	}
+
+	for i := 1; i < len(findings); i++ {
+		if findings[i].Line < findings[i-1].Line {
+			t.Errorf("findings are not sorted by line")
+		}
+	}
+}
+
+func TestRequired(t *testing.T) {
+	tmpl, err := parse.String(`
+Resources:
+  Function:
+    Type: AWS::Lambda::Function
+    Properties:
+      Runtime: python3.12
+      Handler: index.handler
+      Code:
+        S3Bucket: ab
+        S3Key: code.zip
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := lint.Template(tmpl, lint.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := codes(findings)
+	if got[lint.L0002] != 1 {
+		t.Errorf("expected a missing Role, got %v", findings)
+	}
+	if got[lint.L0009] != 1 {
+		t.Errorf("expected S3Bucket to be too short, got %v", findings)
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	tmpl, err := parse.String(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := lint.Template(tmpl, lint.Options{
+		Ignore: []string{lint.L0001, lint.L0007, "AWS::S3::Bucket", "AWS::DynamoDB::Table"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("expected all findings to be ignored, got %v", findings)
+	}
+}
+
+func TestParameters(t *testing.T) {
+	tmpl, err := parse.String(`
+Parameters:
+  Status:
+    Type: String
+    AllowedValues: [Enabled, Fast]
+  Subnets:
+    Type: List<AWS::EC2::Subnet::Id>
+  Mode:
+    Type: String
+    Default: Active
+  Env:
+    Type: String
+    AllowedPattern: "[a-z]+"
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      AccelerateConfiguration:
+        AccelerationStatus: !Ref Status
+      BucketName: !Ref Subnets
+      Tags:
+        - Key: env
+          Value: !Ref Env
+  Function:
+    Type: AWS::Lambda::Function
+    Properties:
+      Role: arn:aws:iam::123456789012:role/test
+      Code:
+        ZipFile: print(1)
+      TracingConfig:
+        Mode: !Ref Mode
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := lint.Template(tmpl, lint.Options{
+		Params: map[string]string{"Env": "Prod", "Mode": "Enabled"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range findings {
+		t.Log(f)
+	}
+
+	got := codes(findings)
+	if got[lint.L0010] != 1 {
+		t.Errorf("expected Subnets to be the wrong type for BucketName")
+	}
+	// Fast is not an AccelerationStatus, and Enabled is not a tracing Mode
+	if got[lint.L0011] != 2 {
+		t.Errorf("expected 2 parameter values to be invalid for their properties")
+	}
+	// Prod does not match the AllowedPattern
+	if got[lint.L0012] != 1 {
+		t.Errorf("expected Env to be rejected by its AllowedPattern")
+	}
+}
+
+func TestCycles(t *testing.T) {
+	tmpl, err := parse.String(`
+Resources:
+  Queue:
+    Type: AWS::SQS::Queue
+    DependsOn: Topic
+  Topic:
+    Type: AWS::SNS::Topic
+    Properties:
+      TopicName: !GetAtt Queue.QueueName
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := lint.Template(tmpl, lint.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := codes(findings); got[lint.L0013] != 1 {
+		t.Fatalf("expected a circular dependency: %v", findings)
+	}
+	expected := "L0013 ERROR on line 3: AWS::SQS::Queue Queue - circular dependency " +
+		"Resources/Queue -> Resources/Topic -> Resources/Queue; " +
+		"remove Topic from the DependsOn of Queue"
+	for _, f := range findings {
+		if f.Code == lint.L0013 && f.String() != expected {
+			t.Errorf("expected %s, got %s", expected, f)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/internal/aws/cfn"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// isListParam returns true for parameter types that resolve to a list
+func isListParam(paramType string) bool {
+	return paramType == "CommaDelimitedList" ||
+		strings.HasPrefix(paramType, "List<") ||
+		strings.HasPrefix(paramType, "AWS::SSM::Parameter::Value<List<")
+}
+
+// paramValue returns the value that will be used for a parameter,
+// either supplied in Options.Params or the Default
+func (l *linter) paramValue(p *cft.ModelParameter) (string, bool) {
+	if v, ok := l.opts.Params[p.Name]; ok {
+		return v, true
+	}
+	if d := p.Default(); d != nil && d.Kind == yaml.ScalarNode {
+		return d.Value, true
+	}
+	return "", false
+}
+
+// parameters checks supplied parameter values against the
+// parameter's own AllowedValues and AllowedPattern
+func (l *linter) parameters() {
+	for _, name := range l.model.Names(cft.Parameters) {
+		p := l.model.Parameters[name]
+		value, ok := l.opts.Params[name]
+		if !ok {
+			continue
+		}
+
+		values := []string{value}
+		if isListParam(p.Type()) {
+			values = splitList(value)
+		}
+
+		allowed := p.AllowedValues()
+		pattern := p.AllowedPattern()
+		for _, v := range values {
+			if len(allowed) > 0 && !slices.Contains(allowed, v) {
+				l.add(Finding{Code: L0012, LogicalId: name,
+					Message: fmt.Sprintf("%s is not one of the AllowedValues: %s",
+						v, strings.Join(allowed, ", ")),
+					Line: p.Line()})
+			}
+			if pattern == "" {
+				continue
+			}
+			// AllowedPattern has to match the entire value
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				config.Debugf("lint skipping AllowedPattern for %s: %v", name, err)
+				continue
+			}
+			if !re.MatchString(v) {
+				l.add(Finding{Code: L0012, LogicalId: name,
+					Message: fmt.Sprintf("%s does not match the AllowedPattern %s", v, pattern),
+					Line:    p.Line()})
+			}
+		}
+	}
+}
+
+func splitList(s string) []string {
+	retval := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		retval = append(retval, strings.TrimSpace(v))
+	}
+	return retval
+}
+
+// parameter checks that a parameter used with Ref is compatible with
+// the property it is assigned to. The parameter type has to match the
+// property type, and its AllowedValues, Default and supplied value
+// have to be valid values for the property.
+func (v *validator) parameter(path string, n *yaml.Node, name string, prop *cfn.Prop) {
+	p := v.l.model.Parameters[name]
+	if p == nil {
+		// Pseudo parameters and resources, or an undefined reference,
+		// which is reported by references
+		return
+	}
+
+	if prop.Ref != "" {
+		prop = v.schema.ResolveRef(prop.Ref)
+		if prop == nil {
+			return
+		}
+	}
+
+	line := n.Line
+	if line == 0 {
+		line = n.Content[1].Line
+	}
+
+	paramType := p.Type()
+	types := propTypes(prop)
+	isList := isListParam(paramType)
+	if isList && len(types) > 0 && !slices.Contains(types, "array") {
+		v.add(L0010, path, line, "parameter %s is a %s, but the property expects %s",
+			name, paramType, strings.Join(types, " or "))
+		return
+	}
+	if !isList && len(types) == 1 && types[0] == "array" {
+		v.add(L0010, path, line, "parameter %s is a %s, but the property expects a list",
+			name, paramType)
+		return
+	}
+
+	itemProp := prop
+	if isList {
+		if prop.Items == nil {
+			return
+		}
+		itemProp = prop.Items
+	}
+
+	// Collect the values that the parameter might have
+	values := slices.Clone(p.AllowedValues())
+	if value, ok := v.l.paramValue(p); ok {
+		if isList {
+			values = append(values, splitList(value)...)
+		} else {
+			values = append(values, value)
+		}
+	}
+
+	checked := make(map[string]bool)
+	for _, value := range values {
+		if checked[value] {
+			continue
+		}
+		checked[value] = true
+		scalar := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Line: line}
+		for _, f := range v.check(scalar, itemProp) {
+			v.add(L0011, path, line, "parameter %s value %s: %s", name, value, f.Message)
+		}
+	}
+}
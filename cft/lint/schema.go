@@ -0,0 +1,257 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/internal/aws/cfn"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// patterns caches compiled schema patterns. A nil entry means the
+// pattern could not be compiled by Go and should be skipped.
+var patterns = make(map[string]*regexp.Regexp)
+
+func compilePattern(p string) *regexp.Regexp {
+	if re, ok := patterns[p]; ok {
+		return re
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		config.Debugf("lint skipping pattern %s: %v", p, err)
+		re = nil
+	}
+	patterns[p] = re
+	return re
+}
+
+// validator checks the properties of a single resource against its schema
+type validator struct {
+	l         *linter
+	schema    *cfn.Schema
+	logicalId string
+	typeName  string
+}
+
+func (v *validator) add(code string, path string, line int, format string, a ...any) {
+	v.l.add(Finding{Code: code, LogicalId: v.logicalId, TypeName: v.typeName,
+		Path: path, Message: fmt.Sprintf(format, a...), Line: line})
+}
+
+// object checks a mapping node against a set of properties
+func (v *validator) object(path string, n *yaml.Node, line int,
+	props map[string]*cfn.Prop, required []string, strictKeys bool) {
+
+	if n.Line > 0 {
+		line = n.Line
+	}
+
+	for _, r := range required {
+		if _, _, ok := getMapValue(n, r); !ok {
+			v.add(L0002, path, line, "missing required property %s", r)
+		}
+	}
+
+	for i := 0; i < len(n.Content); i += 2 {
+		key := n.Content[i]
+		val := n.Content[i+1]
+		prop, ok := props[key.Value]
+		if !ok {
+			if strictKeys {
+				v.add(L0003, path+"/"+key.Value, key.Line, "unknown property %s", key.Value)
+			}
+			continue
+		}
+		v.value(path+"/"+key.Value, val, prop)
+	}
+}
+
+// propTypes returns the allowed JSON schema types for a property
+func propTypes(prop *cfn.Prop) []string {
+	retval := make([]string, 0)
+	switch t := prop.Type.(type) {
+	case string:
+		if t != "" {
+			retval = append(retval, t)
+		}
+	case []any:
+		for _, s := range t {
+			if str, ok := s.(string); ok {
+				retval = append(retval, str)
+			}
+		}
+	}
+	if len(retval) == 0 && len(prop.Properties) > 0 {
+		retval = append(retval, "object")
+	}
+	return retval
+}
+
+// scalarMatches returns true if a scalar value is valid for a type
+func scalarMatches(n *yaml.Node, typeName string) bool {
+	switch typeName {
+	case "string":
+		return true
+	case "integer":
+		_, err := strconv.ParseInt(n.Value, 10, 64)
+		return err == nil
+	case "number":
+		_, err := strconv.ParseFloat(n.Value, 64)
+		return err == nil
+	case "boolean":
+		s := strings.ToLower(n.Value)
+		return s == "true" || s == "false"
+	}
+	return false
+}
+
+// kindMatches returns true if the node's kind is valid for one of the types
+func kindMatches(n *yaml.Node, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		switch n.Kind {
+		case yaml.MappingNode:
+			if t == "object" {
+				return true
+			}
+		case yaml.SequenceNode:
+			if t == "array" {
+				return true
+			}
+		case yaml.ScalarNode:
+			if scalarMatches(n, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// check validates the node against prop and returns the findings
+// without adding them to the linter
+func (v *validator) check(n *yaml.Node, prop *cfn.Prop) []Finding {
+	tmp := &validator{
+		l: &linter{
+			opts:     Options{Params: v.l.opts.Params},
+			model:    v.l.model,
+			findings: make([]Finding, 0),
+		},
+		schema:    v.schema,
+		logicalId: v.logicalId,
+		typeName:  v.typeName,
+	}
+	tmp.value("", n, prop)
+	return tmp.l.findings
+}
+
+// matches returns true if the node validates against prop without any findings
+func (v *validator) matches(n *yaml.Node, prop *cfn.Prop) bool {
+	return len(v.check(n, prop)) == 0
+}
+
+// value checks a node against a property definition
+func (v *validator) value(path string, n *yaml.Node, prop *cfn.Prop) {
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	if prop == nil || n.Tag == "!!null" {
+		return
+	}
+	if isIntrinsic(n) {
+		if n.Content[0].Value == "Ref" && n.Content[1].Kind == yaml.ScalarNode {
+			v.parameter(path, n, n.Content[1].Value, prop)
+		}
+		return
+	}
+
+	if prop.Ref != "" {
+		def := v.schema.ResolveRef(prop.Ref)
+		if def == nil {
+			config.Debugf("lint unable to resolve %s in %s", prop.Ref, v.typeName)
+			return
+		}
+		prop = def
+	}
+
+	for _, all := range prop.AllOf {
+		v.value(path, n, all)
+	}
+
+	branches := append(slices.Clone(prop.OneOf), prop.AnyOf...)
+	if len(branches) > 0 {
+		found := false
+		for _, b := range branches {
+			if v.matches(n, b) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.add(L0006, path, n.Line, "value does not match any of the allowed types")
+			return
+		}
+	}
+
+	types := propTypes(prop)
+	if !kindMatches(n, types) {
+		v.add(L0006, path, n.Line, "expected %s", strings.Join(types, " or "))
+		return
+	}
+
+	switch n.Kind {
+	case yaml.ScalarNode:
+		v.scalar(path, n, prop, types)
+	case yaml.MappingNode:
+		strict := len(prop.Properties) > 0 && prop.PatternProperties == nil
+		v.object(path, n, n.Line, prop.Properties, prop.Required, strict)
+	case yaml.SequenceNode:
+		if prop.Items != nil {
+			for i, item := range n.Content {
+				v.value(fmt.Sprintf("%s/%d", path, i), item, prop.Items)
+			}
+		}
+	}
+}
+
+// scalar checks enum, pattern and length constraints
+func (v *validator) scalar(path string, n *yaml.Node, prop *cfn.Prop, types []string) {
+	if len(prop.Enum) > 0 {
+		found := false
+		allowed := make([]string, 0)
+		for _, e := range prop.Enum {
+			s := fmt.Sprint(e)
+			allowed = append(allowed, s)
+			if s == n.Value {
+				found = true
+			}
+		}
+		if !found {
+			v.add(L0004, path, n.Line, "%s is not one of the allowed values: %s",
+				n.Value, strings.Join(allowed, ", "))
+		}
+	}
+
+	if len(types) > 0 && !slices.Contains(types, "string") {
+		return
+	}
+
+	if prop.Pattern != "" {
+		if re := compilePattern(prop.Pattern); re != nil && !re.MatchString(n.Value) {
+			v.add(L0005, path, n.Line, "%s does not match the pattern %s", n.Value, prop.Pattern)
+		}
+	}
+
+	length := len([]rune(n.Value))
+	if prop.MinLength > 0 && length < prop.MinLength {
+		v.add(L0009, path, n.Line, "length %d is less than the minimum %d", length, prop.MinLength)
+	}
+	if prop.MaxLength > 0 && length > prop.MaxLength {
+		v.add(L0009, path, n.Line, "length %d is greater than the maximum %d", length, prop.MaxLength)
+	}
+}
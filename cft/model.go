@@ -0,0 +1,378 @@
+package cft
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/internal/node"
+	"github.com/aws-cloudformation/rain/internal/s11n"
+	"gopkg.in/yaml.v3"
+)
+
+// Element is a named entry in one of the top level map sections of a
+// template, like a single resource or parameter.
+//
+// Element does not copy anything out of the template. Key and Node point
+// into Template.Node, so changes made through an Element are written
+// directly to the template and keep their comments and formatting.
+type Element struct {
+	// Name is the logical id or name of the element
+	Name string
+
+	// Key is the scalar node that holds Name
+	Key *yaml.Node
+
+	// Node is the value node for the element
+	Node *yaml.Node
+}
+
+// Line returns the line number of the element in the source template
+func (e *Element) Line() int {
+	if e.Key == nil {
+		return 0
+	}
+	return e.Key.Line
+}
+
+// Comment returns the comment attached to the element, if there is one
+func (e *Element) Comment() string {
+	if e.Key == nil {
+		return ""
+	}
+	if e.Key.HeadComment != "" {
+		return e.Key.HeadComment
+	}
+	return e.Key.LineComment
+}
+
+// Get returns the value node for an attribute of the element, or nil
+func (e *Element) Get(name string) *yaml.Node {
+	_, v, _ := s11n.GetMapValue(e.Node, name)
+	return v
+}
+
+// GetString returns a scalar attribute of the element, or an empty string
+func (e *Element) GetString(name string) string {
+	return s11n.GetValue(e.Node, name)
+}
+
+// Set replaces the value of an attribute, adding it if it does not exist
+func (e *Element) Set(name string, value *yaml.Node) {
+	setMapValue(e.Node, name, value)
+}
+
+// Remove removes an attribute from the element
+func (e *Element) Remove(name string) error {
+	return node.RemoveFromMap(e.Node, name)
+}
+
+// ModelResource is an entry in the Resources section
+type ModelResource struct {
+	Element
+}
+
+// Type returns the resource type, for example AWS::S3::Bucket
+func (r *ModelResource) Type() string {
+	return r.GetString("Type")
+}
+
+// Properties returns the Properties node, or nil if the resource has none
+func (r *ModelResource) Properties() *yaml.Node {
+	return r.Get("Properties")
+}
+
+// Property returns the value node of a single property, or nil
+func (r *ModelResource) Property(name string) *yaml.Node {
+	_, v, _ := s11n.GetMapValue(r.Properties(), name)
+	return v
+}
+
+// SetProperty sets a property, creating the Properties node if needed
+func (r *ModelResource) SetProperty(name string, value *yaml.Node) {
+	props := r.Properties()
+	if props == nil {
+		props = &yaml.Node{Kind: yaml.MappingNode}
+		r.Set("Properties", props)
+	}
+	setMapValue(props, name, value)
+}
+
+// Condition returns the name of the condition attached to the resource
+func (r *ModelResource) Condition() string {
+	return r.GetString("Condition")
+}
+
+// DependsOn returns the logical ids the resource explicitly depends on.
+// DependsOn can be a scalar or a list in the template.
+func (r *ModelResource) DependsOn() []string {
+	d := r.Get("DependsOn")
+	if d == nil {
+		return nil
+	}
+	if d.Kind == yaml.ScalarNode {
+		return []string{d.Value}
+	}
+	retval := make([]string, 0)
+	for _, n := range d.Content {
+		if n.Kind == yaml.ScalarNode {
+			retval = append(retval, n.Value)
+		}
+	}
+	return retval
+}
+
+// ModelParameter is an entry in the Parameters section
+type ModelParameter struct {
+	Element
+}
+
+// Type returns the parameter type, for example String
+func (p *ModelParameter) Type() string {
+	return p.GetString("Type")
+}
+
+// Default returns the Default node, or nil
+func (p *ModelParameter) Default() *yaml.Node {
+	return p.Get("Default")
+}
+
+// AllowedValues returns the scalar allowed values for the parameter
+func (p *ModelParameter) AllowedValues() []string {
+	a := p.Get("AllowedValues")
+	if a == nil || a.Kind != yaml.SequenceNode {
+		return nil
+	}
+	retval := make([]string, 0)
+	for _, n := range a.Content {
+		retval = append(retval, n.Value)
+	}
+	return retval
+}
+
+// AllowedPattern returns the AllowedPattern regex, or an empty string
+func (p *ModelParameter) AllowedPattern() string {
+	return p.GetString("AllowedPattern")
+}
+
+// ModelOutput is an entry in the Outputs section
+type ModelOutput struct {
+	Element
+}
+
+// Value returns the Value node of the output
+func (o *ModelOutput) Value() *yaml.Node {
+	return o.Get("Value")
+}
+
+// Export returns the Export Name node of the output, or nil
+func (o *ModelOutput) Export() *yaml.Node {
+	_, v, _ := s11n.GetMapValue(o.Get("Export"), "Name")
+	return v
+}
+
+// Condition returns the name of the condition attached to the output
+func (o *ModelOutput) Condition() string {
+	return o.GetString("Condition")
+}
+
+// ModelCondition is an entry in the Conditions section.
+// Node is the condition expression.
+type ModelCondition struct {
+	Element
+}
+
+// ModelMapping is an entry in the Mappings section
+type ModelMapping struct {
+	Element
+}
+
+// Lookup returns the value for a top level and second level key, or nil
+func (m *ModelMapping) Lookup(topLevelKey, secondLevelKey string) *yaml.Node {
+	_, top, _ := s11n.GetMapValue(m.Node, topLevelKey)
+	_, v, _ := s11n.GetMapValue(top, secondLevelKey)
+	return v
+}
+
+// Model is a typed view of the top level sections of a template.
+//
+// The maps in Model are indexed by logical id. Use Names to get the
+// ids in template order. Everything in the model points into the
+// template's yaml nodes, so the template can be written back with
+// the format package after making changes through the model.
+type Model struct {
+	Resources  map[string]*ModelResource
+	Parameters map[string]*ModelParameter
+	Outputs    map[string]*ModelOutput
+	Conditions map[string]*ModelCondition
+	Mappings   map[string]*ModelMapping
+
+	t Template
+}
+
+// NewModel builds a Model from t.Node
+func NewModel(t Template) (*Model, error) {
+	if t.Node == nil || len(t.Node.Content) == 0 {
+		return nil, errors.New("template has no content")
+	}
+	if t.Node.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("template root is not a mapping")
+	}
+
+	m := &Model{
+		Resources:  make(map[string]*ModelResource),
+		Parameters: make(map[string]*ModelParameter),
+		Outputs:    make(map[string]*ModelOutput),
+		Conditions: make(map[string]*ModelCondition),
+		Mappings:   make(map[string]*ModelMapping),
+		t:          t,
+	}
+
+	for _, e := range m.elements(Resources) {
+		if e.Node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("resource %s is not a mapping", e.Name)
+		}
+		m.Resources[e.Name] = &ModelResource{Element: e}
+	}
+	for _, e := range m.elements(Parameters) {
+		m.Parameters[e.Name] = &ModelParameter{Element: e}
+	}
+	for _, e := range m.elements(Outputs) {
+		m.Outputs[e.Name] = &ModelOutput{Element: e}
+	}
+	for _, e := range m.elements(Conditions) {
+		m.Conditions[e.Name] = &ModelCondition{Element: e}
+	}
+	for _, e := range m.elements(Mappings) {
+		m.Mappings[e.Name] = &ModelMapping{Element: e}
+	}
+
+	return m, nil
+}
+
+// Model returns a new Model for the template
+func (t Template) Model() (*Model, error) {
+	return NewModel(t)
+}
+
+// Template returns the template the model was built from
+func (m *Model) Template() Template {
+	return m.t
+}
+
+func (m *Model) elements(section Section) []Element {
+	_, s, _ := s11n.GetMapValue(m.t.Node.Content[0], string(section))
+	if s == nil || s.Kind != yaml.MappingNode {
+		return nil
+	}
+	retval := make([]Element, 0)
+	for i := 0; i < len(s.Content); i += 2 {
+		if strings.HasPrefix(s.Content[i].Value, "Fn::ForEach::") {
+			// Loops are not expanded in the model
+			continue
+		}
+		retval = append(retval, Element{
+			Name: s.Content[i].Value,
+			Key:  s.Content[i],
+			Node: s.Content[i+1],
+		})
+	}
+	return retval
+}
+
+// Names returns the names of the elements in a section, in template order
+func (m *Model) Names(section Section) []string {
+	retval := make([]string, 0)
+	for _, e := range m.elements(section) {
+		retval = append(retval, e.Name)
+	}
+	return retval
+}
+
+// Resource returns a resource by logical id, or nil
+func (m *Model) Resource(logicalId string) *ModelResource {
+	return m.Resources[logicalId]
+}
+
+// AddResource adds a resource to the template, creating the
+// Resources section if it does not exist. If a resource with the
+// same logical id exists, it is replaced.
+func (m *Model) AddResource(logicalId string, typeName string) (*ModelResource, error) {
+	section, err := m.section(Resources)
+	if err != nil {
+		return nil, err
+	}
+	n := &yaml.Node{Kind: yaml.MappingNode}
+	node.Add(n, "Type", typeName)
+	key := setMapValue(section, logicalId, n)
+	r := &ModelResource{Element: Element{Name: logicalId, Key: key, Node: n}}
+	m.Resources[logicalId] = r
+	return r, nil
+}
+
+// RemoveResource removes a resource from the template
+func (m *Model) RemoveResource(logicalId string) error {
+	if err := m.remove(Resources, logicalId); err != nil {
+		return err
+	}
+	delete(m.Resources, logicalId)
+	return nil
+}
+
+// RemoveParameter removes a parameter from the template
+func (m *Model) RemoveParameter(name string) error {
+	if err := m.remove(Parameters, name); err != nil {
+		return err
+	}
+	delete(m.Parameters, name)
+	return nil
+}
+
+// RemoveOutput removes an output from the template
+func (m *Model) RemoveOutput(name string) error {
+	if err := m.remove(Outputs, name); err != nil {
+		return err
+	}
+	delete(m.Outputs, name)
+	return nil
+}
+
+// RemoveCondition removes a condition from the template
+func (m *Model) RemoveCondition(name string) error {
+	if err := m.remove(Conditions, name); err != nil {
+		return err
+	}
+	delete(m.Conditions, name)
+	return nil
+}
+
+func (m *Model) remove(section Section, name string) error {
+	s, err := m.t.GetSection(section)
+	if err != nil {
+		return err
+	}
+	return node.RemoveFromMap(s, name)
+}
+
+// section returns a map section, creating it if necessary
+func (m *Model) section(section Section) (*yaml.Node, error) {
+	s, err := m.t.GetSection(section)
+	if err == nil {
+		return s, nil
+	}
+	return m.t.AddMapSection(section)
+}
+
+// setMapValue replaces or appends a value in a mapping node and
+// returns the key node
+func setMapValue(n *yaml.Node, name string, value *yaml.Node) *yaml.Node {
+	for i := 0; i < len(n.Content); i += 2 {
+		if n.Content[i].Value == name {
+			n.Content[i+1] = value
+			return n.Content[i]
+		}
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Value: name}
+	n.Content = append(n.Content, key, value)
+	return key
+}
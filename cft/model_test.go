@@ -0,0 +1,216 @@
+package cft_test
+
+import (
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"gopkg.in/yaml.v3"
+)
+
+const modelSource = `
+Description: Model test
+
+Parameters:
+  # The name of the bucket
+  Name:
+    Type: String
+    AllowedValues:
+      - a
+      - b
+
+Conditions:
+  IsA: !Equals [!Ref Name, a]
+
+Mappings:
+  Sizes:
+    a:
+      Size: 1
+
+Resources:
+  # A bucket
+  Bucket:
+    Type: AWS::S3::Bucket
+    Condition: IsA
+    Properties:
+      BucketName: !Ref Name # inline comment
+
+  Topic:
+    Type: AWS::SNS::Topic
+    DependsOn: [Bucket]
+
+Outputs:
+  BucketArn:
+    Value: !GetAtt Bucket.Arn
+    Export:
+      Name: bucket-arn
+`
+
+func TestModel(t *testing.T) {
+	tmpl, err := parse.String(modelSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := tmpl.Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bucket := m.Resource("Bucket")
+	if bucket == nil {
+		t.Fatal("expected Bucket")
+	}
+	if bucket.Type() != "AWS::S3::Bucket" {
+		t.Errorf("unexpected type %s", bucket.Type())
+	}
+	if bucket.Condition() != "IsA" {
+		t.Errorf("unexpected condition %s", bucket.Condition())
+	}
+	if bucket.Line() != 22 {
+		t.Errorf("unexpected line %d", bucket.Line())
+	}
+	if bucket.Comment() != "# A bucket" {
+		t.Errorf("unexpected comment %q", bucket.Comment())
+	}
+	if deps := m.Resource("Topic").DependsOn(); len(deps) != 1 || deps[0] != "Bucket" {
+		t.Errorf("unexpected DependsOn %v", deps)
+	}
+
+	if p := m.Parameters["Name"]; p.Type() != "String" || len(p.AllowedValues()) != 2 {
+		t.Errorf("unexpected parameter %v", p)
+	}
+	if _, ok := m.Conditions["IsA"]; !ok {
+		t.Errorf("expected IsA condition")
+	}
+	if v := m.Mappings["Sizes"].Lookup("a", "Size"); v == nil || v.Value != "1" {
+		t.Errorf("unexpected mapping value %v", v)
+	}
+	if e := m.Outputs["BucketArn"].Export(); e == nil || e.Value != "bucket-arn" {
+		t.Errorf("unexpected export %v", e)
+	}
+
+	names := m.Names(cft.Resources)
+	if len(names) != 2 || names[0] != "Bucket" || names[1] != "Topic" {
+		t.Errorf("unexpected names %v", names)
+	}
+}
+
+const modelEdited = `Description: Model test
+
+Parameters:
+
+  # The name of the bucket
+  Name:
+    Type: String
+    AllowedValues:
+      - a
+      - b
+
+Mappings:
+  Sizes:
+    a:
+      Size: 1
+
+Conditions:
+  IsA: !Equals
+    - !Ref Name
+    - a
+
+Resources:
+
+  # A bucket
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Ref Name # inline comment
+      VersioningConfiguration:
+        Status: Enabled
+    Condition: IsA
+
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      QueueName: q
+
+Outputs:
+  BucketArn:
+    Value: !GetAtt Bucket.Arn
+    Export:
+      Name: bucket-arn
+`
+
+func TestModelRoundTrip(t *testing.T) {
+	tmpl, err := parse.String(modelSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := format.String(tmpl, format.Options{})
+
+	m, err := cft.NewModel(tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing a value and putting it back leaves the output as it was
+	bucket := m.Resource("Bucket")
+	name := bucket.Property("BucketName")
+	bucket.SetProperty("BucketName", &yaml.Node{Kind: yaml.ScalarNode, Value: "changed"})
+	if format.String(m.Template(), format.Options{}) == before {
+		t.Fatal("expected the change to be written to the template")
+	}
+	bucket.SetProperty("BucketName", name)
+	if after := format.String(m.Template(), format.Options{}); after != before {
+		t.Fatalf("round trip changed the template:\n%s\n%s", before, after)
+	}
+
+	bucket.SetProperty("VersioningConfiguration", &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "Status"},
+		{Kind: yaml.ScalarNode, Value: "Enabled"},
+	}})
+	if err := m.RemoveResource("Topic"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := m.AddResource("Queue", "AWS::SQS::Queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetProperty("QueueName", &yaml.Node{Kind: yaml.ScalarNode, Value: "q"})
+
+	if after := format.String(m.Template(), format.Options{}); after != modelEdited {
+		t.Errorf("unexpected template after edits:\n%s", after)
+	}
+	if m.Resource("Topic") != nil {
+		t.Errorf("expected Topic to be removed from the model")
+	}
+}
+
+func TestModelForEach(t *testing.T) {
+	tmpl, err := parse.String(`
+Transform: AWS::LanguageExtensions
+
+Resources:
+  Fn::ForEach::Topics:
+    - Name
+    - [A, B]
+    - Topic${Name}:
+        Type: AWS::SNS::Topic
+
+  Bucket:
+    Type: AWS::S3::Bucket
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Loops are skipped, since they are not resources until they are expanded
+	m, err := tmpl.Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := m.Names(cft.Resources)
+	if len(names) != 1 || names[0] != "Bucket" {
+		t.Errorf("unexpected names %v", names)
+	}
+}
@@ -17,6 +17,8 @@ func parseGetAtt(n *yaml.Node) error {
 		return errors.New("GetAtt requires two parameters")
 	}
 
+	// The logical id starts where the string does,
+	// so it keeps the string's position
 	*n = yaml.Node{
 		Kind: yaml.SequenceNode,
 
@@ -24,12 +26,17 @@ func parseGetAtt(n *yaml.Node) error {
 		LineComment: n.LineComment,
 		FootComment: n.FootComment,
 
+		Line:   n.Line,
+		Column: n.Column,
+
 		Content: []*yaml.Node{
 			{
-				Kind:  yaml.ScalarNode,
-				Style: 0,
-				Tag:   "!!str",
-				Value: parts[0],
+				Kind:   yaml.ScalarNode,
+				Style:  0,
+				Tag:    "!!str",
+				Value:  parts[0],
+				Line:   n.Line,
+				Column: n.Column,
 			},
 			{
 				Kind:  yaml.ScalarNode,
@@ -0,0 +1,179 @@
+package pkg
+
+// This file contains the asset cache, which lets rain pkg skip the Run
+// script and the upload for assets that have not changed
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/aws-cloudformation/rain/internal/config"
+)
+
+// NoCache disables the asset cache, so that every asset is built and uploaded
+var NoCache bool
+
+// CacheDir is where the asset cache is kept, relative to the template
+var CacheDir = filepath.Join(".rain", "cache", "assets")
+
+// cacheEntry records where an asset was uploaded
+type cacheEntry struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	Region string `json:"region"`
+}
+
+// assetHash returns a hash of the Run script and the files at path,
+// which changes when anything that the asset is made from changes.
+// artifact is the asset's own Path, which tells apart the assets
+// that one Run script builds from the same files.
+func assetHash(root string, run string, path string, artifact string, zip bool) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "zip:%v\n", zip)
+	if artifact != "" {
+		fmt.Fprintf(h, "artifact:%s\n", filepath.ToSlash(artifact))
+	}
+
+	if run != "" {
+		content, err := os.ReadFile(filepath.Join(root, run))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "run:%s\n", run)
+		h.Write(content)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	if err := hashPath(h, path); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// hashPath writes the name, mode and content of each file under path to h.
+// filepath.Walk visits files in lexical order, so the hash is stable.
+// Symbolic links are followed, so that a change to the file or directory
+// that a link points to changes the hash.
+func hashPath(h hash.Hash, path string) error {
+	return hashTree(h, path, "", make(map[string]bool))
+}
+
+// hashTree hashes the files under path, naming them relative to path
+// and prefixed with prefix. visited holds the real paths of the
+// directories that are being walked, to stop at links that loop.
+func hashTree(h hash.Hash, path string, prefix string, visited map[string]bool) error {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	if visited[real] {
+		return nil
+	}
+	visited[real] = true
+	defer delete(visited, real)
+
+	// filepath.Walk does not follow path if it is a link,
+	// unless it ends with a separator
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path += string(filepath.Separator)
+	}
+
+	return filepath.Walk(path, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && info.Name() == ".rain" {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(filepath.Join(prefix, rel))
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			target, err := os.Stat(p)
+			if err != nil {
+				return err
+			}
+			if target.IsDir() {
+				return hashTree(h, p, rel, visited)
+			}
+			info = target
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		fmt.Fprintf(h, "%s %o %d\n", rel, info.Mode().Perm(), info.Size())
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(h, f)
+		return err
+	})
+}
+
+// hashString returns the sha256 of a string in hex
+func hashString(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
+func cachePath(root, hash string) string {
+	return filepath.Join(root, CacheDir, hash+".json")
+}
+
+// readCache returns the upload that was recorded for an asset hash
+func readCache(root, hash string) (*cacheEntry, bool) {
+	if NoCache {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(cachePath(root, hash))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		config.Debugf("Ignoring invalid cache entry %s: %v", cachePath(root, hash), err)
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+// writeCache records where an asset was uploaded
+func writeCache(root, hash string, entry cacheEntry) error {
+	if NoCache {
+		return nil
+	}
+
+	path := cachePath(root, hash)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0644)
+}
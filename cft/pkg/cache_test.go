@@ -0,0 +1,169 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAssetHash(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	if err := os.MkdirAll(filepath.Join(src, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("build.sh", "#!/bin/sh\nmake")
+	write("src/main.py", "print('a')")
+	write("src/lib/util.py", "x = 1")
+
+	hash := func(run string, zip bool) string {
+		h, err := assetHash(root, run, "src", "", zip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	first := hash("build.sh", false)
+	if hash("build.sh", false) != first {
+		t.Error("expected the hash to be stable")
+	}
+	if hash("", false) == first {
+		t.Error("expected the Run script to change the hash")
+	}
+	if hash("build.sh", true) == first {
+		t.Error("expected Zip to change the hash")
+	}
+
+	write("src/lib/util.py", "x = 2")
+	if hash("build.sh", false) == first {
+		t.Error("expected a change to a file to change the hash")
+	}
+
+	second := hash("build.sh", false)
+	write("build.sh", "#!/bin/sh\nmake all")
+	if hash("build.sh", false) == second {
+		t.Error("expected a change to the Run script to change the hash")
+	}
+}
+
+func TestAssetHashArtifacts(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"build.sh":    "#!/bin/sh\nmake",
+		"src/main.py": "print('a')",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// One Run script that builds two assets from the same Source
+	hash := func(artifact string) string {
+		h, err := assetHash(root, "build.sh", "src", artifact, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	a := hash("dist/a.zip")
+	b := hash("dist/b.zip")
+	if a == b {
+		t.Error("expected assets with different Paths to have different hashes")
+	}
+	if hash("dist/a.zip") != a {
+		t.Error("expected the hash to be stable")
+	}
+
+	if err := writeCache(root, a, cacheEntry{"bucket", "a", "us-east-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := readCache(root, b); ok {
+		t.Error("expected the second asset to miss the first asset's cache entry")
+	}
+}
+
+func TestAssetHashSymlinks(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dir := range []string{"shared/lib", "src"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("shared/config.json", "{}")
+	write("shared/lib/util.py", "x = 1")
+	write("src/main.py", "print('a')")
+	links := map[string]string{
+		"src/config.json": "../shared/config.json",
+		"src/lib":         "../shared/lib",
+		"src/loop":        "..",
+		"link":            "src",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("unable to create symlinks: %v", err)
+		}
+	}
+
+	hash := func(path string) string {
+		h, err := assetHash(root, "", path, "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	first := hash("src")
+	if hash("link") != first {
+		t.Error("expected a link to a directory to hash the same as the directory")
+	}
+
+	write("shared/config.json", "{\"a\": 1}")
+	second := hash("src")
+	if second == first {
+		t.Error("expected a change to a linked file to change the hash")
+	}
+
+	write("shared/lib/util.py", "x = 2")
+	if hash("src") == second {
+		t.Error("expected a change in a linked directory to change the hash")
+	}
+}
+
+func TestCache(t *testing.T) {
+	root := t.TempDir()
+
+	if _, ok := readCache(root, "abc"); ok {
+		t.Fatal("expected an empty cache")
+	}
+
+	entry := cacheEntry{Bucket: "bucket", Key: "prefix/abc", Region: "us-east-1"}
+	if err := writeCache(root, "abc", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := readCache(root, "abc")
+	if !ok || *got != entry {
+		t.Errorf("expected %v, got %v", entry, got)
+	}
+
+	NoCache = true
+	defer func() { NoCache = false }()
+	if _, ok := readCache(root, "abc"); ok {
+		t.Error("expected --no-cache to ignore the cache")
+	}
+}
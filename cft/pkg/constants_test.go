@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws-cloudformation/rain/cft/diff"
 	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/internal/s11n"
 	"gopkg.in/yaml.v3"
 )
 
@@ -111,3 +112,36 @@ func TestIsSubNeeded(t *testing.T) {
 		}
 	}
 }
+
+func TestPackageAliasTypes(t *testing.T) {
+	tmpl, err := parse.String(`
+Rain:
+  Packages:
+    abc:
+      Location: https://example.com/modules.zip
+
+Resources:
+  Broken: not a resource
+  Bucket:
+    Type: $abc/bucket.yaml
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !processRainSection(&tmpl) {
+		t.Fatal("expected a Rain section")
+	}
+
+	// A resource that isn't a mapping does not stop the others from being rewritten
+	resources, err := tmpl.GetSection("Resources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, bucket, _ := s11n.GetMapValue(resources, "Bucket")
+	_, typ, _ := s11n.GetMapValue(bucket, "Type")
+	if typ.Kind != yaml.MappingNode || len(typ.Content) != 2 ||
+		typ.Content[0].Value != "Rain::Module" || typ.Content[1].Value != "abc/bucket.yaml" {
+		t.Errorf("expected the alias to become a Rain::Module, got %v", typ)
+	}
+}
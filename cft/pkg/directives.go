@@ -10,10 +10,14 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/aws-cloudformation/rain/cft"
 	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/internal/aws"
+	"github.com/aws-cloudformation/rain/internal/aws/s3"
 	"github.com/aws-cloudformation/rain/internal/config"
+	"github.com/aws-cloudformation/rain/internal/console/spinner"
 	"github.com/aws-cloudformation/rain/internal/node"
 	"github.com/aws-cloudformation/rain/internal/s11n"
 	"gopkg.in/yaml.v3"
@@ -34,6 +38,11 @@ type s3Options struct {
 	Zip            bool     `yaml:"Zip"`
 	Format         s3Format `yaml:"Format"`
 	Run            string   `yaml:"Run"`
+
+	// Source is the directory that Run builds Path from. When it is set,
+	// Run is skipped if nothing in Source or the Run script has changed.
+	// Rain can't tell what Run reads, so without Source it runs every time.
+	Source string `yaml:"Source"`
 }
 
 type directiveContext struct {
@@ -60,6 +69,10 @@ func init() {
 	registry["**/*|Rain::S3"] = includeS3
 	registry["**/*|Rain::Module"] = module
 	registry["**/*|Rain::Constant"] = rainConstant
+	registry["**/*|Rain::Template"] = includeTemplate
+	registry["**/*|Rain::Ssm"] = includeSsm
+	registry["**/*|Rain::Secret"] = includeSecret
+	registry["**/*|Rain::Image"] = includeImage
 
 	// Don't forget to also add new items to cft/tags.go
 }
@@ -134,35 +147,100 @@ func includeEnv(ctx *directiveContext) (bool, error) {
 	return true, nil
 }
 
-func handleS3(root string, options s3Options) (*yaml.Node, error) {
+// runBuild runs the build script for an asset
+func runBuild(root string, run string) error {
+	relativePath := filepath.Join(".", root, run)
+	absPath, absErr := filepath.Abs(relativePath)
+	if absErr != nil {
+		config.Debugf("filepath.Abs failed? %s", absErr)
+		return absErr
+	}
+	cmd := exec.Command(absPath)
+	var stdout strings.Builder
+	var stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Dir = root
+	err := cmd.Run()
+	if err != nil {
+		config.Debugf("s3Option Run %s failed with %s: %s",
+			run, err, stderr.String())
+		return err
+	}
+	return nil
+}
 
-	// Check to see if we need to run a build command first
-	if options.Run != "" {
-		relativePath := filepath.Join(".", root, options.Run)
-		absPath, absErr := filepath.Abs(relativePath)
-		if absErr != nil {
-			config.Debugf("filepath.Abs failed? %s", absErr)
-			return nil, absErr
-		}
-		cmd := exec.Command(absPath)
-		var stdout strings.Builder
-		var stderr strings.Builder
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
-		cmd.Dir = root
-		err := cmd.Run()
-		if err != nil {
-			config.Debugf("s3Option Run %s failed with %s: %s",
-				options.Run, err, stderr.String())
+// cachedUpload returns the upload recorded in the asset cache,
+// if the object is still in the bucket
+func cachedUpload(root, hash string) (*s3Path, bool) {
+	entry, ok := readCache(root, hash)
+	if !ok {
+		return nil, false
+	}
+
+	if entry.Bucket != s3.RainBucket(false) || entry.Region != aws.Config().Region {
+		return nil, false
+	}
+
+	// The rain bucket deletes objects after a few days
+	if _, err := s3.HeadObject(entry.Bucket, entry.Key); err != nil {
+		config.Debugf("Cached asset %s is no longer in the bucket: %v", entry.Key, err)
+		return nil, false
+	}
+
+	return &s3Path{bucket: entry.Bucket, key: entry.Key, region: entry.Region}, true
+}
+
+func handleS3(root string, options s3Options) (*yaml.Node, error) {
+	start := time.Now()
+	spinner.StartTimer(fmt.Sprintf("Packaging asset %s", options.Path))
+	defer spinner.StopTimer()
+
+	// Without a Source, the Run script makes the files at Path,
+	// so it has to run before they can be hashed. Only the upload
+	// is skipped if they have not changed.
+	source := options.Source
+	if options.Run != "" && source == "" {
+		if err := runBuild(root, options.Run); err != nil {
 			return nil, err
 		}
 	}
+	if source == "" {
+		source = options.Path
+	}
 
-	s, err := upload(root, options.Path, options.Zip)
+	hash, err := assetHash(root, options.Run, source, options.Path, options.Zip)
 	if err != nil {
 		return nil, err
 	}
 
+	var s *s3Path
+	cached := false
+	if !DryRun {
+		s, cached = cachedUpload(root, hash)
+	}
+	if !cached {
+		if options.Run != "" && options.Source != "" {
+			if err := runBuild(root, options.Run); err != nil {
+				return nil, err
+			}
+		}
+
+		s, err = upload(root, options.Path, options.Zip, hash)
+		if err != nil {
+			return nil, err
+		}
+
+		if !DryRun {
+			if err := writeCache(root, hash, cacheEntry{s.bucket, s.key, s.region}); err != nil {
+				config.Debugf("Unable to write to the asset cache: %v", err)
+			}
+		}
+	}
+
+	config.Debugf("Packaged asset %s in %s (cached: %v)",
+		options.Path, time.Since(start).Truncate(time.Millisecond), cached)
+
 	if options.Format == "" {
 		if options.BucketProperty != "" && options.KeyProperty != "" {
 			options.Format = s3Object
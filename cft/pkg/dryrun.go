@@ -0,0 +1,59 @@
+package pkg
+
+// This file contains the dry run mode, which packages
+// templates without making any calls to AWS
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/aws-cloudformation/rain/internal/aws/s3"
+	"github.com/aws-cloudformation/rain/internal/config"
+)
+
+// DryRun packages templates without calling AWS. Assets are zipped, hashed
+// and written to DryRunDir instead of S3, and the template gets placeholder
+// S3 URIs that only change when an asset changes. Images are tagged but
+// not built, and !Rain::Ssm and !Rain::Secret become dynamic references,
+// unless Values has been set to another source.
+var DryRun bool
+
+// DryRunDir is where DryRun writes assets. If it is empty,
+// they are written to .rain/dry-run next to the template.
+var DryRunDir string
+
+// The placeholders that DryRun uses instead of the rain bucket and repository
+const (
+	DryRunBucket     = "rain-artifacts-dry-run"
+	DryRunRegion     = "dry-run"
+	DryRunRepository = "000000000000.dkr.ecr.dry-run.amazonaws.com/rain-images"
+)
+
+// dryRunUpload writes an asset to the dry run directory, at
+// the same key that it would have been uploaded to
+func dryRunUpload(root string, name string, content []byte) (*s3Path, error) {
+	dir := DryRunDir
+	if dir == "" {
+		dir = filepath.Join(root, ".rain", "dry-run")
+	}
+
+	key := filepath.ToSlash(filepath.Join(s3.BucketKeyPrefix, name))
+	path := filepath.Join(dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return nil, err
+	}
+
+	config.Debugf("Dry run wrote %s", path)
+
+	return &s3Path{bucket: DryRunBucket, key: key, region: DryRunRegion}, nil
+}
+
+// dryRunValues returns true if !Rain::Ssm and !Rain::Secret should insert
+// dynamic references instead of reading values from AWS
+func dryRunValues() bool {
+	_, isAws := Values.(awsValues)
+	return DryRun && isAws
+}
@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+)
+
+func TestDryRun(t *testing.T) {
+	root := t.TempDir()
+	out := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(root, "lambda"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"lambda/index.py": "def handler(event, context): pass\n",
+		"app/Dockerfile":  "FROM scratch\n",
+		"readme.txt":      "hello\n",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	DryRun = true
+	DryRunDir = out
+	defer func() {
+		DryRun = false
+		DryRunDir = ""
+		uploads = map[string]*s3Path{}
+		images = map[string]string{}
+	}()
+
+	source := `
+Resources:
+  Function:
+    Type: AWS::Lambda::Function
+    Properties:
+      Code: lambda
+      Environment:
+        Variables:
+          README: !Rain::S3Http readme.txt
+          VPC: !Rain::Ssm /app/vpc-id
+          PASSWORD: !Rain::Secret {SecretId: app/db, Key: password}
+  Container:
+    Type: AWS::Lambda::Function
+    Properties:
+      Code:
+        ImageUri: !Rain::Image app
+`
+
+	pkg := func() string {
+		uploads = map[string]*s3Path{}
+		images = map[string]string{}
+		p, err := parse.String(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmpl, err := Template(p, root, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return format.String(tmpl, format.Options{})
+	}
+
+	first := pkg()
+	for _, expected := range []string{
+		"S3Bucket: " + DryRunBucket,
+		"https://" + DryRunBucket + ".s3." + DryRunRegion + ".amazonaws.com/",
+		"VPC: '{{resolve:ssm:/app/vpc-id}}'",
+		"PASSWORD: '{{resolve:secretsmanager:app/db:SecretString:password}}'",
+		"ImageUri: " + DryRunRepository + ":",
+	} {
+		if !strings.Contains(first, expected) {
+			t.Errorf("expected '%s' in:\n%s", expected, first)
+		}
+	}
+
+	entries, err := os.ReadDir(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected the zip and the file in %s, got %d files", out, len(entries))
+	}
+
+	if second := pkg(); second != first {
+		t.Errorf("expected the same output each time:\n%s\n%s", first, second)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, CacheDir)); err == nil {
+		t.Error("expected a dry run not to write to the asset cache")
+	}
+}
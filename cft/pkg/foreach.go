@@ -15,6 +15,11 @@ type fnfe struct {
 	fnForEachSequence  *yaml.Node
 }
 
+// handleForEach copies a Fn::ForEach in a module into the parent template,
+// resolving a collection that refers to a module parameter. The loop is
+// left for the AWS::LanguageExtensions transform, or for langext.Expand
+// when packaging with ExpandLanguageExtensions.
+//
 // TODO: This was broken in the refactor, come back to it later
 func handleForEach(
 	moduleResources *yaml.Node,
@@ -0,0 +1,191 @@
+package pkg
+
+// This file contains the implementation of the `!Rain::Template` directive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"gopkg.in/yaml.v3"
+)
+
+// Params holds parameter values from --params and --config,
+// which templates rendered by !Rain::Template can use. Commands that
+// don't take parameter values leave it nil, and then any parameter a
+// template uses is an empty string, instead of an error.
+var Params map[string]string
+
+// paramRef finds uses of .Params.Name in a template
+var paramRef = regexp.MustCompile(`\.Params\.([A-Za-z0-9_]+)`)
+
+type templateOptions struct {
+	Path string    `yaml:"Path"`
+	Data yaml.Node `yaml:"Data"`
+}
+
+// templateData is what a template rendered by !Rain::Template can refer to
+type templateData struct {
+	// Constants are the Rain Constants in the parent template
+	Constants map[string]any
+
+	// Params are the parameter values from --params and --config
+	Params map[string]string
+
+	// Data is the Data property of the directive, or the
+	// content of the file it names
+	Data any
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": func(sep string, v []any) string {
+		s := make([]string, len(v))
+		for i, item := range v {
+			s[i] = fmt.Sprint(item)
+		}
+		return strings.Join(s, sep)
+	},
+}
+
+// includeTemplate renders a file with Go text/template and inserts the
+// result into the template as YAML. The value is either a path, or an
+// object with a Path and optional Data, which can be a map or the path
+// to a YAML or JSON file.
+func includeTemplate(ctx *directiveContext) (bool, error) {
+	n := ctx.n
+	if len(n.Content) != 2 {
+		return false, errors.New("expected exactly one key")
+	}
+
+	var options templateOptions
+	switch n.Content[1].Kind {
+	case yaml.ScalarNode:
+		options.Path = n.Content[1].Value
+	case yaml.MappingNode:
+		if err := n.Content[1].Decode(&options); err != nil {
+			return false, err
+		}
+	default:
+		return false, errors.New("expected a path or a map with Path and Data")
+	}
+	if options.Path == "" {
+		return false, errors.New("missing Path")
+	}
+
+	data := templateData{
+		Constants: make(map[string]any),
+		Params:    Params,
+	}
+	for name, c := range ctx.t.Constants {
+		var v any
+		if err := c.Decode(&v); err != nil {
+			return false, fmt.Errorf("unable to decode constant %s: %v", name, err)
+		}
+		data.Constants[name] = v
+	}
+
+	var err error
+	data.Data, err = templateInput(&options.Data, ctx.rootDir)
+	if err != nil {
+		return false, err
+	}
+
+	path := options.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ctx.rootDir, path)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	if data.Params == nil {
+		data.Params = make(map[string]string)
+		for _, m := range paramRef.FindAllStringSubmatch(string(content), -1) {
+			data.Params[m[1]] = ""
+		}
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).
+		Funcs(templateFuncs).
+		Option("missingkey=error").
+		Parse(string(content))
+	if err != nil {
+		return false, err
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return false, err
+	}
+
+	var contentNode yaml.Node
+	if err := yaml.Unmarshal(rendered.Bytes(), &contentNode); err != nil {
+		return false, fmt.Errorf("unable to parse the output of '%s' as YAML: %v", path, err)
+	}
+	if len(contentNode.Content) == 0 {
+		return false, fmt.Errorf("'%s' rendered an empty document", path)
+	}
+
+	err = parse.NormalizeNode(&contentNode)
+	if err != nil {
+		return false, err
+	}
+
+	// The output can use other directives
+	_, err = transform(&transformContext{
+		nodeToTransform: &contentNode,
+		rootDir:         filepath.Dir(path),
+		t:               ctx.t,
+		parent:          nil,
+		fs:              nil,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// Unwrap from the document node
+	*n = *contentNode.Content[0]
+	return true, nil
+}
+
+// templateInput decodes the Data of a !Rain::Template, reading
+// the file that it names if it is a string
+func templateInput(n *yaml.Node, root string) (any, error) {
+	if n.Kind == 0 {
+		return nil, nil
+	}
+
+	if n.Kind != yaml.ScalarNode {
+		var v any
+		err := n.Decode(&v)
+		return v, err
+	}
+
+	path := n.Value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any
+	if err := yaml.Unmarshal(content, &v); err != nil {
+		return nil, fmt.Errorf("unable to parse '%s': %v", path, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/diff"
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+)
+
+func TestRainTemplate(t *testing.T) {
+	dir := t.TempDir()
+
+	policy := `
+Version: "2012-10-17"
+Statement:
+{{- range .Data.Buckets }}
+  - Effect: Allow
+    Action: {{ json $.Data.Actions }}
+    Resource: !Sub arn:${AWS::Partition}:s3:::{{ . }}-{{ $.Params.Env }}/*
+{{- end }}
+  - Effect: Deny
+    Action: "*"
+    Resource: {{ .Constants.Denied }}
+`
+	data := `
+Buckets: [logs, assets]
+Actions: [s3:GetObject, s3:PutObject]
+`
+	states := `Comment: {{ join ", " .Constants.States }}`
+
+	for name, content := range map[string]string{
+		"policy.tmpl": policy,
+		"data.yaml":   data,
+		"states.tmpl": states,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := `
+Rain:
+  Constants:
+    Denied: arn:aws:s3:::secret
+    States: [Start, Wait, Done]
+
+Resources:
+  Policy:
+    Type: AWS::IAM::ManagedPolicy
+    Properties:
+      PolicyDocument: !Rain::Template
+        Path: policy.tmpl
+        Data: data.yaml
+  Machine:
+    Type: AWS::StepFunctions::StateMachine
+    Properties:
+      Definition: !Rain::Template states.tmpl
+`
+	expect := `
+Resources:
+  Policy:
+    Type: AWS::IAM::ManagedPolicy
+    Properties:
+      PolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Action: [s3:GetObject, s3:PutObject]
+            Resource: !Sub arn:${AWS::Partition}:s3:::logs-prod/*
+          - Effect: Allow
+            Action: [s3:GetObject, s3:PutObject]
+            Resource: !Sub arn:${AWS::Partition}:s3:::assets-prod/*
+          - Effect: Deny
+            Action: "*"
+            Resource: arn:aws:s3:::secret
+  Machine:
+    Type: AWS::StepFunctions::StateMachine
+    Properties:
+      Definition:
+        Comment: Start, Wait, Done
+`
+
+	Params = map[string]string{"Env": "prod"}
+	defer func() { Params = nil }()
+
+	p, err := parse.String(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := Template(p, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	et, err := parse.String(expect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := diff.New(tmpl, et)
+	if d.Mode() != "=" {
+		t.Errorf("Output does not match expected: %v", d.Format(true))
+	}
+}
+
+func TestRainTemplateMissingParam(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "t.tmpl"), []byte("Name: {{ .Params.Missing }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := parse.String(`
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties: !Rain::Template t.tmpl
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Commands that take parameter values expect every parameter to be set
+	Params = map[string]string{}
+	defer func() { Params = nil }()
+	if _, err := Template(p, dir, nil); err == nil {
+		t.Error("expected an error for a missing parameter")
+	}
+
+	// Other commands see missing parameters as empty strings
+	Params = nil
+	packaged, err := Template(p, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := format.String(packaged, format.Options{}); !strings.Contains(out, "Name:") {
+		t.Errorf("expected an empty Name:\n%s", out)
+	}
+}
@@ -0,0 +1,249 @@
+package pkg
+
+// This file contains the implementation of the `!Rain::Image` directive,
+// which builds a container image and pushes it to a registry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws-cloudformation/rain/internal/aws/ecr"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"github.com/aws-cloudformation/rain/internal/console/spinner"
+	"gopkg.in/yaml.v3"
+)
+
+// ImageBuilder is the command that builds and pushes images, such as
+// docker, podman or buildah. They all take the same arguments.
+var ImageBuilder = "docker"
+
+// ImagePusher is where !Rain::Image puts the images that it builds
+type ImagePusher interface {
+	// Find returns the URI that an image with the tag is pushed to,
+	// and whether the registry already has it
+	Find(tag string) (uri string, exists bool, err error)
+
+	// Push pushes a local image to the URI that Find returned
+	Push(builder string, image string, uri string) error
+}
+
+// Images is where !Rain::Image pushes images. By default, it is
+// the ECR repository that rain bootstrap creates.
+var Images ImagePusher = &ecrPusher{}
+
+// ecrPusher pushes images to the rain ECR repository
+type ecrPusher struct {
+	repository string
+	loggedIn   bool
+}
+
+func (p *ecrPusher) Find(tag string) (string, bool, error) {
+	if p.repository == "" {
+		p.repository = ecr.RainRepository(false)
+	}
+
+	exists, err := ecr.ImageExists(ecr.RepositoryName, tag)
+	if err != nil {
+		return "", false, err
+	}
+
+	return p.repository + ":" + tag, exists, nil
+}
+
+func (p *ecrPusher) Push(builder string, image string, uri string) error {
+	if !p.loggedIn {
+		user, password, err := ecr.GetLoginPassword()
+		if err != nil {
+			return err
+		}
+
+		registry, _, _ := strings.Cut(p.repository, "/")
+		cmd := exec.Command(builder, "login", "--username", user, "--password-stdin", registry)
+		cmd.Stdin = strings.NewReader(password)
+		if err := runCommand(cmd); err != nil {
+			return fmt.Errorf("unable to log in to %s: %v", registry, err)
+		}
+		p.loggedIn = true
+	}
+
+	return pushImage(builder, image, uri)
+}
+
+// RegistryPusher pushes images to another registry, like a local registry
+// that tests can use without access to AWS
+type RegistryPusher struct {
+	// Repository is where images are pushed, like localhost:5000/rain
+	Repository string
+}
+
+func (p *RegistryPusher) Find(tag string) (string, bool, error) {
+	return p.Repository + ":" + tag, false, nil
+}
+
+func (p *RegistryPusher) Push(builder string, image string, uri string) error {
+	return pushImage(builder, image, uri)
+}
+
+func pushImage(builder string, image string, uri string) error {
+	if err := runCommand(exec.Command(builder, "tag", image, uri)); err != nil {
+		return err
+	}
+	return runCommand(exec.Command(builder, "push", uri))
+}
+
+type imageOptions struct {
+	Path       string            `yaml:"Path"`
+	Dockerfile string            `yaml:"Dockerfile"`
+	Platform   string            `yaml:"Platform"`
+	BuildArgs  map[string]string `yaml:"BuildArgs"`
+}
+
+// images are the URIs of images that have already been pushed, by tag
+var images = map[string]string{}
+
+// includeImage builds an image from a directory with a Dockerfile, tags it with a
+// hash of the directory, pushes it, and inserts the image URI into the template.
+// The value is a path, or an object with Path, Dockerfile, Platform and BuildArgs.
+func includeImage(ctx *directiveContext) (bool, error) {
+	n := ctx.n
+	if len(n.Content) != 2 {
+		return false, errors.New("expected exactly one key")
+	}
+
+	var options imageOptions
+	switch n.Content[1].Kind {
+	case yaml.ScalarNode:
+		options.Path = n.Content[1].Value
+	case yaml.MappingNode:
+		if err := n.Content[1].Decode(&options); err != nil {
+			return false, err
+		}
+	default:
+		return false, errors.New("expected a path or a map with Path")
+	}
+	if options.Path == "" {
+		return false, errors.New("missing Path")
+	}
+
+	uri, err := handleImage(ctx.rootDir, options)
+	if err != nil {
+		return false, err
+	}
+
+	return true, n.Encode(uri)
+}
+
+func handleImage(root string, options imageOptions) (string, error) {
+	start := time.Now()
+	spinner.StartTimer(fmt.Sprintf("Packaging image %s", options.Path))
+	defer spinner.StopTimer()
+
+	path := options.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+
+	tag, err := imageTag(path, options)
+	if err != nil {
+		return "", err
+	}
+
+	if uri, ok := images[tag]; ok {
+		return uri, nil
+	}
+
+	if DryRun {
+		uri := DryRunRepository + ":" + tag
+		images[tag] = uri
+		return uri, nil
+	}
+
+	uri, exists, err := Images.Find(tag)
+	if err != nil {
+		return "", err
+	}
+
+	// With NoCache, images are built and pushed again even if the tag exists
+	if NoCache {
+		exists = false
+	}
+
+	if !exists {
+		image := "rain-image:" + tag
+		args := []string{"build", "--tag", image}
+		if options.Dockerfile != "" {
+			args = append(args, "--file", filepath.Join(path, options.Dockerfile))
+		}
+		if options.Platform != "" {
+			args = append(args, "--platform", options.Platform)
+		}
+		for _, k := range sortedKeys(options.BuildArgs) {
+			args = append(args, "--build-arg", k+"="+options.BuildArgs[k])
+		}
+		args = append(args, path)
+
+		if err := runCommand(exec.Command(ImageBuilder, args...)); err != nil {
+			return "", fmt.Errorf("unable to build image %s: %v", options.Path, err)
+		}
+
+		if err := Images.Push(ImageBuilder, image, uri); err != nil {
+			return "", fmt.Errorf("unable to push image %s: %v", options.Path, err)
+		}
+	}
+
+	config.Debugf("Packaged image %s as %s in %s (cached: %v)",
+		options.Path, uri, time.Since(start).Truncate(time.Millisecond), exists)
+
+	images[tag] = uri
+	return uri, nil
+}
+
+// imageTag returns a hash of the files that an image is built from,
+// and the options that change how it is built
+func imageTag(path string, options imageOptions) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("expected '%s' to be a directory", path)
+	}
+
+	run := fmt.Sprintf("image:%s:%s", options.Dockerfile, options.Platform)
+	for _, k := range sortedKeys(options.BuildArgs) {
+		run += fmt.Sprintf(":%s=%s", k, options.BuildArgs[k])
+	}
+
+	hash, err := assetHash(path, "", ".", "", false)
+	if err != nil {
+		return "", err
+	}
+
+	return hashString(run + ":" + hash), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// runCommand runs a build tool command, and
+// returns its output in the error if it fails
+func runCommand(cmd *exec.Cmd) error {
+	config.Debugf("Running %s", strings.Join(cmd.Args, " "))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %v: %s", cmd.Args[0], cmd.Args[1], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
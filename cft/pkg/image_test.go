@@ -0,0 +1,147 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+)
+
+func TestRainImage(t *testing.T) {
+	dir := t.TempDir()
+
+	// A fake builder that records the commands it is given
+	log := filepath.Join(dir, "builder.log")
+	builder := filepath.Join(dir, "builder.sh")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\n"
+	if err := os.WriteFile(builder, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	app := filepath.Join(dir, "app")
+	if err := os.MkdirAll(app, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(app, "Dockerfile"), []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ImageBuilder = builder
+	Images = &RegistryPusher{Repository: "localhost:5000/rain"}
+	defer func() {
+		ImageBuilder = "docker"
+		Images = &ecrPusher{}
+		images = map[string]string{}
+	}()
+
+	p, err := parse.String(`
+Resources:
+  Function:
+    Type: AWS::Lambda::Function
+    Properties:
+      Code:
+        ImageUri: !Rain::Image app
+  Other:
+    Type: AWS::Lambda::Function
+    Properties:
+      Code:
+        ImageUri: !Rain::Image
+          Path: app
+          Platform: linux/arm64
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := Template(p, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := imageTag(app, imageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := format.String(tmpl, format.Options{})
+	if !strings.Contains(out, "ImageUri: localhost:5000/rain:"+tag) {
+		t.Errorf("expected the image URI in:\n%s", out)
+	}
+
+	commands, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(commands)), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("expected a build, tag and push for each image, got:\n%s", commands)
+	}
+	if !strings.Contains(string(commands), "push localhost:5000/rain:"+tag) {
+		t.Errorf("expected a push of %s, got:\n%s", tag, commands)
+	}
+	if !strings.Contains(string(commands), "--platform linux/arm64") {
+		t.Errorf("expected a build for linux/arm64, got:\n%s", commands)
+	}
+
+	// Changing the directory changes the tag
+	if err := os.WriteFile(filepath.Join(app, "main.py"), []byte("print('a')\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := imageTag(app, imageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed == tag {
+		t.Error("expected a change to the directory to change the tag")
+	}
+}
+
+// existingPusher finds every image, as if it had already been pushed
+type existingPusher struct {
+	RegistryPusher
+}
+
+func (p *existingPusher) Find(tag string) (string, bool, error) {
+	return p.Repository + ":" + tag, true, nil
+}
+
+func TestRainImageNoCache(t *testing.T) {
+	dir := t.TempDir()
+
+	log := filepath.Join(dir, "builder.log")
+	builder := filepath.Join(dir, "builder.sh")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\n"
+	if err := os.WriteFile(builder, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ImageBuilder = builder
+	Images = &existingPusher{RegistryPusher{Repository: "localhost:5000/rain"}}
+	defer func() {
+		ImageBuilder = "docker"
+		Images = &ecrPusher{}
+		NoCache = false
+		images = map[string]string{}
+	}()
+
+	for _, noCache := range []bool{false, true} {
+		NoCache = noCache
+		images = map[string]string{}
+		os.Remove(log)
+
+		if _, err := handleImage(dir, imageOptions{Path: "."}); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := os.Stat(log)
+		if built := err == nil; built != noCache {
+			t.Errorf("with NoCache %v, expected built to be %v", noCache, noCache)
+		}
+	}
+}
@@ -334,9 +334,17 @@ func resolveModuleSub(parentName string, prop *yaml.Node, sidx int, ctx *refctx)
 						// This would need to resolve to a string so assume a len of 2
 						if len(parentVal.Content) == 2 {
 							needSub = true
-							if parentVal.Content[0].Value == "Ref" {
+							switch {
+							case parentVal.Content[0].Value == "Ref":
 								resolved = fmt.Sprintf("${%s}", parentVal.Content[1].Value)
-							} else {
+							case parentVal.Content[0].Value == "Fn::GetAtt" && parentVal.Content[1].Kind == yaml.SequenceNode:
+								// [Name, Attribute] becomes ${Name.Attribute}
+								parts := make([]string, 0)
+								for _, p := range parentVal.Content[1].Content {
+									parts = append(parts, p.Value)
+								}
+								resolved = fmt.Sprintf("${%s}", strings.Join(parts, "."))
+							default:
 								resolved = parentVal.Content[1].Value
 							}
 						}
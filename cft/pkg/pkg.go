@@ -22,6 +22,21 @@
 //	must be called "ModuleExtension", and it must have a Metadata entry called
 //	"Extends" that supplies the existing type to be extended. The Parameters section
 //	of the module can be used to define additional properties for the extension.
+//
+// `Rain::Template`: renders a file with Go text/template and inserts the result as YAML.
+//
+//	The template can use .Constants from the Rain section, .Params from --params
+//	and --config, and .Data, which is set with an object that has Path and Data properties.
+//
+// `Rain::Ssm`: inserts the value of an SSM parameter as a string.
+// `Rain::Secret`: inserts the value of a Secrets Manager secret as a string. Supply an object
+//
+//	with SecretId and Key to insert one key of a secret that is a JSON object.
+//	Values come from Values, which can be set to read a local file instead of AWS.
+//
+// `Rain::Image`: builds a container image from a directory with a Dockerfile, tags it with a hash
+//
+//	of the directory, pushes it to Images, and inserts the image URI as a string.
 package pkg
 
 import (
@@ -35,11 +50,15 @@ import (
 	rainpkl "github.com/aws-cloudformation/rain/pkl"
 
 	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/langext"
 	"github.com/aws-cloudformation/rain/cft/parse"
 	"github.com/aws-cloudformation/rain/cft/visitor"
 	"github.com/aws-cloudformation/rain/internal/config"
 	"github.com/aws-cloudformation/rain/internal/node"
 	"github.com/aws-cloudformation/rain/internal/s11n"
+	"github.com/aws-cloudformation/rain/plugins/deployconfig"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
 	"gopkg.in/yaml.v3"
 )
 
@@ -48,6 +67,11 @@ var Experimental bool
 var NoAnalytics bool
 var HasRainSection bool
 
+// ExpandLanguageExtensions expands Fn::ForEach and the other functions of the
+// AWS::LanguageExtensions transform in the packaged template. Anything that
+// depends on a parameter that is not in Params is left for the transform.
+var ExpandLanguageExtensions bool
+
 type analytics struct {
 	// Current Rain version
 	Version string
@@ -142,7 +166,9 @@ func processRainSection(t *cft.Template) bool {
 			t.Packages[k] = p
 		}
 
-		// Visit all resources to look for Type nodes that use $alias.module shorthand
+		// Visit all resources to look for Type nodes that use $alias.module shorthand.
+		// Each resource is checked on its own, so that one that isn't a mapping,
+		// which would stop the template from becoming a Model, doesn't stop the rest.
 		resources, err := t.GetSection(cft.Resources)
 		if err == nil {
 			for i := 0; i < len(resources.Content); i += 2 {
@@ -183,6 +209,8 @@ func Template(t cft.Template, rootDir string, fs *embed.FS) (cft.Template, error
 	templateNode := t.Node
 	var err error
 
+	secretNodes = make(map[*yaml.Node]bool)
+
 	//config.Debugf("Original template short: %v", node.ToSJson(t.Node))
 	//config.Debugf("Original template long: %v", node.ToJson(t.Node))
 
@@ -284,6 +312,25 @@ func Template(t cft.Template, rootDir string, fs *embed.FS) (cft.Template, error
 		retval.Node.Content = append(retval.Node.Content, templateNode)
 	}
 
+	if ExpandLanguageExtensions && langext.Uses(retval) {
+		// Parameters that are not in Params are only known when the
+		// template is deployed, so anything that uses them is left
+		// for the transform
+		dc := &deployconfig.DeployConfig{}
+		for _, k := range sortedKeys(Params) {
+			dc.Params = append(dc.Params, types.Parameter{
+				ParameterKey:   ptr.String(k),
+				ParameterValue: ptr.String(Params[k]),
+			})
+		}
+		retval, err = langext.Expand(retval, langext.Options{Config: dc, NoDefaults: true})
+		if err != nil {
+			return retval, fmt.Errorf("failed to expand language extensions: %v", err)
+		}
+	}
+
+	markSecrets(retval.Node)
+
 	// Add analytics to Metadata
 	if !NoAnalytics {
 		metadata, err := retval.GetSection(cft.Metadata)
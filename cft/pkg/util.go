@@ -111,7 +111,8 @@ func zipPath(root string) (string, error) {
 
 // Upload a file or directory to S3.
 // If path is a directory, it will be zipped first.
-func upload(root, path string, force bool) (*s3Path, error) {
+// The object key ends with name, which is the asset's hash.
+func upload(root, path string, force bool, name string) (*s3Path, error) {
 	if !filepath.IsAbs(path) {
 		path = filepath.Join(root, path)
 		if abs, err := filepath.Abs(path); err == nil {
@@ -151,8 +152,17 @@ func upload(root, path string, force bool) (*s3Path, error) {
 		return nil, err
 	}
 
+	if DryRun {
+		s, err := dryRunUpload(root, name, content)
+		if err != nil {
+			return nil, err
+		}
+		uploads[artifactName] = s
+		return s, nil
+	}
+
 	bucket := s3.RainBucket(false)
-	key, err := s3.Upload(bucket, content)
+	key, err := s3.UploadAs(bucket, name, content)
 
 	uploads[artifactName] = &s3Path{
 		bucket: bucket,
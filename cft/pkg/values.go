@@ -0,0 +1,203 @@
+package pkg
+
+// This file contains the implementations of the `!Rain::Ssm`
+// and `!Rain::Secret` directives, which look up values while packaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws-cloudformation/rain/internal/aws/ssm"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces secret values in output that should not show them
+const Redacted = "****"
+
+// ValueSource looks up the values for !Rain::Ssm and !Rain::Secret
+type ValueSource interface {
+	// Parameter returns the value of an SSM parameter
+	Parameter(name string) (string, error)
+
+	// Secret returns the value of a Secrets Manager secret
+	Secret(id string) (string, error)
+}
+
+// Values is where !Rain::Ssm and !Rain::Secret get their values.
+// By default, it reads them from the AWS account.
+var Values ValueSource = awsValues{}
+
+// awsValues reads values from SSM Parameter Store and Secrets Manager
+type awsValues struct{}
+
+func (awsValues) Parameter(name string) (string, error) {
+	return ssm.GetParameter(name)
+}
+
+func (awsValues) Secret(id string) (string, error) {
+	return ssm.GetSecret(id)
+}
+
+// FileValues reads values from a local YAML or JSON file, so that
+// templates can be packaged without access to an AWS account
+type FileValues struct {
+	Parameters map[string]string `yaml:"Parameters"`
+	Secrets    map[string]string `yaml:"Secrets"`
+}
+
+// NewFileValues reads a file with Parameters and Secrets maps:
+//
+//	Parameters:
+//	  /app/vpc-id: vpc-123
+//	Secrets:
+//	  app/db: '{"password": "example"}'
+func NewFileValues(path string) (*FileValues, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var v FileValues
+	if err := yaml.Unmarshal(content, &v); err != nil {
+		return nil, fmt.Errorf("unable to parse '%s': %v", path, err)
+	}
+	return &v, nil
+}
+
+func (f *FileValues) Parameter(name string) (string, error) {
+	if v, ok := f.Parameters[name]; ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("parameter %s not found", name)
+}
+
+func (f *FileValues) Secret(id string) (string, error) {
+	if v, ok := f.Secrets[id]; ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("secret %s not found", id)
+}
+
+// ResolveSecrets makes !Rain::Secret insert the value of the secret.
+// Otherwise it inserts a dynamic reference, so that the value is not
+// stored in the template that CloudFormation keeps for the stack. Only
+// rain pkg sets it, since it redacts the values in its output.
+var ResolveSecrets bool
+
+// secretNodes are the nodes that !Rain::Secret wrote values to
+// during the last call to Template
+var secretNodes = make(map[*yaml.Node]bool)
+
+// secretTag marks the nodes that !Rain::Secret wrote values to while the
+// template is transformed. Template re-parses and clones nodes, so the
+// mark has to survive that. It is not a CloudFormation tag, so parse
+// leaves it alone, and markSecrets removes it at the end of Template.
+const secretTag = "!rain-secret"
+
+type secretOptions struct {
+	SecretId string `yaml:"SecretId"`
+	Key      string `yaml:"Key"`
+}
+
+func includeSsm(ctx *directiveContext) (bool, error) {
+	name, err := expectString(ctx.n)
+	if err != nil {
+		return false, err
+	}
+
+	if dryRunValues() {
+		return true, ctx.n.Encode(fmt.Sprintf("{{resolve:ssm:%s}}", name))
+	}
+
+	config.Debugf("Looking up SSM parameter %s", name)
+	val, err := Values.Parameter(name)
+	if err != nil {
+		return false, fmt.Errorf("unable to read SSM parameter %s: %v", name, err)
+	}
+
+	return true, ctx.n.Encode(val)
+}
+
+// includeSecret inserts the value of a secret, or one key of a secret
+// that is a JSON object. The value is a secret id, or an object with
+// SecretId and Key properties.
+func includeSecret(ctx *directiveContext) (bool, error) {
+	n := ctx.n
+	if len(n.Content) != 2 {
+		return false, errors.New("expected exactly one key")
+	}
+
+	var options secretOptions
+	switch n.Content[1].Kind {
+	case yaml.ScalarNode:
+		options.SecretId = n.Content[1].Value
+	case yaml.MappingNode:
+		if err := n.Content[1].Decode(&options); err != nil {
+			return false, err
+		}
+	default:
+		return false, errors.New("expected a secret id or a map with SecretId and Key")
+	}
+	if options.SecretId == "" {
+		return false, errors.New("missing SecretId")
+	}
+
+	if !ResolveSecrets || dryRunValues() {
+		ref := "{{resolve:secretsmanager:" + options.SecretId
+		if options.Key != "" {
+			ref += ":SecretString:" + options.Key
+		}
+		return true, n.Encode(ref + "}}")
+	}
+
+	config.Debugf("Looking up secret %s", options.SecretId)
+	val, err := Values.Secret(options.SecretId)
+	if err != nil {
+		return false, fmt.Errorf("unable to read secret %s: %v", options.SecretId, err)
+	}
+
+	if options.Key != "" {
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(val), &fields); err != nil {
+			return false, fmt.Errorf("expected secret %s to be a JSON object: %v", options.SecretId, err)
+		}
+		field, ok := fields[options.Key]
+		if !ok {
+			return false, fmt.Errorf("secret %s does not have the key %s", options.SecretId, options.Key)
+		}
+		val = fmt.Sprint(field)
+	}
+
+	if err := n.Encode(val); err != nil {
+		return false, err
+	}
+	n.Tag = secretTag
+
+	return true, nil
+}
+
+// markSecrets records the nodes that have secretTag in secretNodes
+// and sets their tag back to a plain string
+func markSecrets(n *yaml.Node) {
+	if n.Tag == secretTag {
+		n.Tag = "!!str"
+		secretNodes[n] = true
+	}
+	for _, c := range n.Content {
+		markSecrets(c)
+	}
+}
+
+// Redact replaces the values of secrets that !Rain::Secret put into
+// the template with Redacted, so that they can be shown safely
+func Redact(n *yaml.Node) {
+	if secretNodes[n] && n.Kind == yaml.ScalarNode {
+		n.Value = Redacted
+	}
+	for _, c := range n.Content {
+		Redact(c)
+	}
+}
@@ -0,0 +1,183 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+)
+
+func TestValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "values.yaml")
+	err := os.WriteFile(path, []byte(`
+Parameters:
+  /app/vpc-id: vpc-123
+Secrets:
+  app/token: s3cr3t
+  app/db: '{"username": "admin", "password": "hunter2"}'
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := NewFileValues(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Values = values
+	ResolveSecrets = true
+	defer func() {
+		Values = awsValues{}
+		ResolveSecrets = false
+	}()
+
+	p, err := parse.String(`
+Resources:
+  Group:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      VpcId: !Rain::Ssm /app/vpc-id
+      GroupDescription: !Rain::Secret app/token
+  Database:
+    Type: AWS::RDS::DBInstance
+    Properties:
+      MasterUsername: !Rain::Secret {SecretId: app/db, Key: username}
+      MasterUserPassword: !Rain::Secret
+        SecretId: app/db
+        Key: password
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := Template(p, ".", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := format.String(tmpl, format.Options{})
+	for _, expected := range []string{"VpcId: vpc-123", "GroupDescription: s3cr3t", "MasterUsername: admin", "MasterUserPassword: hunter2"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected '%s' in:\n%s", expected, out)
+		}
+	}
+
+	Redact(tmpl.Node)
+	out = format.String(tmpl, format.Options{})
+	for _, secret := range []string{"s3cr3t", "admin", "hunter2"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("expected '%s' to be redacted:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "VpcId: vpc-123") {
+		t.Errorf("expected parameters not to be redacted:\n%s", out)
+	}
+
+	p, err = parse.String(`
+Resources:
+  Group:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      VpcId: !Rain::Ssm /app/missing
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Template(p, ".", nil); err == nil {
+		t.Error("expected an error for a missing parameter")
+	}
+}
+
+func TestSecretReference(t *testing.T) {
+	Values = &FileValues{Secrets: map[string]string{"app/db": `{"password": "hunter2"}`}}
+	defer func() { Values = awsValues{} }()
+
+	p, err := parse.String(`
+Resources:
+  Database:
+    Type: AWS::RDS::DBInstance
+    Properties:
+      MasterUserPassword: !Rain::Secret {SecretId: app/db, Key: password}
+      DBName: !Rain::Secret app/db
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := Template(p, ".", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Secrets are only resolved by rain pkg, which redacts its output
+	out := format.String(tmpl, format.Options{})
+	for _, expected := range []string{
+		"MasterUserPassword: '{{resolve:secretsmanager:app/db:SecretString:password}}'",
+		"DBName: '{{resolve:secretsmanager:app/db}}'",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected '%s' in:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("expected the secret not to be in the template:\n%s", out)
+	}
+}
+
+func TestRedactOnlySecrets(t *testing.T) {
+	Values = &FileValues{Secrets: map[string]string{"app/flag": "1"}}
+	ResolveSecrets = true
+	defer func() {
+		Values = awsValues{}
+		ResolveSecrets = false
+	}()
+
+	p, err := parse.String(`
+Resources:
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      DelaySeconds: !Rain::Secret app/flag
+      MaximumMessageSize: 1024
+      "1": one
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := Template(p, ".", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Redact(tmpl.Node)
+
+	out := format.String(tmpl, format.Options{})
+	for _, expected := range []string{"DelaySeconds: '" + Redacted + "'", "MaximumMessageSize: 1024", "\"1\": one"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected '%s' in:\n%s", expected, out)
+		}
+	}
+
+	// Redaction only applies to the nodes from the last Template call
+	p, err = parse.String(`
+Resources:
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      DelaySeconds: 1
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err = Template(p, ".", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Redact(tmpl.Node)
+	if out := format.String(tmpl, format.Options{}); !strings.Contains(out, "DelaySeconds: 1") {
+		t.Errorf("expected nothing to be redacted:\n%s", out)
+	}
+}
@@ -0,0 +1,170 @@
+package refactor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/internal/s11n"
+	"gopkg.in/yaml.v3"
+)
+
+// Extract moves resources from t into a new Rain module, and replaces
+// them with a single resource called moduleName with Type: !Rain::Module source.
+//
+// When the module is packaged, its resources are named moduleName followed
+// by the name in the module. If all of the resources already start with
+// moduleName, the prefix is removed in the module, so that the packaged
+// template has the same logical ids as before. Otherwise each resource is
+// renamed to moduleName followed by its old name, along with any references
+// to it in t, and the new names are returned.
+//
+// References from the extracted resources to anything else in t become module
+// Parameters, with the values set in the module resource's Properties.
+// DependsOn on resources that stay in t moves to the module resource's Overrides.
+// Conditions and mappings are resolved in t when the module is packaged, so
+// they are left as they are.
+func Extract(t cft.Template, names []string, moduleName, source string) (cft.Template, map[string]string, error) {
+	if len(names) == 0 {
+		return cft.Template{}, nil, fmt.Errorf("no resources to extract")
+	}
+
+	if err := checkNewName(t, moduleName); err != nil {
+		return cft.Template{}, nil, err
+	}
+
+	resources, err := t.GetSection(cft.Resources)
+	if err != nil {
+		return cft.Template{}, nil, err
+	}
+
+	names = slices.Clone(names)
+	prefixed := true
+	for i, name := range names {
+		if slices.Index(names, name) != i {
+			return cft.Template{}, nil, fmt.Errorf("'%s' is listed more than once", name)
+		}
+		if !hasKey(resources, name) {
+			return cft.Template{}, nil, fmt.Errorf("'%s' is not a resource", name)
+		}
+		if !strings.HasPrefix(name, moduleName) {
+			prefixed = false
+		}
+	}
+
+	renamed := make(map[string]string)
+	if !prefixed {
+		for i, name := range names {
+			if _, err := Rename(t, name, moduleName+name); err != nil {
+				return cft.Template{}, nil, err
+			}
+			renamed[name] = moduleName + name
+			names[i] = moduleName + name
+		}
+	}
+
+	// Move the resources, in the order they are in the template,
+	// and put the module resource where the first one was
+	moduleResources := &yaml.Node{Kind: yaml.MappingNode}
+	content := make([]*yaml.Node, 0)
+	position := -1
+	for i := 0; i < len(resources.Content); i += 2 {
+		if slices.Contains(names, resources.Content[i].Value) {
+			if position < 0 {
+				position = len(content)
+			}
+			moduleResources.Content = append(moduleResources.Content, resources.Content[i], resources.Content[i+1])
+		} else {
+			content = append(content, resources.Content[i], resources.Content[i+1])
+		}
+	}
+
+	module := cft.Template{Node: &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{
+		{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: string(cft.Resources)},
+			moduleResources,
+		}},
+	}}}
+
+	// Refer to the resources by their names in the module
+	local := make([]string, 0)
+	for _, name := range names {
+		localName := strings.TrimPrefix(name, moduleName)
+		if _, err := Rename(module, name, localName); err != nil {
+			return cft.Template{}, nil, err
+		}
+		local = append(local, localName)
+	}
+
+	for i := 1; i < len(moduleResources.Content); i += 2 {
+		if hasSubVariables(moduleResources.Content[i]) {
+			// rain pkg does not resolve the variables of a Sub in a module
+			return cft.Template{}, nil, fmt.Errorf("'%s' uses a Fn::Sub with a list of variables, which can't be used in a module",
+				moduleResources.Content[i-1].Value)
+		}
+	}
+
+	parentParams, _ := t.GetSection(cft.Parameters)
+	iso := NewIsolator(local, parentParams)
+	if err := iso.Resources(moduleResources); err != nil {
+		return cft.Template{}, nil, err
+	}
+
+	if len(iso.Parameters.Content) > 0 {
+		module.Node.Content[0].Content = append([]*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: string(cft.Parameters)},
+			iso.Parameters,
+		}, module.Node.Content[0].Content...)
+	}
+
+	resource := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "Type"},
+		{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "Rain::Module"},
+			{Kind: yaml.ScalarNode, Value: source},
+		}},
+	}}
+	if len(iso.Values.Content) > 0 {
+		resource.Content = append(resource.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "Properties"}, iso.Values)
+	}
+	if len(iso.DependsOn.Content) > 0 {
+		resource.Content = append(resource.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "Overrides"}, iso.DependsOn)
+	}
+
+	resources.Content = slices.Insert(content, position,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: moduleName}, resource)
+
+	return module, renamed, nil
+}
+
+// hasKey returns true if the mapping n has the key
+func hasKey(n *yaml.Node, key string) bool {
+	if n == nil {
+		return false
+	}
+	_, v, _ := s11n.GetMapValue(n, key)
+	return v != nil
+}
+
+// hasSubVariables returns true if n contains a Fn::Sub with a list of variables
+func hasSubVariables(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(n.Content); i += 2 {
+			if n.Content[i].Value == "Fn::Sub" && n.Content[i+1].Kind == yaml.SequenceNode {
+				return true
+			}
+			if hasSubVariables(n.Content[i+1]) {
+				return true
+			}
+		}
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			if hasSubVariables(c) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package refactor_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/diff"
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/cft/pkg"
+	"github.com/aws-cloudformation/rain/cft/refactor"
+)
+
+const extractSource = `
+Parameters:
+  Env:
+    Type: String
+    AllowedValues: [dev, prod]
+
+Conditions:
+  IsProd: !Equals [!Ref Env, prod]
+
+Resources:
+  Role:
+    Type: AWS::IAM::Role
+    Properties:
+      AssumeRolePolicyDocument: {}
+
+  # The data bucket
+  StorageBucket:
+    Type: AWS::S3::Bucket
+    Condition: IsProd
+    DependsOn: [StorageLogBucket, Role]
+    Properties:
+      BucketName: !Sub ${Env}-${AWS::Region}-data
+      LoggingConfiguration:
+        DestinationBucketName: !Ref StorageLogBucket
+
+  StorageLogBucket:
+    Type: AWS::S3::Bucket
+
+  StoragePolicy:
+    Type: AWS::S3::BucketPolicy
+    Properties:
+      Bucket: !Ref StorageBucket
+      PolicyDocument:
+        Statement:
+          - Principal:
+              AWS: !GetAtt Role.Arn
+            Resource: !Sub ${StorageBucket.Arn}/*
+          - Principal:
+              AWS: !Sub ${Role.Arn}
+            Resource: !GetAtt StorageLogBucket.Arn
+
+  Function:
+    Type: AWS::Lambda::Function
+    Properties:
+      Environment:
+        Variables:
+          BUCKET: !Ref StorageBucket
+`
+
+func TestExtract(t *testing.T) {
+	template, err := parse.String(extractSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module, renamed, err := refactor.Extract(template,
+		[]string{"StorageBucket", "StorageLogBucket", "StoragePolicy"}, "Storage", "./storage.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(renamed) != 0 {
+		t.Errorf("expected no renamed resources, got %v", renamed)
+	}
+
+	out := format.String(template, format.Options{Unsorted: true})
+	expected := `  Storage:
+    Type: !Rain::Module ./storage.yaml
+    Properties:
+      Env: !Ref Env
+      RoleArn: !GetAtt Role.Arn
+    Overrides:
+      Bucket:
+        DependsOn:
+          - Role
+`
+	if !strings.Contains(out, expected) {
+		t.Errorf("expected module resource:\n%s\nin:\n%s", expected, out)
+	}
+
+	m := format.String(module, format.Options{Unsorted: true})
+	for _, s := range []string{
+		"Env:\n    Type: String\n",
+		"RoleArn:\n    Type: String\n",
+		"# The data bucket\n  Bucket:",
+		"DependsOn:\n      - LogBucket",
+		"DestinationBucketName: !Ref LogBucket",
+		"AWS: !Ref RoleArn",
+		"AWS: !Sub ${RoleArn}",
+		"Resource: !Sub ${Bucket.Arn}/*",
+	} {
+		if !strings.Contains(m, s) {
+			t.Errorf("expected %q in module:\n%s", s, m)
+		}
+	}
+
+	// Packaging the module gives back the original template
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "storage.yaml"), []byte(m), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fn := filepath.Join(dir, "template.yaml")
+	if err := os.WriteFile(fn, []byte(out), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg.Experimental = true
+	pkg.NoAnalytics = true
+	packaged, err := pkg.File(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := parse.String(extractSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := diff.New(original, packaged)
+	if d.Mode() != "=" {
+		t.Errorf("packaged module does not match the original template: %s", d.Format(true))
+	}
+}
+
+func TestExtractRename(t *testing.T) {
+	template, err := parse.String(extractSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, renamed, err := refactor.Extract(template, []string{"Role"}, "Auth", "./auth.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if renamed["Role"] != "AuthRole" {
+		t.Errorf("expected Role to be renamed to AuthRole, got %v", renamed)
+	}
+
+	out := format.String(template, format.Options{Unsorted: true})
+	if !strings.Contains(out, "AWS: !GetAtt AuthRole.Arn") {
+		t.Errorf("expected references to the renamed resource:\n%s", out)
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	template, err := parse.String(extractSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, names := range [][]string{
+		{},
+		{"Missing"},
+		{"Role", "Role"},
+	} {
+		if _, _, err := refactor.Extract(template, names, "Module", "./module.yaml"); err == nil {
+			t.Errorf("expected an error extracting %v", names)
+		}
+	}
+
+	if _, _, err := refactor.Extract(template, []string{"Role"}, "Function", "./module.yaml"); err == nil {
+		t.Errorf("expected an error for an existing module name")
+	}
+}
@@ -0,0 +1,269 @@
+package refactor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/internal/node"
+	"github.com/aws-cloudformation/rain/internal/s11n"
+	"gopkg.in/yaml.v3"
+)
+
+var notAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// Isolator rewrites a set of resources that are moving out of a template,
+// so that anything they refer to in the template becomes a parameter.
+// A Ref to a parameter or resource becomes a parameter with the same name,
+// and a GetAtt becomes a parameter like RoleArn for Role.Arn.
+type Isolator struct {
+	// Parameters are the new parameters, in the format of a Parameters section
+	Parameters *yaml.Node
+
+	// Values maps each new parameter to the Ref or GetAtt in the
+	// template that it replaces
+	Values *yaml.Node
+
+	// DependsOn maps the name of each resource that depended on resources
+	// in the template to {DependsOn: [names]}, in the format of the
+	// Overrides of a module. The names are removed from the resource.
+	DependsOn *yaml.Node
+
+	// local is the names of the resources that are moving
+	local map[string]bool
+
+	// templateParams is the Parameters section of the template, if it has one
+	templateParams *yaml.Node
+}
+
+// NewIsolator returns an Isolator for the named resources. Parameters
+// that come from parameters in the template have the same Type.
+func NewIsolator(names []string, templateParams *yaml.Node) *Isolator {
+	local := make(map[string]bool)
+	for _, name := range names {
+		local[name] = true
+	}
+	return &Isolator{
+		Parameters:     &yaml.Node{Kind: yaml.MappingNode},
+		Values:         &yaml.Node{Kind: yaml.MappingNode},
+		DependsOn:      &yaml.Node{Kind: yaml.MappingNode},
+		local:          local,
+		templateParams: templateParams,
+	}
+}
+
+// Resources rewrites each resource in a Resources section
+func (iso *Isolator) Resources(resources *yaml.Node) error {
+	for i := 0; i < len(resources.Content); i += 2 {
+		iso.dependsOn(resources.Content[i].Value, resources.Content[i+1])
+		if err := iso.Walk(resources.Content[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Walk replaces references to the template in n and its children
+// with references to parameters
+func (iso *Isolator) Walk(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			if err := iso.Walk(c); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		if len(n.Content) == 2 {
+			key, value := n.Content[0].Value, n.Content[1]
+			switch key {
+			case "Ref":
+				if value.Kind == yaml.ScalarNode && iso.outside(value.Value) {
+					return iso.refParam(value.Value)
+				}
+				return nil
+			case "Fn::GetAtt":
+				name, attribute := getAttParts(value)
+				if name != "" && iso.outside(name) {
+					paramName, err := iso.getAttParam(name, attribute)
+					if err != nil {
+						return err
+					}
+					*n = yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+						{Kind: yaml.ScalarNode, Value: "Ref"},
+						{Kind: yaml.ScalarNode, Value: paramName},
+					}}
+				}
+				return nil
+			case "Fn::Sub":
+				if err := iso.sub(value); err != nil {
+					return err
+				}
+			}
+		}
+		for i := 1; i < len(n.Content); i += 2 {
+			if err := iso.Walk(n.Content[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addParam adds a parameter that gets its value from the template
+func (iso *Isolator) addParam(name string, value *yaml.Node) error {
+	if iso.local[name] {
+		return fmt.Errorf("a parameter called '%s' is needed, which is also the name of a resource that is moving", name)
+	}
+	if hasKey(iso.Parameters, name) {
+		return nil
+	}
+
+	// Use the type of the template parameter it comes from
+	typ := &yaml.Node{Kind: yaml.ScalarNode, Value: "String"}
+	if iso.templateParams != nil {
+		if _, p, _ := s11n.GetMapValue(iso.templateParams, name); p != nil {
+			if _, t, _ := s11n.GetMapValue(p, "Type"); t != nil {
+				typ = node.Clone(t)
+			}
+		}
+	}
+
+	iso.Parameters.Content = append(iso.Parameters.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: name},
+		&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "Type"},
+			typ,
+		}})
+	iso.Values.Content = append(iso.Values.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: name},
+		value)
+
+	return nil
+}
+
+// refParam adds a parameter for a Ref to something in the template
+func (iso *Isolator) refParam(name string) error {
+	return iso.addParam(name, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "Ref"},
+		{Kind: yaml.ScalarNode, Value: name},
+	}})
+}
+
+// getAttParam adds a parameter for a GetAtt on a resource in the
+// template, and returns the name of the parameter, like RoleArn for Role.Arn
+func (iso *Isolator) getAttParam(name, attribute string) (string, error) {
+	paramName := name + notAlphanumeric.ReplaceAllString(attribute, "")
+	return paramName, iso.addParam(paramName, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "Fn::GetAtt"},
+		{Kind: yaml.SequenceNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: name},
+			{Kind: yaml.ScalarNode, Value: attribute},
+		}},
+	}})
+}
+
+// outside returns true if the name refers to something in the template
+func (iso *Isolator) outside(name string) bool {
+	return !iso.local[name] && !strings.HasPrefix(name, "AWS::")
+}
+
+// dependsOn moves dependencies on resources in the template to iso.DependsOn
+func (iso *Isolator) dependsOn(name string, resource *yaml.Node) {
+	_, d, _ := s11n.GetMapValue(resource, "DependsOn")
+	if d == nil {
+		return
+	}
+
+	values := []*yaml.Node{d}
+	if d.Kind == yaml.SequenceNode {
+		values = d.Content
+	}
+
+	keep := make([]*yaml.Node, 0)
+	move := make([]*yaml.Node, 0)
+	for _, v := range values {
+		if iso.outside(v.Value) {
+			move = append(move, v)
+		} else {
+			keep = append(keep, v)
+		}
+	}
+	if len(move) == 0 {
+		return
+	}
+
+	if len(keep) == 0 {
+		node.RemoveFromMap(resource, "DependsOn")
+	} else {
+		d.Kind = yaml.SequenceNode
+		d.Value = ""
+		d.Content = keep
+	}
+
+	iso.DependsOn.Content = append(iso.DependsOn.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: name},
+		&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "DependsOn"},
+			{Kind: yaml.SequenceNode, Content: move},
+		}})
+}
+
+// sub replaces variables in a Sub string that refer to the template.
+// Variables that the Sub defines are left alone.
+func (iso *Isolator) sub(n *yaml.Node) error {
+	s := n
+	var vars *yaml.Node
+	if n.Kind == yaml.SequenceNode {
+		if len(n.Content) != 2 {
+			return nil
+		}
+		s = n.Content[0]
+		vars = n.Content[1]
+	}
+	if s.Kind != yaml.ScalarNode {
+		return nil
+	}
+
+	var err error
+	retval, changed, parseErr := rewriteSub(s.Value, func(w parse.SubWord) string {
+		left, right, found := strings.Cut(w.W, ".")
+		if !iso.outside(left) || hasKey(vars, left) {
+			return w.W
+		}
+		if !found {
+			if refErr := iso.refParam(left); refErr != nil {
+				err = refErr
+			}
+			return w.W
+		}
+		paramName, getAttErr := iso.getAttParam(left, right)
+		if getAttErr != nil {
+			err = getAttErr
+		}
+		return paramName
+	})
+	if parseErr != nil || err != nil {
+		return err
+	}
+
+	if changed {
+		s.Value = retval
+	}
+	return nil
+}
+
+// getAttParts returns the resource name and attribute of a GetAtt
+func getAttParts(n *yaml.Node) (string, string) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		name, attribute, _ := strings.Cut(n.Value, ".")
+		return name, attribute
+	case yaml.SequenceNode:
+		if len(n.Content) == 2 && n.Content[0].Kind == yaml.ScalarNode && n.Content[1].Kind == yaml.ScalarNode {
+			return n.Content[0].Value, n.Content[1].Value
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,240 @@
+package refactor
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/graph"
+)
+
+// Partition divides the resources in t into count partitions of about the
+// same size, keeping resources that refer to each other together where
+// possible, so that there are few references between partitions.
+//
+// Partitions are ordered, and resources only depend on resources in the same
+// partition or an earlier one, so that stacks made from the partitions can be
+// deployed in order. pins maps resource names to the partition they must be in.
+//
+// Each partition is a list of resource names, in the order of the template.
+func Partition(t cft.Template, count int, pins map[string]int) ([][]string, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("can't split a template into %d partitions", count)
+	}
+
+	g := graph.New(t)
+	if cycles := g.Cycles(); len(cycles) > 0 {
+		return nil, fmt.Errorf("circular dependency: %s", cycles[0])
+	}
+
+	resources, err := t.GetSection(cft.Resources)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	for i := 0; i < len(resources.Content); i += 2 {
+		names = append(names, resources.Content[i].Value)
+	}
+
+	for name, p := range pins {
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("'%s' is not a resource", name)
+		}
+		if p < 0 || p >= count {
+			return nil, fmt.Errorf("'%s' is pinned to partition %d, but there are %d partitions", name, p, count)
+		}
+	}
+
+	deps := make(map[string][]string)
+	dependents := make(map[string][]string)
+	for _, name := range names {
+		for _, dep := range g.Get(graph.Node{Type: string(cft.Resources), Name: name}) {
+			if dep.Type == string(cft.Resources) && dep.Name != name {
+				deps[name] = append(deps[name], dep.Name)
+				dependents[dep.Name] = append(dependents[dep.Name], name)
+			}
+		}
+	}
+
+	groups := connectedGroups(names, deps, dependents)
+	order := make([]string, 0)
+	group := make(map[string]int)
+	for i, members := range groups {
+		for _, name := range members {
+			group[name] = i
+		}
+		order = append(order, members...)
+	}
+
+	// A resource can't be in a later partition than anything pinned that depends on it
+	last := make(map[string]int)
+	for i := len(order) - 1; i >= 0; i-- {
+		name := order[i]
+		last[name] = count - 1
+		if p, ok := pins[name]; ok {
+			last[name] = p
+		}
+		for _, d := range dependents[name] {
+			last[name] = min(last[name], last[d])
+		}
+	}
+
+	capacity := (len(names) + count - 1) / count
+	sizes := make([]int, count)
+	for _, p := range pins {
+		sizes[p]++
+	}
+
+	assigned := make(map[string]int)
+	preferred := -1
+	current := -1
+	for _, name := range order {
+		// A resource can't be in an earlier partition than its dependencies
+		first := 0
+		for _, d := range deps[name] {
+			first = max(first, assigned[d])
+		}
+
+		if p, ok := pins[name]; ok {
+			if p < first {
+				return nil, fmt.Errorf("'%s' is pinned to partition %d, but it depends on a resource in partition %d",
+					name, p, first)
+			}
+			assigned[name] = p
+			continue
+		}
+
+		if first > last[name] {
+			return nil, fmt.Errorf("'%s' depends on a resource in partition %d, but a resource pinned to partition %d depends on it",
+				name, first, last[name])
+		}
+
+		// Start each group of connected resources in a partition with room for all of them
+		if group[name] != current {
+			current = group[name]
+			size := len(groups[current])
+			preferred = -1
+			for p := first; p <= last[name]; p++ {
+				if capacity-sizes[p] >= size {
+					preferred = p
+					break
+				}
+			}
+		}
+
+		best := -1
+		bestScore := -1
+		for p := first; p <= last[name]; p++ {
+			if sizes[p] >= capacity {
+				continue
+			}
+			score := 0
+			for _, d := range deps[name] {
+				if assigned[d] == p {
+					score += 2
+				}
+			}
+			if p == preferred {
+				score++
+			}
+			if score > bestScore {
+				best = p
+				bestScore = score
+			}
+		}
+		if best < 0 {
+			// Every partition it can go in is full, so use the smallest
+			best = first
+			for p := first; p <= last[name]; p++ {
+				if sizes[p] < sizes[best] {
+					best = p
+				}
+			}
+		}
+
+		assigned[name] = best
+		sizes[best]++
+	}
+
+	retval := make([][]string, count)
+	for i := range retval {
+		retval[i] = make([]string, 0)
+	}
+	for _, name := range names {
+		retval[assigned[name]] = append(retval[assigned[name]], name)
+	}
+
+	return retval, nil
+}
+
+// connected returns the resources that are connected to name
+// through references in either direction
+func connected(name string, deps, dependents map[string][]string) map[string]bool {
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, next := range append(slices.Clone(deps[n]), dependents[n]...) {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return seen
+}
+
+// connectedGroups divides resources into groups of connected resources,
+// with the largest groups first. In each group, resources come after their
+// dependencies, and are otherwise in the order of the template.
+func connectedGroups(names []string, deps, dependents map[string][]string) [][]string {
+	groups := make([][]string, 0)
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		c := connected(name, deps, dependents)
+		group := make([]string, 0)
+		for _, n := range names {
+			if c[n] {
+				seen[n] = true
+				group = append(group, n)
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i]) > len(groups[j])
+	})
+
+	for i, group := range groups {
+		groups[i] = topological(group, deps)
+	}
+	return groups
+}
+
+// topological sorts names so that each comes after its dependencies,
+// keeping the original order where possible
+func topological(names []string, deps map[string][]string) []string {
+	done := make(map[string]bool)
+	retval := make([]string, 0)
+	var visit func(string)
+	visit = func(name string) {
+		if done[name] {
+			return
+		}
+		done[name] = true
+		for _, d := range deps[name] {
+			visit(d)
+		}
+		retval = append(retval, name)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return retval
+}
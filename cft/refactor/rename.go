@@ -0,0 +1,329 @@
+// Package refactor makes structural changes to templates, like renaming
+// a logical id, while keeping every reference to it intact.
+//
+// Changes are made to the template's yaml nodes in place, so comments
+// are kept.
+package refactor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/internal/s11n"
+	"gopkg.in/yaml.v3"
+)
+
+// renameable are the sections that contain names that can be renamed
+var renameable = []cft.Section{cft.Parameters, cft.Resources, cft.Conditions, cft.Mappings}
+
+// logicalIds are the sections whose names must be unique in the template
+var logicalIds = []cft.Section{cft.Parameters, cft.Resources, cft.Conditions, cft.Mappings, cft.Outputs}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// Rename renames a parameter, resource, condition or mapping, and
+// rewrites every Ref, GetAtt, Sub, DependsOn, Condition, Fn::If and
+// Fn::FindInMap that refers to it. It returns the section that
+// oldName was found in.
+func Rename(t cft.Template, oldName, newName string) (cft.Section, error) {
+	section, _, err := rename(t, oldName, newName)
+	return section, err
+}
+
+// edits are the scalars that a rename changed. The value is true for
+// Sub strings, which can refer to the name more than once.
+type edits map[*yaml.Node]bool
+
+// rename renames oldName in t and returns the section it was found in,
+// and the scalars that it changed
+func rename(t cft.Template, oldName, newName string) (cft.Section, edits, error) {
+	if err := checkNewName(t, newName); err != nil {
+		return "", nil, err
+	}
+
+	var section cft.Section
+	var key *yaml.Node
+	for _, s := range renameable {
+		n, err := t.GetSection(s)
+		if err != nil {
+			continue
+		}
+		for i := 0; i < len(n.Content); i += 2 {
+			if n.Content[i].Value != oldName {
+				continue
+			}
+			if key != nil {
+				return "", nil, fmt.Errorf("'%s' is in both %s and %s", oldName, section, s)
+			}
+			section = s
+			key = n.Content[i]
+		}
+	}
+	if key == nil {
+		return "", nil, fmt.Errorf("'%s' is not a parameter, resource, condition or mapping", oldName)
+	}
+
+	key.Value = newName
+
+	r := renamer{oldName: oldName, newName: newName, section: section, edits: edits{key: false}}
+
+	for _, s := range []cft.Section{cft.Metadata, cft.Rules, cft.Conditions, cft.Resources, cft.Outputs} {
+		n, err := t.GetSection(s)
+		if err != nil {
+			continue
+		}
+
+		switch s {
+		case cft.Metadata:
+			if section == cft.Parameters {
+				r.interfaceMetadata(n)
+			}
+		case cft.Conditions:
+			for i := 1; i < len(n.Content); i += 2 {
+				r.conditionFunctions(n.Content[i])
+				r.walk(n.Content[i])
+			}
+		case cft.Resources, cft.Outputs:
+			for i := 1; i < len(n.Content); i += 2 {
+				r.attributes(n.Content[i])
+				r.walk(n.Content[i])
+			}
+		default:
+			r.walk(n)
+		}
+	}
+
+	return section, r.edits, nil
+}
+
+// checkNewName returns an error if name is not a valid logical id,
+// or is already used in the template
+func checkNewName(t cft.Template, name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("'%s' is not a valid logical id, which must be alphanumeric", name)
+	}
+
+	for _, s := range logicalIds {
+		n, err := t.GetSection(s)
+		if err != nil {
+			continue
+		}
+		for i := 0; i < len(n.Content); i += 2 {
+			if n.Content[i].Value == name {
+				return fmt.Errorf("'%s' already exists in %s", name, s)
+			}
+		}
+	}
+
+	return nil
+}
+
+type renamer struct {
+	oldName string
+	newName string
+
+	// section is where the renamed element is
+	section cft.Section
+
+	edits edits
+}
+
+// refs is true if the element can be used with Ref, GetAtt and Sub
+func (r renamer) refs() bool {
+	return r.section == cft.Parameters || r.section == cft.Resources
+}
+
+// scalar renames n if it's a scalar with the old name
+func (r renamer) scalar(n *yaml.Node) {
+	if n != nil && n.Kind == yaml.ScalarNode && n.Value == r.oldName {
+		n.Value = r.newName
+		r.edits[n] = false
+	}
+}
+
+// first renames the first element of a sequence
+func (r renamer) first(n *yaml.Node) {
+	if n.Kind == yaml.SequenceNode && len(n.Content) > 0 {
+		r.scalar(n.Content[0])
+	}
+}
+
+// attributes renames DependsOn and Condition in a resource or output
+func (r renamer) attributes(n *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+
+	if r.section == cft.Resources {
+		_, dependsOn, _ := s11n.GetMapValue(n, "DependsOn")
+		if dependsOn != nil {
+			r.scalar(dependsOn)
+			if dependsOn.Kind == yaml.SequenceNode {
+				for _, d := range dependsOn.Content {
+					r.scalar(d)
+				}
+			}
+		}
+	}
+
+	if r.section == cft.Conditions {
+		_, condition, _ := s11n.GetMapValue(n, "Condition")
+		r.scalar(condition)
+	}
+}
+
+// conditionFunctions renames {Condition: Name} inside a condition,
+// like Fn::And: [{Condition: A}, {Condition: B}]
+func (r renamer) conditionFunctions(n *yaml.Node) {
+	if r.section != cft.Conditions {
+		return
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(n.Content); i += 2 {
+			if n.Content[i].Value == "Condition" {
+				r.scalar(n.Content[i+1])
+			} else {
+				r.conditionFunctions(n.Content[i+1])
+			}
+		}
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			r.conditionFunctions(c)
+		}
+	}
+}
+
+// interfaceMetadata renames a parameter in AWS::CloudFormation::Interface
+// ParameterGroups and ParameterLabels
+func (r renamer) interfaceMetadata(n *yaml.Node) {
+	_, iface, _ := s11n.GetMapValue(n, "AWS::CloudFormation::Interface")
+	if iface == nil {
+		return
+	}
+
+	_, groups, _ := s11n.GetMapValue(iface, "ParameterGroups")
+	if groups != nil && groups.Kind == yaml.SequenceNode {
+		for _, group := range groups.Content {
+			_, params, _ := s11n.GetMapValue(group, "Parameters")
+			if params != nil && params.Kind == yaml.SequenceNode {
+				for _, p := range params.Content {
+					r.scalar(p)
+				}
+			}
+		}
+	}
+
+	_, labels, _ := s11n.GetMapValue(iface, "ParameterLabels")
+	if labels != nil && labels.Kind == yaml.MappingNode {
+		for i := 0; i < len(labels.Content); i += 2 {
+			r.scalar(labels.Content[i])
+		}
+	}
+}
+
+// walk renames references in intrinsic functions in n and its children
+func (r renamer) walk(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			r.walk(c)
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(n.Content); i += 2 {
+			key, value := n.Content[i].Value, n.Content[i+1]
+			switch {
+			case key == "Ref" && r.refs():
+				r.scalar(value)
+			case key == "Fn::GetAtt" && r.section == cft.Resources:
+				if value.Kind == yaml.ScalarNode {
+					// Name.Attribute
+					if left, right, found := strings.Cut(value.Value, "."); found && left == r.oldName {
+						value.Value = r.newName + "." + right
+						r.edits[value] = false
+					}
+				}
+				r.first(value)
+			case key == "Fn::Sub" && r.refs():
+				r.sub(value)
+			case key == "Fn::If" && r.section == cft.Conditions:
+				r.first(value)
+			case key == "Fn::FindInMap" && r.section == cft.Mappings:
+				r.first(value)
+			}
+			r.walk(value)
+		}
+	}
+}
+
+// sub renames variables in a Sub string, unless the Sub defines a
+// variable with the same name
+func (r renamer) sub(n *yaml.Node) {
+	s := n
+	if n.Kind == yaml.SequenceNode {
+		if len(n.Content) != 2 {
+			return
+		}
+		s = n.Content[0]
+		_, v, _ := s11n.GetMapValue(n.Content[1], r.oldName)
+		if v != nil {
+			return
+		}
+	}
+	if s.Kind != yaml.ScalarNode {
+		return
+	}
+
+	retval, changed, err := rewriteSub(s.Value, func(w parse.SubWord) string {
+		left, right, found := strings.Cut(w.W, ".")
+		if left != r.oldName {
+			return w.W
+		}
+		if found {
+			return r.newName + "." + right
+		}
+		return r.newName
+	})
+	if err != nil {
+		return
+	}
+
+	// Only rewrite strings that change, in case parsing loses anything
+	if changed {
+		s.Value = retval
+		r.edits[s] = true
+	}
+}
+
+// rewriteSub rebuilds a Sub string, replacing each ${Name} and ${Name.Attribute}
+// variable with the result of f. It returns true if anything changed.
+func rewriteSub(sub string, f func(w parse.SubWord) string) (string, bool, error) {
+	words, err := parse.ParseSub(sub, true)
+	if err != nil {
+		return sub, false, err
+	}
+
+	retval := ""
+	changed := false
+	for _, w := range words {
+		switch w.T {
+		case parse.STR:
+			retval += w.W
+		case parse.AWS:
+			retval += fmt.Sprintf("${AWS::%s}", w.W)
+		case parse.RAIN:
+			retval += fmt.Sprintf("${Rain::%s}", w.W)
+		case parse.REF, parse.GETATT:
+			v := f(w)
+			if v != w.W {
+				changed = true
+			}
+			retval += fmt.Sprintf("${%s}", v)
+		}
+	}
+
+	return retval, changed, nil
+}
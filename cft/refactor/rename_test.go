@@ -0,0 +1,239 @@
+package refactor_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/cft/refactor"
+)
+
+const source = `
+Metadata:
+  AWS::CloudFormation::Interface:
+    ParameterGroups:
+      - Parameters: [Name]
+    ParameterLabels:
+      Name:
+        default: The name
+
+Parameters:
+  Name:
+    Type: String # the name
+
+Mappings:
+  Sizes:
+    prod:
+      Size: 10
+
+Conditions:
+  IsProd: !Equals [!Ref Name, prod]
+  Both: !And
+    - !Condition IsProd
+    - !Equals [a, a]
+
+Resources:
+  # The bucket
+  Bucket:
+    Type: AWS::S3::Bucket
+    Condition: IsProd
+    Properties:
+      BucketName: !Sub ${Name}-bucket-${!Name}
+
+  Policy:
+    Type: AWS::S3::BucketPolicy
+    DependsOn: Bucket
+    Properties:
+      Bucket: !Ref Bucket
+      Size: !FindInMap [Sizes, prod, Size]
+      PolicyDocument:
+        Statement:
+          - Resource: !GetAtt Bucket.Arn
+          - Resource: !Sub "${Bucket.Arn}/*"
+          - Resource: !Sub
+              - "${Bucket}/${Name}"
+              - Name: !GetAtt [Bucket, Arn]
+
+Outputs:
+  Arn:
+    Condition: IsProd
+    Value: !If [IsProd, !GetAtt Bucket.Arn, !Ref Name]
+`
+
+func rename(t *testing.T, oldName, newName string) (cft.Section, string) {
+	template, err := parse.String(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	section, err := refactor.Rename(template, oldName, newName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return section, format.String(template, format.Options{Unsorted: true})
+}
+
+func TestRenameResource(t *testing.T) {
+	section, out := rename(t, "Bucket", "Store")
+	if section != cft.Resources {
+		t.Errorf("expected Resources, got %s", section)
+	}
+
+	for _, expected := range []string{
+		"# The bucket\n  Store:",
+		"DependsOn: Store",
+		"Bucket: !Ref Store",
+		"!GetAtt Store.Arn",
+		"!Sub ${Store.Arn}/*",
+		"- ${Store}/${Name}",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in:\n%s", expected, out)
+		}
+	}
+
+	if strings.Contains(out, "Bucket.Arn") {
+		t.Errorf("expected no references to Bucket:\n%s", out)
+	}
+}
+
+func TestRenameParameter(t *testing.T) {
+	_, out := rename(t, "Name", "BucketName")
+
+	for _, expected := range []string{
+		"- BucketName\n",
+		"BucketName:\n        default: The name",
+		"- !Ref BucketName",
+		"!Sub ${BucketName}-bucket-${!Name}",
+		// The Sub variable hides the parameter
+		"- ${Bucket}/${Name}",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in:\n%s", expected, out)
+		}
+	}
+}
+
+func TestRenameCondition(t *testing.T) {
+	_, out := rename(t, "IsProd", "Prod")
+
+	if strings.Contains(out, "IsProd") {
+		t.Errorf("expected no references to IsProd:\n%s", out)
+	}
+	if !strings.Contains(out, "!Condition Prod") {
+		t.Errorf("expected the condition function to be renamed:\n%s", out)
+	}
+}
+
+func TestRenameMapping(t *testing.T) {
+	_, out := rename(t, "Sizes", "BucketSizes")
+
+	if !strings.Contains(out, "- BucketSizes\n") {
+		t.Errorf("expected FindInMap to be renamed:\n%s", out)
+	}
+}
+
+func TestRenameErrors(t *testing.T) {
+	template, err := parse.String(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, names := range [][2]string{
+		{"Missing", "Other"},
+		{"Bucket", "Policy"},
+		{"Bucket", "Arn"},
+		{"Bucket", "Not-Valid"},
+	} {
+		if _, err := refactor.Rename(template, names[0], names[1]); err == nil {
+			t.Errorf("expected an error renaming %s to %s", names[0], names[1])
+		}
+	}
+}
+
+func TestRenameSource(t *testing.T) {
+	input := `Resources:
+    # The bucket
+    Bucket:
+        Type: AWS::S3::Bucket
+        Properties:
+            BucketName: 'my-bucket'   # keep the quotes
+            Tags: [{Key: a, Value: b}]
+
+
+    Policy:
+        Type: AWS::S3::BucketPolicy
+        DependsOn: [ Bucket ]
+        Properties:
+            Bucket: !Ref   "Bucket"
+            PolicyDocument:
+                Statement:
+                    - Resource: !GetAtt Bucket.Arn
+                    - Resource: !GetAtt [Bucket, Arn]
+                    - Resource: !Sub |
+                        ${Bucket.Arn}/*
+                        ${Bucket}/${!Bucket}/${BucketB}
+Outputs:
+    Name: {Value: {"Fn::Sub": "${Bucket}"}}
+`
+
+	expected := `Resources:
+    # The bucket
+    Store:
+        Type: AWS::S3::Bucket
+        Properties:
+            BucketName: 'my-bucket'   # keep the quotes
+            Tags: [{Key: a, Value: b}]
+
+
+    Policy:
+        Type: AWS::S3::BucketPolicy
+        DependsOn: [ Store ]
+        Properties:
+            Bucket: !Ref   "Store"
+            PolicyDocument:
+                Statement:
+                    - Resource: !GetAtt Store.Arn
+                    - Resource: !GetAtt [Store, Arn]
+                    - Resource: !Sub |
+                        ${Store.Arn}/*
+                        ${Store}/${!Bucket}/${BucketB}
+Outputs:
+    Name: {Value: {"Fn::Sub": "${Store}"}}
+`
+
+	out, section, err := refactor.RenameSource(input, "Bucket", "Store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if section != cft.Resources {
+		t.Errorf("expected Resources, got %s", section)
+	}
+	if out != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+
+	// JSON keeps its formatting too
+	input = `{
+  "Resources": {
+    "Bucket": {"Type": "AWS::S3::Bucket"},
+    "Policy": {
+      "Type": "AWS::S3::BucketPolicy",
+      "Properties": {"Bucket": {"Ref": "Bucket"}}
+    }
+  }
+}
+`
+	out, _, err = refactor.RenameSource(input, "Bucket", "Store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = strings.Replace(input, `"Bucket": {"Type"`, `"Store": {"Type"`, 1)
+	expected = strings.Replace(expected, `"Ref": "Bucket"`, `"Ref": "Store"`, 1)
+	if out != expected {
+		t.Errorf("unexpected JSON:\n%s", out)
+	}
+}
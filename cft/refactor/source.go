@@ -0,0 +1,155 @@
+package refactor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"gopkg.in/yaml.v3"
+)
+
+// RenameSource renames oldName to newName in the source of a YAML or JSON
+// template, like Rename, and returns the new source. Only the renamed
+// names are changed, so the rest of the source comes back byte for byte.
+func RenameSource(source string, oldName, newName string) (string, cft.Section, error) {
+	t, err := parse.String(source)
+	if err != nil {
+		return "", "", err
+	}
+
+	section, edits, err := rename(t, oldName, newName)
+	if err != nil {
+		return "", "", err
+	}
+
+	retval, err := splice(source, t.Node, edits, oldName, newName)
+	if err != nil {
+		return "", "", err
+	}
+
+	return retval, section, nil
+}
+
+// splice rewrites oldName in the source of each edited scalar. A scalar's
+// source starts at its line and column, and ends where the next node starts.
+func splice(source string, root *yaml.Node, edits edits, oldName, newName string) (string, error) {
+	lines := []int{0}
+	for i, c := range source {
+		if c == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+
+	// offset converts a line and column, which counts characters, to a byte offset
+	offset := func(line, column int) (int, error) {
+		if line < 1 || line > len(lines) {
+			return 0, fmt.Errorf("line %d is not in the template", line)
+		}
+		i := lines[line-1]
+		for c := 1; c < column && i < len(source); c++ {
+			_, size := utf8.DecodeRuneInString(source[i:])
+			i += size
+		}
+		return i, nil
+	}
+
+	var starts []int
+	var visit func(n *yaml.Node) error
+	visit = func(n *yaml.Node) error {
+		if n.Line > 0 {
+			start, err := offset(n.Line, n.Column)
+			if err != nil {
+				return err
+			}
+			starts = append(starts, start)
+		}
+		for _, c := range n.Content {
+			if err := visit(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(root); err != nil {
+		return "", err
+	}
+	sort.Ints(starts)
+
+	type replacement struct {
+		start, end int
+		text       string
+	}
+	replacements := make([]replacement, 0, len(edits))
+
+	for n, isSub := range edits {
+		if n.Line == 0 {
+			return "", fmt.Errorf("unable to find '%s' in the template", n.Value)
+		}
+		start, _ := offset(n.Line, n.Column)
+		end := len(source)
+		if i := sort.SearchInts(starts, start+1); i < len(starts) {
+			end = starts[i]
+		}
+		s := source[start:end]
+
+		var text string
+		if isSub {
+			text = strings.ReplaceAll(s, "${"+oldName+"}", "${"+newName+"}")
+			text = strings.ReplaceAll(text, "${"+oldName+".", "${"+newName+".")
+		} else {
+			i := nameIndex(s, oldName)
+			if i >= 0 {
+				text = s[:i] + newName + s[i+len(oldName):]
+			}
+		}
+		if text == "" || text == s {
+			return "", fmt.Errorf("unable to find '%s' on line %d", oldName, n.Line)
+		}
+
+		replacements = append(replacements, replacement{start, end, text})
+	}
+
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].start > replacements[j].start
+	})
+	for _, r := range replacements {
+		source = source[:r.start] + r.text + source[r.end:]
+	}
+
+	return source, nil
+}
+
+// nameIndex returns the index of the first whole word name in s,
+// after the tag if s starts with one, or -1
+func nameIndex(s, name string) int {
+	skip := 0
+	if strings.HasPrefix(s, "!") {
+		skip = strings.IndexAny(s, " \t\n")
+		if skip < 0 {
+			return -1
+		}
+	}
+
+	for i := skip; i+len(name) <= len(s); i++ {
+		if s[i:i+len(name)] != name {
+			continue
+		}
+		if i > 0 && isNameByte(s[i-1]) {
+			continue
+		}
+		if j := i + len(name); j < len(s) && isNameByte(s[j]) {
+			continue
+		}
+		return i
+	}
+
+	return -1
+}
+
+// isNameByte is true for the characters that logical ids are made of
+func isNameByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
@@ -0,0 +1,761 @@
+package refactor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/graph"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/internal/node"
+	"github.com/aws-cloudformation/rain/internal/s11n"
+	"gopkg.in/yaml.v3"
+)
+
+// Stack is one of the stacks that a template is split into
+type Stack struct {
+	// Name is the logical id of the nested stack, and is used in file names
+	Name string
+
+	// Resources are the names of the resources in the stack
+	Resources []string
+
+	// Template is set by Split
+	Template cft.Template
+}
+
+// SplitOptions configures Split
+type SplitOptions struct {
+	// Exports makes independent stacks that share values with Outputs that
+	// have an Export and Fn::ImportValue, instead of nested stacks
+	Exports bool
+
+	// ExportPrefix starts each export name, like Prefix-BucketArn
+	ExportPrefix string
+
+	// TemplateURL returns the TemplateURL of a nested stack
+	TemplateURL func(stack Stack) string
+}
+
+// Split divides t into a template for each stack, which is the opposite of
+// merging templates. Stacks must be in the order they can be deployed in,
+// with resources only depending on resources in the same stack or an
+// earlier one, which is what Partition returns.
+//
+// Each stack gets the Conditions its resources use, the Mappings, and the
+// Parameters it needs. When a resource refers to a resource in an earlier
+// stack, the earlier stack gets an Output, like BucketArn for Bucket.Arn.
+//
+// By default, Split returns a parent template that has the Parameters,
+// Conditions, Rules and Outputs of t, and an AWS::CloudFormation::Stack for
+// each stack that passes in parameters and outputs of the earlier stacks.
+//
+// With options.Exports, the Outputs are exported, and imported by later
+// stacks with Fn::ImportValue. The outputs of t go in the last stack that
+// they refer to, and Split returns an empty template.
+func Split(t cft.Template, stacks []Stack, options SplitOptions) (cft.Template, error) {
+	resources, err := t.GetSection(cft.Resources)
+	if err != nil {
+		return cft.Template{}, err
+	}
+	params, _ := t.GetSection(cft.Parameters)
+
+	s := splitter{
+		t:        t,
+		g:        graph.New(t),
+		stacks:   stacks,
+		options:  options,
+		owner:    make(map[string]int),
+		params:   params,
+		outputs:  make([]*yaml.Node, len(stacks)),
+		parts:    make([]part, len(stacks)),
+		produced: make([]*yaml.Node, len(stacks)),
+	}
+
+	for i, stack := range stacks {
+		if !validName.MatchString(stack.Name) {
+			return cft.Template{}, fmt.Errorf("'%s' is not a valid stack name; use only letters and numbers", stack.Name)
+		}
+		if hasKey(params, stack.Name) {
+			return cft.Template{}, fmt.Errorf("'%s' is already the name of a parameter", stack.Name)
+		}
+		if len(stack.Resources) == 0 {
+			return cft.Template{}, fmt.Errorf("stack '%s' has no resources", stack.Name)
+		}
+		for _, name := range stack.Resources {
+			if !hasKey(resources, name) {
+				return cft.Template{}, fmt.Errorf("'%s' is not a resource", name)
+			}
+			if _, ok := s.owner[name]; ok {
+				return cft.Template{}, fmt.Errorf("'%s' is in more than one stack", name)
+			}
+			s.owner[name] = i
+		}
+		s.outputs[i] = &yaml.Node{Kind: yaml.MappingNode}
+		s.produced[i] = &yaml.Node{Kind: yaml.MappingNode}
+	}
+	for i := 0; i < len(resources.Content); i += 2 {
+		if _, ok := s.owner[resources.Content[i].Value]; !ok {
+			return cft.Template{}, fmt.Errorf("'%s' is not in a stack", resources.Content[i].Value)
+		}
+	}
+
+	if options.Exports {
+		s.assignOutputs()
+	}
+
+	for i := range stacks {
+		if err := s.isolate(i); err != nil {
+			return cft.Template{}, err
+		}
+	}
+
+	if options.Exports {
+		return cft.Template{}, s.exports()
+	}
+	return s.nested()
+}
+
+type splitter struct {
+	t       cft.Template
+	g       graph.Graph
+	stacks  []Stack
+	options SplitOptions
+
+	// owner maps resource names to the stack they are in
+	owner map[string]int
+
+	// params is the Parameters section of t, if it has one
+	params *yaml.Node
+
+	// outputs are the Outputs of t that go in each stack
+	outputs []*yaml.Node
+
+	parts []part
+
+	// produced are the Outputs that later stacks use
+	produced []*yaml.Node
+}
+
+// part is a stack's template before it is put together
+type part struct {
+	resources  *yaml.Node
+	conditions *yaml.Node
+	outputs    *yaml.Node
+	iso        *Isolator
+}
+
+// assignOutputs puts each output of t in the last stack it refers to
+func (s *splitter) assignOutputs() {
+	outputs, err := s.t.GetSection(cft.Outputs)
+	if err != nil {
+		return
+	}
+	for i := 0; i < len(outputs.Content); i += 2 {
+		name := outputs.Content[i].Value
+		stack := 0
+		for _, dep := range s.g.Get(graph.Node{Type: string(cft.Outputs), Name: name}) {
+			if dep.Type == string(cft.Resources) {
+				stack = max(stack, s.owner[dep.Name])
+			}
+		}
+		s.outputs[stack].Content = append(s.outputs[stack].Content,
+			node.Clone(outputs.Content[i]), node.Clone(outputs.Content[i+1]))
+	}
+}
+
+// isolate copies the resources of a stack, and the conditions and
+// outputs they need, and replaces references to the rest of t with parameters
+func (s *splitter) isolate(i int) error {
+	stack := s.stacks[i]
+	resources, _ := s.t.GetSection(cft.Resources)
+
+	p := part{
+		resources:  &yaml.Node{Kind: yaml.MappingNode},
+		conditions: &yaml.Node{Kind: yaml.MappingNode},
+		outputs:    s.outputs[i],
+	}
+
+	used := make([]graph.Node, 0)
+	for j := 0; j < len(resources.Content); j += 2 {
+		name := resources.Content[j].Value
+		if slices.Contains(stack.Resources, name) {
+			p.resources.Content = append(p.resources.Content,
+				node.Clone(resources.Content[j]), node.Clone(resources.Content[j+1]))
+			used = append(used, graph.Node{Type: string(cft.Resources), Name: name})
+		}
+	}
+	for j := 0; j < len(p.outputs.Content); j += 2 {
+		used = append(used, graph.Node{Type: string(cft.Outputs), Name: p.outputs.Content[j].Value})
+	}
+
+	conditionNames := s.conditions(used)
+	if conditions, err := s.t.GetSection(cft.Conditions); err == nil {
+		for j := 0; j < len(conditions.Content); j += 2 {
+			if conditionNames[conditions.Content[j].Value] {
+				p.conditions.Content = append(p.conditions.Content,
+					node.Clone(conditions.Content[j]), node.Clone(conditions.Content[j+1]))
+			}
+		}
+	}
+
+	p.iso = NewIsolator(stack.Resources, s.params)
+	if err := p.iso.Resources(p.resources); err != nil {
+		return err
+	}
+	if err := p.iso.Walk(p.conditions); err != nil {
+		return err
+	}
+	if err := p.iso.Walk(p.outputs); err != nil {
+		return err
+	}
+
+	s.parts[i] = p
+	return nil
+}
+
+// conditions returns the names of the conditions that the nodes use,
+// including conditions used by those conditions
+func (s *splitter) conditions(nodes []graph.Node) map[string]bool {
+	retval := make(map[string]bool)
+	queue := slices.Clone(nodes)
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, dep := range s.g.Get(n) {
+			if dep.Type == string(cft.Conditions) && !retval[dep.Name] {
+				retval[dep.Name] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return retval
+}
+
+// source returns the stack that has the resource that a parameter's value
+// refers to, or -1 if the value is a parameter of t
+func (s *splitter) source(name string, value *yaml.Node) (int, error) {
+	if len(value.Content) == 2 {
+		switch value.Content[0].Value {
+		case "Ref":
+			if hasKey(s.params, value.Content[1].Value) {
+				return -1, nil
+			}
+			if stack, ok := s.owner[value.Content[1].Value]; ok {
+				return stack, nil
+			}
+		case "Fn::GetAtt":
+			resource, _ := getAttParts(value.Content[1])
+			if stack, ok := s.owner[resource]; ok {
+				return stack, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("'%s' is not a parameter or resource", name)
+}
+
+// produce adds an output for value to a stack, for a later stack to use
+func (s *splitter) produce(stack int, name string, value *yaml.Node) error {
+	if hasKey(s.produced[stack], name) {
+		return nil
+	}
+	if hasKey(s.parts[stack].outputs, name) {
+		return fmt.Errorf("stack '%s' already has an output called '%s'", s.stacks[stack].Name, name)
+	}
+
+	output := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "Value"},
+		node.Clone(value),
+	}}
+	if condition := s.condition(value); condition != "" {
+		output.Content = append(output.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "Condition"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: condition})
+	}
+	if s.options.Exports {
+		output.Content = append(output.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "Export"},
+			&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Value: "Name"},
+				{Kind: yaml.ScalarNode, Value: s.exportName(name)},
+			}})
+	}
+
+	s.produced[stack].Content = append(s.produced[stack].Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: name}, output)
+	return nil
+}
+
+// condition returns the Condition of the resource that a Ref or GetAtt refers to
+func (s *splitter) condition(value *yaml.Node) string {
+	name := value.Content[1].Value
+	if value.Content[0].Value == "Fn::GetAtt" {
+		name, _ = getAttParts(value.Content[1])
+	}
+	resource, err := s.t.GetResource(name)
+	if err != nil {
+		return ""
+	}
+	_, c, _ := s11n.GetMapValue(resource, "Condition")
+	if c == nil {
+		return ""
+	}
+	return c.Value
+}
+
+func (s *splitter) exportName(name string) string {
+	if s.options.ExportPrefix == "" {
+		return name
+	}
+	return s.options.ExportPrefix + "-" + name
+}
+
+// parameter returns the definition of a parameter in a stack. Parameters
+// of t keep their definition, and the rest are strings.
+func (s *splitter) parameter(name string, fromTemplate bool) *yaml.Node {
+	if !fromTemplate {
+		return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "Type"},
+			{Kind: yaml.ScalarNode, Value: "String"},
+		}}
+	}
+
+	_, def, _ := s11n.GetMapValue(s.params, name)
+	def = node.Clone(def)
+	if s.options.Exports {
+		return def
+	}
+
+	// The parent stack resolves SSM parameters, and passes in the value
+	_, typ, _ := s11n.GetMapValue(def, "Type")
+	if typ != nil && strings.HasPrefix(typ.Value, ssmPrefix) {
+		inner := strings.TrimSuffix(strings.TrimPrefix(typ.Value, ssmPrefix), ">")
+		if inner == "List<String>" {
+			inner = "CommaDelimitedList"
+		}
+		retval := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "Type"},
+			{Kind: yaml.ScalarNode, Value: inner},
+		}}
+		if _, d, _ := s11n.GetMapValue(def, "Description"); d != nil {
+			retval.Content = append(retval.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "Description"}, d)
+		}
+		return retval
+	}
+	return def
+}
+
+const ssmPrefix = "AWS::SSM::Parameter::Value<"
+
+// isList returns true if a parameter of t is a list, which has to be
+// joined to pass it to a nested stack
+func (s *splitter) isList(name string) bool {
+	_, def, _ := s11n.GetMapValue(s.params, name)
+	if def == nil {
+		return false
+	}
+	_, typ, _ := s11n.GetMapValue(def, "Type")
+	if typ == nil {
+		return false
+	}
+	t := strings.TrimPrefix(typ.Value, ssmPrefix)
+	return t == "CommaDelimitedList" || strings.HasPrefix(t, "List<")
+}
+
+// nested makes a template for each stack with Outputs for the later stacks,
+// and the parent template that passes them in
+func (s *splitter) nested() (cft.Template, error) {
+	stackResources := &yaml.Node{Kind: yaml.MappingNode}
+
+	for i, p := range s.parts {
+		parameters := &yaml.Node{Kind: yaml.MappingNode}
+		values := &yaml.Node{Kind: yaml.MappingNode}
+		dependsOn := make([]string, 0)
+
+		for j := 0; j < len(p.iso.Values.Content); j += 2 {
+			name, value := p.iso.Values.Content[j].Value, p.iso.Values.Content[j+1]
+			stack, err := s.source(name, value)
+			if err != nil {
+				return cft.Template{}, err
+			}
+
+			var v *yaml.Node
+			if stack < 0 {
+				v = value
+				if s.isList(name) {
+					v = join(value)
+				}
+			} else {
+				if err := s.produce(stack, name, value); err != nil {
+					return cft.Template{}, err
+				}
+				v = s.stackOutput(stack, name, value)
+			}
+			parameters.Content = append(parameters.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: name}, s.parameter(name, stack < 0))
+			values.Content = append(values.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: name}, v)
+		}
+
+		// The dependencies on earlier stacks become dependencies between stacks
+		for j := 1; j < len(p.iso.DependsOn.Content); j += 2 {
+			_, deps, _ := s11n.GetMapValue(p.iso.DependsOn.Content[j], "DependsOn")
+			for _, d := range deps.Content {
+				name := s.stacks[s.owner[d.Value]].Name
+				if !slices.Contains(dependsOn, name) {
+					dependsOn = append(dependsOn, name)
+				}
+			}
+		}
+
+		p.iso.Parameters = parameters
+		s.parts[i] = p
+
+		resource := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "Type"},
+			{Kind: yaml.ScalarNode, Value: "AWS::CloudFormation::Stack"},
+		}}
+		if len(dependsOn) > 0 {
+			d := &yaml.Node{Kind: yaml.SequenceNode}
+			for _, name := range dependsOn {
+				d.Content = append(d.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name})
+			}
+			resource.Content = append(resource.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "DependsOn"}, d)
+		}
+		properties := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "TemplateURL"},
+			{Kind: yaml.ScalarNode, Value: s.templateURL(s.stacks[i])},
+		}}
+		if len(values.Content) > 0 {
+			properties.Content = append(properties.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "Parameters"}, values)
+		}
+		resource.Content = append(resource.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "Properties"}, properties)
+
+		stackResources.Content = append(stackResources.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: s.stacks[i].Name}, resource)
+	}
+
+	// The parent's outputs refer to the outputs of the stacks
+	outputs, err := s.t.GetSection(cft.Outputs)
+	if err == nil {
+		outputs = node.Clone(outputs)
+		iso := NewIsolator(nil, s.params)
+		if err := iso.Walk(outputs); err != nil {
+			return cft.Template{}, err
+		}
+		values := make(map[string]*yaml.Node)
+		for j := 0; j < len(iso.Values.Content); j += 2 {
+			name, value := iso.Values.Content[j].Value, iso.Values.Content[j+1]
+			stack, err := s.source(name, value)
+			if err != nil {
+				return cft.Template{}, err
+			}
+			if stack < 0 {
+				continue
+			}
+			if err := s.produce(stack, name, value); err != nil {
+				return cft.Template{}, err
+			}
+			values[name] = s.stackOutput(stack, name, value)
+		}
+		substitute(outputs, values)
+	}
+
+	parent := cft.Template{Node: &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{
+		{Kind: yaml.MappingNode},
+	}}}
+	root := parent.Node.Content[0]
+	for i := 0; i < len(s.t.Node.Content[0].Content); i += 2 {
+		key, value := s.t.Node.Content[0].Content[i], s.t.Node.Content[0].Content[i+1]
+		switch cft.Section(key.Value) {
+		case cft.Resources:
+			value = stackResources
+		case cft.Outputs:
+			value = outputs
+		default:
+			value = node.Clone(value)
+		}
+		root.Content = append(root.Content, node.Clone(key), value)
+	}
+
+	for i := range s.stacks {
+		s.stacks[i].Template = s.template(i)
+	}
+
+	return parent, nil
+}
+
+// exports makes a template for each stack, with exported Outputs
+// for the later stacks, which import them with Fn::ImportValue
+func (s *splitter) exports() error {
+	rules, _ := s.t.GetSection(cft.Rules)
+
+	for i, p := range s.parts {
+		parameters := &yaml.Node{Kind: yaml.MappingNode}
+		imports := make(map[string]*yaml.Node)
+
+		for j := 0; j < len(p.iso.Values.Content); j += 2 {
+			name, value := p.iso.Values.Content[j].Value, p.iso.Values.Content[j+1]
+			stack, err := s.source(name, value)
+			if err != nil {
+				return err
+			}
+			if stack < 0 {
+				parameters.Content = append(parameters.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Value: name}, s.parameter(name, true))
+				continue
+			}
+			if err := s.produce(stack, name, value); err != nil {
+				return err
+			}
+			imports[name] = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Value: "Fn::ImportValue"},
+				{Kind: yaml.ScalarNode, Value: s.exportName(name)},
+			}}
+		}
+
+		substitute(p.resources, imports)
+		substitute(p.outputs, imports)
+
+		// Stacks are deployed in order, so DependsOn earlier stacks is not needed
+		p.iso.Parameters = parameters
+		s.parts[i] = p
+	}
+
+	for i := range s.stacks {
+		s.stacks[i].Template = s.template(i)
+
+		// Keep the rules that only check parameters this stack has
+		if rules == nil {
+			continue
+		}
+		params := s.parts[i].iso.Parameters
+		kept := &yaml.Node{Kind: yaml.MappingNode}
+		for j := 0; j < len(rules.Content); j += 2 {
+			keep := true
+			for _, name := range ruleParameters(rules.Content[j+1]) {
+				if !hasKey(params, name) {
+					keep = false
+				}
+			}
+			if keep {
+				kept.Content = append(kept.Content, node.Clone(rules.Content[j]), node.Clone(rules.Content[j+1]))
+			}
+		}
+		if len(kept.Content) > 0 {
+			addSection(s.stacks[i].Template, cft.Rules, kept, cft.Parameters)
+		}
+	}
+
+	return nil
+}
+
+// template puts together the template for a stack
+func (s *splitter) template(i int) cft.Template {
+	p := s.parts[i]
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	add := func(section cft.Section, value *yaml.Node) {
+		if value == nil || (value.Kind == yaml.MappingNode && len(value.Content) == 0) {
+			return
+		}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: string(section)}, value)
+	}
+
+	get := func(section cft.Section) *yaml.Node {
+		n, err := s.t.GetSection(section)
+		if err != nil {
+			return nil
+		}
+		return node.Clone(n)
+	}
+
+	add(cft.AWSTemplateFormatVersion, get(cft.AWSTemplateFormatVersion))
+	if desc := get(cft.Description); desc != nil && s.options.Exports {
+		add(cft.Description, desc)
+	}
+	add(cft.Transform, get(cft.Transform))
+	add(cft.Parameters, p.iso.Parameters)
+	add(cft.Mappings, get(cft.Mappings))
+	add(cft.Conditions, p.conditions)
+	add(cft.Resources, p.resources)
+
+	outputs := &yaml.Node{Kind: yaml.MappingNode}
+	outputs.Content = append(outputs.Content, p.outputs.Content...)
+	outputs.Content = append(outputs.Content, s.produced[i].Content...)
+	add(cft.Outputs, outputs)
+
+	return cft.Template{Node: &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}}
+}
+
+func (s *splitter) templateURL(stack Stack) string {
+	if s.options.TemplateURL == nil {
+		return "./" + stack.Name + ".yaml"
+	}
+	return s.options.TemplateURL(stack)
+}
+
+// stackOutput returns a GetAtt of an output of a nested stack. If the output
+// has a condition, the value is empty when the condition is false.
+func (s *splitter) stackOutput(stack int, name string, value *yaml.Node) *yaml.Node {
+	getAtt := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "Fn::GetAtt"},
+		{Kind: yaml.SequenceNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: s.stacks[stack].Name},
+			{Kind: yaml.ScalarNode, Value: "Outputs." + name},
+		}},
+	}}
+
+	condition := s.condition(value)
+	if condition == "" {
+		return getAtt
+	}
+	return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "Fn::If"},
+		{Kind: yaml.SequenceNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: condition},
+			getAtt,
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: "", Style: yaml.DoubleQuotedStyle},
+		}},
+	}}
+}
+
+// addSection inserts a section into t after another section
+func addSection(t cft.Template, section cft.Section, value *yaml.Node, after cft.Section) {
+	root := t.Node.Content[0]
+	position := 0
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value == string(after) {
+			position = i + 2
+		}
+	}
+	root.Content = slices.Insert(root.Content, position,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: string(section)}, value)
+}
+
+// join makes a Fn::Join of a list, to pass it as a parameter to a nested stack
+func join(value *yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "Fn::Join"},
+		{Kind: yaml.SequenceNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: ","},
+			value,
+		}},
+	}}
+}
+
+// ruleParameters returns the names of the parameters that a rule checks
+func ruleParameters(n *yaml.Node) []string {
+	retval := make([]string, 0)
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			retval = append(retval, ruleParameters(c)...)
+		}
+	case yaml.MappingNode:
+		if len(n.Content) == 2 {
+			switch n.Content[0].Value {
+			case "Ref":
+				if !strings.HasPrefix(n.Content[1].Value, "AWS::") {
+					retval = append(retval, n.Content[1].Value)
+				}
+				return retval
+			case "Fn::ValueOf", "Fn::ValueOfAll":
+				if n.Content[1].Kind == yaml.SequenceNode && len(n.Content[1].Content) > 0 {
+					retval = append(retval, n.Content[1].Content[0].Value)
+				}
+				return retval
+			}
+		}
+		for i := 1; i < len(n.Content); i += 2 {
+			retval = append(retval, ruleParameters(n.Content[i])...)
+		}
+	}
+	return retval
+}
+
+// substitute replaces each Ref to a name in values with its value,
+// including variables in Sub strings
+func substitute(n *yaml.Node, values map[string]*yaml.Node) {
+	if len(values) == 0 {
+		return
+	}
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			substitute(c, values)
+		}
+	case yaml.MappingNode:
+		if len(n.Content) == 2 {
+			switch n.Content[0].Value {
+			case "Ref":
+				if v, ok := values[n.Content[1].Value]; ok {
+					*n = *node.Clone(v)
+				}
+				return
+			case "Fn::Sub":
+				substituteSub(n.Content[1], values)
+			}
+		}
+		for i := 1; i < len(n.Content); i += 2 {
+			substitute(n.Content[i], values)
+		}
+	}
+}
+
+// substituteSub replaces variables in a Sub string. Values that can't be
+// written in the string, like Fn::ImportValue, become Sub variables.
+func substituteSub(n *yaml.Node, values map[string]*yaml.Node) {
+	s := n
+	var vars *yaml.Node
+	if n.Kind == yaml.SequenceNode {
+		if len(n.Content) != 2 {
+			return
+		}
+		s = n.Content[0]
+		vars = n.Content[1]
+	}
+	if s.Kind != yaml.ScalarNode {
+		return
+	}
+
+	added := &yaml.Node{Kind: yaml.MappingNode}
+	retval, changed, err := rewriteSub(s.Value, func(w parse.SubWord) string {
+		v, ok := values[w.W]
+		if w.T != parse.REF || !ok || hasKey(vars, w.W) {
+			return w.W
+		}
+		if len(v.Content) == 2 {
+			switch v.Content[0].Value {
+			case "Ref":
+				return v.Content[1].Value
+			case "Fn::GetAtt":
+				if resource, attribute := getAttParts(v.Content[1]); resource != "" {
+					return resource + "." + attribute
+				}
+			}
+		}
+		if !hasKey(added, w.W) {
+			added.Content = append(added.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: w.W}, node.Clone(v))
+		}
+		return w.W
+	})
+	if err != nil {
+		return
+	}
+	if changed {
+		s.Value = retval
+	}
+	if len(added.Content) == 0 {
+		return
+	}
+
+	if vars == nil {
+		*n = yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: s.Value, Style: s.Style},
+			added,
+		}}
+		return
+	}
+	vars.Content = append(vars.Content, added.Content...)
+}
@@ -0,0 +1,226 @@
+package refactor_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/cft/refactor"
+	"github.com/google/go-cmp/cmp"
+)
+
+const splitSource = `
+Parameters:
+  Env:
+    Type: String
+    AllowedValues: [dev, prod]
+  Subnets:
+    Type: List<AWS::EC2::Subnet::Id>
+
+Conditions:
+  IsProd: !Equals [!Ref Env, prod]
+
+Resources:
+  Vpc:
+    Type: AWS::EC2::VPC
+
+  SecurityGroup:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      VpcId: !Ref Vpc
+
+  Bucket:
+    Type: AWS::S3::Bucket
+    Condition: IsProd
+
+  Role:
+    Type: AWS::IAM::Role
+    Properties:
+      Policies:
+        - PolicyDocument:
+            Statement:
+              - Resource: !Sub ${Bucket.Arn}/*
+
+  Function:
+    Type: AWS::Lambda::Function
+    DependsOn: Bucket
+    Properties:
+      Role: !GetAtt Role.Arn
+      VpcConfig:
+        SecurityGroupIds: [!Ref SecurityGroup]
+        SubnetIds: !Ref Subnets
+
+Outputs:
+  FunctionArn:
+    Value: !GetAtt Function.Arn
+  BucketUrl:
+    Value: !Sub https://${Bucket.DomainName}/${Env}
+`
+
+func TestPartition(t *testing.T) {
+	template, err := parse.String(splitSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts, err := refactor.Partition(template, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		{"Vpc", "SecurityGroup", "Bucket"},
+		{"Role", "Function"},
+	}
+	if d := cmp.Diff(expected, parts); d != "" {
+		t.Error(d)
+	}
+
+	// Pins move resources, and the resources they depend on, to earlier partitions
+	parts, err = refactor.Partition(template, 2, map[string]int{"Function": 0, "Vpc": 1})
+	if err == nil {
+		t.Errorf("expected an error pinning Vpc after SecurityGroup, got %v", parts)
+	}
+
+	parts, err = refactor.Partition(template, 3, map[string]int{"Role": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, part := range parts {
+		for _, name := range part {
+			if name == "Role" && i != 0 {
+				t.Errorf("Role is in partition %d: %v", i, parts)
+			}
+			if name == "Bucket" && i != 0 {
+				t.Errorf("Bucket should be before Role: %v", parts)
+			}
+		}
+	}
+}
+
+func split(t *testing.T, options refactor.SplitOptions) (string, []string) {
+	template, err := parse.String(splitSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stacks := []refactor.Stack{
+		{Name: "Network", Resources: []string{"Vpc", "SecurityGroup", "Bucket"}},
+		{Name: "App", Resources: []string{"Role", "Function"}},
+	}
+	parent, err := refactor.Split(template, stacks, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make([]string, 0)
+	for _, stack := range stacks {
+		out = append(out, format.String(stack.Template, format.Options{Unsorted: true}))
+	}
+	if parent.Node == nil {
+		return "", out
+	}
+	return format.String(parent, format.Options{Unsorted: true}), out
+}
+
+func TestSplitNested(t *testing.T) {
+	parent, stacks := split(t, refactor.SplitOptions{})
+
+	for _, s := range []string{
+		"IsProd: !Equals",
+		`  Network:
+    Type: AWS::CloudFormation::Stack
+    Properties:
+      TemplateURL: ./Network.yaml
+      Parameters:
+        Env: !Ref Env
+`,
+		`  App:
+    Type: AWS::CloudFormation::Stack
+    DependsOn:
+      - Network
+    Properties:
+      TemplateURL: ./App.yaml
+      Parameters:
+        BucketArn: !If
+          - IsProd
+          - !GetAtt Network.Outputs.BucketArn
+          - ""
+        SecurityGroup: !GetAtt Network.Outputs.SecurityGroup
+        Subnets: !Join
+          - ','
+          - !Ref Subnets
+`,
+		"Value: !GetAtt App.Outputs.FunctionArn",
+		`Value: !Sub
+      - https://${BucketDomainName}/${Env}
+      - BucketDomainName: !If
+          - IsProd
+          - !GetAtt Network.Outputs.BucketDomainName
+          - ""
+`,
+	} {
+		if !strings.Contains(parent, s) {
+			t.Errorf("expected %q in parent:\n%s", s, parent)
+		}
+	}
+
+	for _, s := range []string{
+		"IsProd: !Equals\n    - !Ref Env",
+		"VpcId: !Ref Vpc",
+		"BucketArn:\n    Value: !GetAtt Bucket.Arn\n    Condition: IsProd",
+		"BucketDomainName:\n    Value: !GetAtt Bucket.DomainName",
+	} {
+		if !strings.Contains(stacks[0], s) {
+			t.Errorf("expected %q in first stack:\n%s", s, stacks[0])
+		}
+	}
+
+	for _, s := range []string{
+		"Subnets:\n    Type: List<AWS::EC2::Subnet::Id>",
+		"BucketArn:\n    Type: String",
+		"Resource: !Sub ${BucketArn}/*",
+		"Role: !GetAtt Role.Arn",
+		"SecurityGroupIds:\n          - !Ref SecurityGroup",
+		"FunctionArn:\n    Value: !GetAtt Function.Arn",
+	} {
+		if !strings.Contains(stacks[1], s) {
+			t.Errorf("expected %q in second stack:\n%s", s, stacks[1])
+		}
+	}
+	if strings.Contains(stacks[1], "DependsOn") || strings.Contains(stacks[1], "Conditions") {
+		t.Errorf("expected no DependsOn or Conditions in second stack:\n%s", stacks[1])
+	}
+}
+
+func TestSplitExports(t *testing.T) {
+	parent, stacks := split(t, refactor.SplitOptions{Exports: true, ExportPrefix: "app"})
+	if parent != "" {
+		t.Errorf("expected no parent template, got:\n%s", parent)
+	}
+
+	for _, s := range []string{
+		"Env:\n    Type: String\n    AllowedValues:",
+		"Export:\n      Name: app-BucketArn",
+		"BucketUrl:\n    Value: !Sub https://${Bucket.DomainName}/${Env}",
+	} {
+		if !strings.Contains(stacks[0], s) {
+			t.Errorf("expected %q in first stack:\n%s", s, stacks[0])
+		}
+	}
+
+	for _, s := range []string{
+		"Subnets:\n    Type: List<AWS::EC2::Subnet::Id>",
+		"- !ImportValue app-SecurityGroup",
+		"Resource: !Sub\n                  - ${BucketArn}/*\n                  - BucketArn: !ImportValue app-BucketArn",
+		"FunctionArn:\n    Value: !GetAtt Function.Arn",
+	} {
+		if !strings.Contains(stacks[1], s) {
+			t.Errorf("expected %q in second stack:\n%s", s, stacks[1])
+		}
+	}
+	if strings.Contains(stacks[1], "BucketArn:\n    Type") {
+		t.Errorf("expected imports instead of parameters:\n%s", stacks[1])
+	}
+}
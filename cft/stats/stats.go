@@ -0,0 +1,213 @@
+// Package stats measures a template against the quotas
+// that CloudFormation enforces on templates
+package stats
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/format"
+	"gopkg.in/yaml.v3"
+)
+
+// Quota is a limit that CloudFormation enforces on a template
+type Quota struct {
+	// Name describes what is limited
+	Name string
+
+	// Limit is the most that CloudFormation accepts
+	Limit int
+
+	// Unit is what Limit counts, like "bytes"
+	Unit string
+
+	// Blocking is true if a template over the limit can't be deployed.
+	// rain deploy uploads templates that are too big to send directly to S3.
+	Blocking bool
+}
+
+// The quotas from https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/cloudformation-limits.html
+var (
+	TemplateBodySize  = Quota{"Template size", 51200, "bytes", false}
+	TemplateS3Size    = Quota{"Template size in S3", 1024 * 1024, "bytes", true}
+	Resources         = Quota{"Resources", 500, "resources", true}
+	Parameters        = Quota{"Parameters", 200, "parameters", true}
+	Outputs           = Quota{"Outputs", 200, "outputs", true}
+	Mappings          = Quota{"Mappings", 200, "mappings", true}
+	MappingAttributes = Quota{"Mapping attributes", 200, "attributes", true}
+	SubLength         = Quota{"Fn::Sub length", 4096, "characters", true}
+	DescriptionLength = Quota{"Description length", 1024, "bytes", true}
+	NameLength        = Quota{"Logical id length", 255, "characters", true}
+)
+
+// Quotas is every quota that Template measures, in the order it returns them
+var Quotas = []Quota{
+	TemplateBodySize,
+	TemplateS3Size,
+	Resources,
+	Parameters,
+	Outputs,
+	Mappings,
+	MappingAttributes,
+	SubLength,
+	DescriptionLength,
+	NameLength,
+}
+
+// Metric is how close a template is to a quota
+type Metric struct {
+	Quota
+
+	// Value is the measurement. For quotas on each item in the
+	// template, like the length of each Fn::Sub, it is the largest one.
+	Value int
+
+	// Where is the largest item, like Resources/Bucket, when the quota is on each item
+	Where string
+}
+
+// Percent returns the value as a percentage of the limit
+func (m Metric) Percent() int {
+	return m.Value * 100 / m.Limit
+}
+
+// Exceeded returns true if the value is over the limit
+func (m Metric) Exceeded() bool {
+	return m.Value > m.Limit
+}
+
+// Near returns true if the value is at least threshold percent of the limit
+func (m Metric) Near(threshold int) bool {
+	return m.Percent() >= threshold
+}
+
+func (m Metric) String() string {
+	s := fmt.Sprintf("%s is %d of %d %s (%d%%)", m.Name, m.Value, m.Limit, m.Unit, m.Percent())
+	if m.Where != "" {
+		s += fmt.Sprintf(" at %s", m.Where)
+	}
+	return s
+}
+
+// Template measures a template, which should already be packaged,
+// against each of the Quotas. Transforms like AWS::Serverless can add
+// resources when the template is deployed, which are not counted.
+func Template(t cft.Template) []Metric {
+	size := len(format.String(t, format.Options{}))
+
+	description := 0
+	if d, err := t.GetSection(cft.Description); err == nil {
+		description = len(d.Value)
+	}
+
+	return []Metric{
+		{Quota: TemplateBodySize, Value: size},
+		{Quota: TemplateS3Size, Value: size},
+		{Quota: Resources, Value: count(t, cft.Resources)},
+		{Quota: Parameters, Value: count(t, cft.Parameters)},
+		{Quota: Outputs, Value: count(t, cft.Outputs)},
+		{Quota: Mappings, Value: count(t, cft.Mappings)},
+		mappingAttributes(t),
+		subLength(t),
+		{Quota: DescriptionLength, Value: description},
+		nameLength(t),
+	}
+}
+
+// count returns the number of entries in a section
+func count(t cft.Template, section cft.Section) int {
+	n, err := t.GetSection(section)
+	if err != nil {
+		return 0
+	}
+	return len(n.Content) / 2
+}
+
+// mappingAttributes finds the mapping with the most keys at either level
+func mappingAttributes(t cft.Template) Metric {
+	m := Metric{Quota: MappingAttributes}
+	mappings, err := t.GetSection(cft.Mappings)
+	if err != nil {
+		return m
+	}
+
+	for i := 0; i < len(mappings.Content); i += 2 {
+		name, mapping := mappings.Content[i].Value, mappings.Content[i+1]
+		if mapping.Kind != yaml.MappingNode {
+			continue
+		}
+		if n := len(mapping.Content) / 2; n > m.Value {
+			m.Value = n
+			m.Where = "Mappings/" + name
+		}
+		for j := 0; j < len(mapping.Content); j += 2 {
+			key, attributes := mapping.Content[j].Value, mapping.Content[j+1]
+			if n := len(attributes.Content) / 2; attributes.Kind == yaml.MappingNode && n > m.Value {
+				m.Value = n
+				m.Where = "Mappings/" + name + "/" + key
+			}
+		}
+	}
+
+	return m
+}
+
+// subLength finds the longest Fn::Sub string
+func subLength(t cft.Template) Metric {
+	m := Metric{Quota: SubLength}
+
+	var walk func(n *yaml.Node, where string)
+	walk = func(n *yaml.Node, where string) {
+		switch n.Kind {
+		case yaml.SequenceNode:
+			for _, c := range n.Content {
+				walk(c, where)
+			}
+		case yaml.MappingNode:
+			for i := 0; i < len(n.Content); i += 2 {
+				if n.Content[i].Value == "Fn::Sub" {
+					s := n.Content[i+1]
+					if s.Kind == yaml.SequenceNode && len(s.Content) > 0 {
+						s = s.Content[0]
+					}
+					if l := utf8.RuneCountInString(s.Value); s.Kind == yaml.ScalarNode && l > m.Value {
+						m.Value = l
+						m.Where = where
+					}
+				}
+				walk(n.Content[i+1], where)
+			}
+		}
+	}
+
+	for _, section := range []cft.Section{cft.Resources, cft.Outputs, cft.Conditions, cft.Rules, cft.Metadata} {
+		n, err := t.GetSection(section)
+		if err != nil || n.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i < len(n.Content); i += 2 {
+			walk(n.Content[i+1], string(section)+"/"+n.Content[i].Value)
+		}
+	}
+
+	return m
+}
+
+// nameLength finds the longest logical id
+func nameLength(t cft.Template) Metric {
+	m := Metric{Quota: NameLength}
+	for _, section := range []cft.Section{cft.Parameters, cft.Resources, cft.Outputs, cft.Mappings, cft.Conditions} {
+		n, err := t.GetSection(section)
+		if err != nil {
+			continue
+		}
+		for i := 0; i < len(n.Content); i += 2 {
+			if l := utf8.RuneCountInString(n.Content[i].Value); l > m.Value {
+				m.Value = l
+				m.Where = string(section) + "/" + n.Content[i].Value
+			}
+		}
+	}
+	return m
+}
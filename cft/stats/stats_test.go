@@ -0,0 +1,83 @@
+package stats_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/cft/stats"
+)
+
+func TestTemplate(t *testing.T) {
+	resources := strings.Builder{}
+	for i := 0; i < 450; i++ {
+		resources.WriteString(fmt.Sprintf("  Bucket%d:\n    Type: AWS::S3::Bucket\n", i))
+	}
+
+	source := fmt.Sprintf(`
+Description: Buckets
+Mappings:
+  Regions:
+    us-east-1:
+      Ami: a
+      Size: b
+      Name: c
+    us-west-2:
+      Ami: d
+Resources:
+%s
+  Function:
+    Type: AWS::Lambda::Function
+    Properties:
+      Description: !Sub
+        - "%s"
+        - Name: x
+Outputs:
+  Name:
+    Value: !Sub ${Function}
+`, resources.String(), strings.Repeat("a", 5000))
+
+	template, err := parse.String(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := stats.Template(template)
+	if len(metrics) != len(stats.Quotas) {
+		t.Fatalf("expected %d metrics, got %d", len(stats.Quotas), len(metrics))
+	}
+
+	byName := make(map[string]stats.Metric)
+	for i, m := range metrics {
+		if m.Quota != stats.Quotas[i] {
+			t.Errorf("expected %s, got %s", stats.Quotas[i].Name, m.Name)
+		}
+		byName[m.Name] = m
+	}
+
+	for _, expected := range []stats.Metric{
+		{Quota: stats.Resources, Value: 451},
+		{Quota: stats.Parameters, Value: 0},
+		{Quota: stats.Outputs, Value: 1},
+		{Quota: stats.Mappings, Value: 1},
+		{Quota: stats.MappingAttributes, Value: 3, Where: "Mappings/Regions/us-east-1"},
+		{Quota: stats.SubLength, Value: 5000, Where: "Resources/Function"},
+		{Quota: stats.DescriptionLength, Value: 7},
+		{Quota: stats.NameLength, Value: 9, Where: "Resources/Bucket100"},
+	} {
+		if m := byName[expected.Name]; m != expected {
+			t.Errorf("expected %v, got %v", expected, m)
+		}
+	}
+
+	if m := byName[stats.Resources.Name]; !m.Near(90) || m.Exceeded() {
+		t.Errorf("expected resources to be near the limit: %v", m)
+	}
+	if m := byName[stats.SubLength.Name]; !m.Exceeded() {
+		t.Errorf("expected the Sub to be too long: %v", m)
+	}
+	if m := byName[stats.TemplateBodySize.Name]; m.Value < 10000 || m.Value != byName[stats.TemplateS3Size.Name].Value {
+		t.Errorf("unexpected template size: %v", m)
+	}
+}
@@ -26,4 +26,8 @@ var Tags = map[string]string{
 	"!Rain::S3":       "Rain::S3",
 	"!Rain::Module":   "Rain::Module",
 	"!Rain::Constant": "Rain::Constant",
+	"!Rain::Template": "Rain::Template",
+	"!Rain::Ssm":      "Rain::Ssm",
+	"!Rain::Secret":   "Rain::Secret",
+	"!Rain::Image":    "Rain::Image",
 }
@@ -35,6 +35,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/cloudfront v1.44.2
 	github.com/aws/aws-sdk-go-v2/service/codeartifact v1.33.9
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.39.2
+	github.com/aws/aws-sdk-go-v2/service/ecr v1.38.0
 	github.com/aws/aws-sdk-go-v2/service/kms v1.37.10
 	github.com/aws/aws-sdk-go-v2/service/lightsail v1.42.9
 	github.com/aws/aws-sdk-go-v2/service/rds v1.93.4
@@ -14,6 +14,7 @@ import (
 
 	"github.com/aws-cloudformation/rain/cft"
 	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/stats"
 	"github.com/aws-cloudformation/rain/internal/aws"
 	"github.com/aws-cloudformation/rain/internal/aws/ccapi"
 	"github.com/aws-cloudformation/rain/internal/aws/s3"
@@ -64,12 +65,11 @@ var schemaFiles embed.FS
 func checkTemplate(template cft.Template) (string, error) {
 	templateBody := format.String(template, format.Options{})
 
-	// Max template size is 1MB
-	if len(templateBody) > (1024 * 1024) {
+	if len(templateBody) > stats.TemplateS3Size.Limit {
 		return "", fmt.Errorf("template is too large to deploy")
 	}
 
-	if len(templateBody) > 51200 {
+	if len(templateBody) > stats.TemplateBodySize.Limit {
 		config.Debugf("Template is too large to deploy directly; uploading to S3.")
 
 		bucket := s3.RainBucket(false)
@@ -179,6 +179,39 @@ func ListStacks() ([]types.StackSummary, error) {
 	return stacks, nil
 }
 
+// ListImports returns the names of the stacks that import an export
+func ListImports(exportName string) ([]string, error) {
+	imports := make([]string, 0)
+
+	var token *string
+
+	for {
+		res, err := getClient().ListImports(context.Background(), &cloudformation.ListImportsInput{
+			ExportName: ptr.String(exportName),
+			NextToken:  token,
+		})
+
+		if err != nil {
+			// CloudFormation returns an error if no stacks import the export
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && strings.Contains(apiErr.ErrorMessage(), "is not imported by any stack") {
+				return imports, nil
+			}
+			return imports, err
+		}
+
+		imports = append(imports, res.Imports...)
+
+		if res.NextToken == nil {
+			break
+		}
+
+		token = res.NextToken
+	}
+
+	return imports, nil
+}
+
 // DeleteStack deletes a stack
 func DeleteStack(stackName string, roleArn string) error {
 	input := &cloudformation.DeleteStackInput{
@@ -3,6 +3,7 @@ package cfn
 import (
 	"encoding/json"
 	"reflect"
+	"strings"
 )
 
 type SchemaLike interface {
@@ -16,7 +17,7 @@ type Prop struct {
 	Items                *Prop            `json:"items"`
 	Type                 any              `json:"type"`
 	UniqueItems          bool             `json:"uniqueItems"`
-	InsertionOrder       bool             `json:"insertionOrder"`
+	InsertionOrder       *bool            `json:"insertionOrder"` // nil means true
 	Ref                  string           `json:"$ref"`
 	MaxLength            int              `json:"maxLength"`
 	MinLength            int              `json:"minLength"`
@@ -76,6 +77,93 @@ func ParseSchema(source string) (*Schema, error) {
 	return &s, nil
 }
 
+// parsedSchemas caches schemas returned by GetSchema
+var parsedSchemas = make(map[string]*Schema)
+
+// GetSchema returns the parsed and patched registry schema for a type.
+// Use OnlyUseCache to avoid any API calls and only read embedded schemas.
+func GetSchema(typeName string, cacheUsage ResourceCacheUsage) (*Schema, error) {
+	if s, ok := parsedSchemas[typeName]; ok && cacheUsage != DoNotUseCache {
+		return s, nil
+	}
+	source, err := GetTypeSchema(typeName, cacheUsage)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := ParseSchema(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.Patch(); err != nil {
+		return nil, err
+	}
+	parsedSchemas[typeName] = schema
+	return schema, nil
+}
+
+// ResolveRef returns the definition for a $ref like #/definitions/Name,
+// or nil if it can't be found
+func (schema *Schema) ResolveRef(ref string) *Prop {
+	name, found := strings.CutPrefix(ref, "#/definitions/")
+	if !found {
+		return nil
+	}
+	return schema.Definitions[name]
+}
+
+// Prop returns the property at path, following definitions and list items.
+// The path is made of property names, without list indexes.
+func (schema *Schema) Prop(path []string) *Prop {
+	props := schema.Properties
+	var prop *Prop
+	for _, name := range path {
+		if props == nil {
+			return nil
+		}
+		prop = schema.resolve(props[name])
+		if prop == nil {
+			return nil
+		}
+		props = prop.Properties
+		if items := schema.resolve(prop.Items); items != nil {
+			props = items.Properties
+		}
+	}
+	return prop
+}
+
+// resolve follows a $ref if the property has one
+func (schema *Schema) resolve(prop *Prop) *Prop {
+	if prop != nil && prop.Ref != "" {
+		return schema.ResolveRef(prop.Ref)
+	}
+	return prop
+}
+
+// IsUnorderedList returns true if the property at path is a list
+// with insertionOrder set to false, which means that the order of
+// the list elements does not matter. The schema is read from the
+// cache, so this does not call the registry.
+func IsUnorderedList(typeName string, path []string) bool {
+	schema, err := GetSchema(typeName, OnlyUseCache)
+	if err != nil {
+		return false
+	}
+	prop := schema.Prop(path)
+	return prop != nil && prop.InsertionOrder != nil && !*prop.InsertionOrder
+}
+
+// CreateOnlyProperties returns the createOnlyProperties for a type,
+// like /properties/BucketName. Changing one of these properties
+// replaces the resource. The schema is read from the cache.
+func CreateOnlyProperties(typeName string) []string {
+	schema, err := GetSchema(typeName, OnlyUseCache)
+	if err != nil {
+		return nil
+	}
+	return schema.CreateOnlyProperties
+}
+
 // Patch applies patches to the schema to add things like undocumented enums
 func (schema *Schema) Patch() error {
 	switch schema.TypeName {
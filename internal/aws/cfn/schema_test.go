@@ -111,3 +111,15 @@ func TestSchemaFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestIsUnorderedList(t *testing.T) {
+	if !cfn.IsUnorderedList("AWS::EC2::SecurityGroup", []string{"SecurityGroupIngress"}) {
+		t.Errorf("expected SecurityGroupIngress to be unordered")
+	}
+	if cfn.IsUnorderedList("AWS::EC2::SecurityGroup", []string{"GroupDescription"}) {
+		t.Errorf("expected GroupDescription not to be an unordered list")
+	}
+	if cfn.IsUnorderedList("AWS::Not::AType", []string{"Foo"}) {
+		t.Errorf("expected unknown types to be ordered")
+	}
+}
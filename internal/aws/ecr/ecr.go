@@ -0,0 +1,134 @@
+package ecr
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	rainaws "github.com/aws-cloudformation/rain/internal/aws"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"github.com/aws-cloudformation/rain/internal/console"
+	"github.com/aws-cloudformation/rain/internal/console/spinner"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// RepositoryName is the name of the repository that rain bootstrap
+// creates for container images
+const RepositoryName = "rain-images"
+
+func getClient() *ecr.Client {
+	return ecr.NewFromConfig(rainaws.Config())
+}
+
+// GetRepositoryURI returns the URI of a repository,
+// or an empty string if it does not exist
+func GetRepositoryURI(name string) (string, error) {
+	res, err := getClient().DescribeRepositories(context.Background(), &ecr.DescribeRepositoriesInput{
+		RepositoryNames: []string{name},
+	})
+	if err != nil {
+		var nf *types.RepositoryNotFoundException
+		if errors.As(err, &nf) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	if len(res.Repositories) == 0 {
+		return "", nil
+	}
+
+	return aws.ToString(res.Repositories[0].RepositoryUri), nil
+}
+
+// CreateRepository creates a repository that scans images when they are pushed
+// and does not allow tags to be overwritten, and returns its URI
+func CreateRepository(name string) (string, error) {
+	res, err := getClient().CreateRepository(context.Background(), &ecr.CreateRepositoryInput{
+		RepositoryName:     aws.String(name),
+		ImageTagMutability: types.ImageTagMutabilityImmutable,
+		ImageScanningConfiguration: &types.ImageScanningConfiguration{
+			ScanOnPush: true,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(res.Repository.RepositoryUri), nil
+}
+
+// RainRepository returns the URI of the rain image repository in the current
+// region and asks the user if they wish it to be created if it does not exist,
+// unless forceCreation is true, then it will not ask.
+func RainRepository(forceCreation bool) string {
+	uri, err := GetRepositoryURI(RepositoryName)
+	if err != nil {
+		panic(fmt.Errorf("unable to confirm whether image repository exists: %w", err))
+	}
+
+	if uri != "" {
+		config.Debugf("Image repository: %s", uri)
+		return uri
+	}
+
+	spinner.Pause()
+	if !forceCreation && !console.Confirm(true, fmt.Sprintf("Rain needs to create an ECR repository called '%s'. Continue?", RepositoryName)) {
+		panic(errors.New("you may create the repository manually and then re-run this operation"))
+	}
+	spinner.Resume()
+
+	uri, err = CreateRepository(RepositoryName)
+	if err != nil {
+		panic(fmt.Errorf("unable to create image repository '%s': %w", RepositoryName, err))
+	}
+
+	config.Debugf("Created image repository: %s", uri)
+	return uri
+}
+
+// ImageExists returns true if the repository has an image with the tag
+func ImageExists(name string, tag string) (bool, error) {
+	_, err := getClient().DescribeImages(context.Background(), &ecr.DescribeImagesInput{
+		RepositoryName: aws.String(name),
+		ImageIds:       []types.ImageIdentifier{{ImageTag: aws.String(tag)}},
+	})
+	if err != nil {
+		var nf *types.ImageNotFoundException
+		if errors.As(err, &nf) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetLoginPassword returns the user name and password that
+// docker login needs to push images to ECR
+func GetLoginPassword() (string, string, error) {
+	res, err := getClient().GetAuthorizationToken(context.Background(), &ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(res.AuthorizationData) == 0 {
+		return "", "", errors.New("no authorization data returned")
+	}
+
+	token, err := base64.StdEncoding.DecodeString(aws.ToString(res.AuthorizationData[0].AuthorizationToken))
+	if err != nil {
+		return "", "", err
+	}
+
+	user, password, ok := strings.Cut(string(token), ":")
+	if !ok {
+		return "", "", errors.New("unexpected authorization token format")
+	}
+
+	return user, password, nil
+}
@@ -174,6 +174,12 @@ func CreateBucket(bucketName string) error {
 
 // Upload uploads an artifact to the bucket with a unique name
 func Upload(bucketName string, content []byte) (string, error) {
+	return UploadAs(bucketName, fmt.Sprintf("%x", sha256.Sum256(content)), content)
+}
+
+// UploadAs uploads an artifact to the bucket with a key that ends with
+// name, such as a hash of the files that the artifact was made from
+func UploadAs(bucketName string, name string, content []byte) (string, error) {
 	isBucketExists, errBucketExists := BucketExists(bucketName)
 
 	if errBucketExists != nil {
@@ -184,7 +190,7 @@ func Upload(bucketName string, content []byte) (string, error) {
 		return "", fmt.Errorf("bucket does not exist: '%s'", bucketName)
 	}
 
-	key := filepath.Join(BucketKeyPrefix, fmt.Sprintf("%x", sha256.Sum256(content)))
+	key := filepath.Join(BucketKeyPrefix, name)
 
 	accountId, err := getAccountId()
 	if err != nil {
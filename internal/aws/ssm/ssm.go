@@ -27,6 +27,21 @@ func GetParameter(name string) (string, error) {
 	return *parameter.Parameter.Value, nil
 }
 
+// GetSecret returns the value of a Secrets Manager secret, which SSM
+// can read through the /aws/reference/secretsmanager/ path.
+func GetSecret(id string) (string, error) {
+	client := getClient()
+	parameter, err := client.GetParameter(context.Background(), &ssm.GetParameterInput{
+		Name:           aws.String("/aws/reference/secretsmanager/" + id),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return *parameter.Parameter.Value, nil
+}
+
 // SetParameter sets the value of a parameter and overwrites a pervious value
 func SetParameter(name string, value string) error {
 	client := getClient()
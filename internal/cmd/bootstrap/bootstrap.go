@@ -1,6 +1,7 @@
 package bootstrap
 
 import (
+	"github.com/aws-cloudformation/rain/internal/aws/ecr"
 	"github.com/aws-cloudformation/rain/internal/aws/s3"
 	"github.com/spf13/cobra"
 )
@@ -10,16 +11,17 @@ var force = false
 // Cmd is the bootstrap command's entrypoint
 var Cmd = &cobra.Command{
 	Use:                   "bootstrap",
-	Short:                 "Creates the artifacts bucket",
-	Long:                  "Creates a s3 bucket to hold all the artifacts generated and referenced by rain cli",
+	Short:                 "Creates the artifacts bucket and image repository",
+	Long:                  "Creates a s3 bucket to hold all the artifacts generated and referenced by rain cli, and an ECR repository for the images that !Rain::Image builds",
 	Args:                  cobra.MaximumNArgs(0),
 	Aliases:               []string{"bootstrap"},
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		s3.RainBucket(force)
+		ecr.RainRepository(force)
 	},
 }
 
 func init() {
-	Cmd.Flags().BoolVarP(&force, "yes", "y", false, "creates the bucket in the account without any user confirmation")
+	Cmd.Flags().BoolVarP(&force, "yes", "y", false, "creates the bucket and repository in the account without any user confirmation")
 }
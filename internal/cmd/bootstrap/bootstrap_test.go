@@ -13,7 +13,7 @@ func Example_bootstrap_help() {
 	}
 
 	bootstrap.Cmd.Execute()
-	// Creates a s3 bucket to hold all the artifacts generated and referenced by rain cli
+	// Creates a s3 bucket to hold all the artifacts generated and referenced by rain cli, and an ECR repository for the images that !Rain::Image builds
 
 	// Usage:
 	//   rain bootstrap
@@ -27,5 +27,5 @@ func Example_bootstrap_help() {
 	//       --no-colour        Disable colour output
 	//   -p, --profile string   AWS profile name; read from the AWS CLI configuration file
 	//   -r, --region string    AWS region to use
-	//   -y, --yes              creates the bucket in the account without any user confirmation
+	//   -y, --yes              creates the bucket and repository in the account without any user confirmation
 }
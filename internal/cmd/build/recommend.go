@@ -3,6 +3,7 @@ package build
 import (
 	"embed"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/aws-cloudformation/rain/cft/format"
@@ -39,10 +40,17 @@ func writeFile(args []string) {
 		return
 	}
 
-	// TODO: Local builds should not make any API calls like zipping
-	// files and putting them into an S3 bucket.
-	// Either hack the packaging code to not process S3 directives,
-	// or make sure none of the samples use them
+	// Local builds should not make any API calls, so
+	// S3 directives get placeholder URIs. The assets are
+	// not needed, so they go to a directory that is removed.
+	dryRunDir, err := os.MkdirTemp("", "rain-build-")
+	if err != nil {
+		fmt.Println(console.Red(err))
+		return
+	}
+	defer os.RemoveAll(dryRunDir)
+	pkg.DryRun = true
+	pkg.DryRunDir = dryRunDir
 
 	transformed, err := pkg.Template(packaged, "tmpl", &templateFiles)
 	if err != nil {
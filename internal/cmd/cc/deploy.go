@@ -7,6 +7,7 @@ import (
 
 	"github.com/aws-cloudformation/rain/cft"
 	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/langext"
 	"github.com/aws-cloudformation/rain/cft/pkg"
 	"github.com/aws-cloudformation/rain/internal/aws/cfn"
 	"github.com/aws-cloudformation/rain/internal/aws/s3"
@@ -47,6 +48,7 @@ func deploy(cmd *cobra.Command, args []string) {
 
 	// Package template
 	spinner.Push(fmt.Sprintf("Preparing template '%s'", base))
+	_, pkg.Params = dc.CombineConfig(nil, params, configFilePath)
 	template := PackageTemplate(fn, true)
 	spinner.Pop()
 
@@ -60,6 +62,18 @@ func deploy(cmd *cobra.Command, args []string) {
 	}
 	templateConfig = dc
 
+	// Cloud Control API does not run transforms, so Fn::ForEach
+	// and the other language extensions are expanded here
+	if langext.Uses(template) {
+		template, err = langext.Expand(template, langext.Options{Config: dc})
+		if err != nil {
+			panic(ui.Errorf(err, "unable to expand language extensions in '%s'", fn))
+		}
+		if langext.Uses(template) {
+			panic(fmt.Errorf("unable to fully expand %s in '%s'", langext.Transform, fn))
+		}
+	}
+
 	// Before we do anything else, make sure that all types in the template
 	// are fully supported by Cloud Control API
 	types, err := template.GetTypes()
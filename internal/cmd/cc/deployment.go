@@ -29,21 +29,18 @@ var successFormat table.Formatter
 
 // getTemplateResource returns the yaml node based on the logical id
 func getTemplateResource(template cft.Template, logicalId string) (*yaml.Node, error) {
-	rootMap := template.Node.Content[0]
-	_, resources, _ := s11n.GetMapValue(rootMap, "Resources")
-	if resources == nil {
+	if _, err := template.GetSection(cft.Resources); err != nil {
 		panic("Expected to find a Resources section in the template")
 	}
-	for i, r := range resources.Content {
-		if i%2 != 0 {
-			continue
-		}
-		if logicalId == r.Value {
-			resource := resources.Content[i+1]
-			return resource, nil
-		}
+	model, err := template.Model()
+	if err != nil {
+		return nil, err
+	}
+	resource := model.Resource(logicalId)
+	if resource == nil {
+		return nil, fmt.Errorf("could not find Resource %v", logicalId)
 	}
-	return nil, fmt.Errorf("could not find Resource %v", logicalId)
+	return resource.Node, nil
 }
 
 // deployResource calls the Cloud Control API to deploy the resource
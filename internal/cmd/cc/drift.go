@@ -59,7 +59,11 @@ func runDrift(cmd *cobra.Command, args []string) {
 
 func runDriftOnState(name string, template cft.Template, bucketName string, key string) error {
 
-	resources, err := template.GetSection(cft.Resources)
+	if _, err := template.GetSection(cft.Resources); err != nil {
+		return err
+	}
+
+	model, err := template.Model()
 	if err != nil {
 		return err
 	}
@@ -104,9 +108,8 @@ func runDriftOnState(name string, template cft.Template, bucketName string, key
 	selections := make([]selection, 0)
 
 	// Query each resource and stop to ask how to handle drift after each one
-	for i := 0; i < len(resources.Content); i += 2 {
-		resourceName := resources.Content[i].Value
-		resourceNode := resources.Content[i+1]
+	for _, resourceName := range model.Names(cft.Resources) {
+		resourceNode := model.Resource(resourceName).Node
 		_, resourceModel, _ := s11n.GetMapValue(resourceModels, resourceName)
 		if resourceModel == nil {
 			panic(fmt.Errorf("expected %s to have a ResourceModel", resourceName))
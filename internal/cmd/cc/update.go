@@ -79,28 +79,29 @@ func update(stateTemplate cft.Template, template cft.Template) (cft.Template, er
 	newTemplate.Node = node.Clone(template.Node)
 
 	// Get a reference to the resources in the new template
-	newRootMap := newTemplate.Node.Content[0]
-	_, newResourceMap, _ := s11n.GetMapValue(newRootMap, "Resources")
-	if newResourceMap == nil {
+	newResourceMap, err := newTemplate.GetSection(cft.Resources)
+	if err != nil {
 		panic("Expected to find a Resources section in the new template")
 	}
+	newModel, err := newTemplate.Model()
+	if err != nil {
+		panic(err)
+	}
+	stateModel, err := stateTemplate.Model()
+	if err != nil {
+		panic(err)
+	}
 
 	stateResources := make(map[string]*yaml.Node, 0)
 	newResources := make(map[string]*yaml.Node, 0)
 	resourceActionStates := make(map[string]*yaml.Node) // "State" mapping node
 
-	for i, r := range stateResourceMap.Content {
-		if i%2 == 0 {
-			name := r.Value
-			stateResources[name] = stateResourceMap.Content[i+1]
-		}
+	for name, r := range stateModel.Resources {
+		stateResources[name] = r.Node
 	}
-	for i, r := range newResourceMap.Content {
-		if i%2 == 0 {
-			name := r.Value
-			newResources[name] = newResourceMap.Content[i+1]
-			resourceActionStates[name] = node.AddMap(newResources[name], "State")
-		}
+	for name, r := range newModel.Resources {
+		newResources[name] = r.Node
+		resourceActionStates[name] = node.AddMap(newResources[name], "State")
 	}
 
 	// Iterate over the diff and add actions to the output file
@@ -35,6 +35,8 @@ var noexec bool
 var changeset bool
 var experimental bool
 var includeNested bool
+var noLint bool
+var strictLint bool
 
 // Cmd is the deploy command's entrypoint
 var Cmd = &cobra.Command{
@@ -81,6 +83,15 @@ To execute a changeset:
 rain deploy --changeset <stackName> <changeSetName>
 
 To list and delete changesets, use the ls and rm commands.
+
+Before creating a changeset, rain checks the template and parameter values against
+the resource schemas in the same way as rain lint. Invalid parameter values stop the
+deployment. Other findings are shown as warnings, since the embedded schemas may be
+older than the registry; use --strict-lint to stop on any lint error, or --no-lint to
+skip the checks.
+Circular dependencies between resources are always reported, since CloudFormation
+would reject the template. So are templates that are over, or close to, the quotas
+that CloudFormation enforces, like the number of resources. See rain stats.
 `,
 	Args:                  cobra.RangeArgs(1, 3),
 	DisableFlagsInUseLine: true,
@@ -120,6 +131,7 @@ To list and delete changesets, use the ls and rm commands.
 			if experimental {
 				cftpkg.Experimental = true
 			}
+			_, cftpkg.Params = dc.CombineConfig(nil, params, configFilePath)
 			spinner.Push(fmt.Sprintf("Preparing template '%s'", base))
 			template := PackageTemplate(fn, yes)
 			templateNode = template.Node
@@ -131,15 +143,6 @@ To list and delete changesets, use the ls and rm commands.
 				panic("metadata commands require the --experimental flag")
 			}
 
-			// Process metadata Rain Content before (Run build scripts before deployment)
-			if !changeset {
-				err := processMetadataBefore(cft.Template{Node: templateNode},
-					stackName, filepath.Dir(fn))
-				if err != nil {
-					panic(err)
-				}
-			}
-
 			stackName = dc.GetStackName(suppliedStackName, base)
 
 			// Check current stack status
@@ -153,6 +156,29 @@ To list and delete changesets, use the ls and rm commands.
 				panic(err)
 			}
 
+			if err := checkCycles(template); err != nil {
+				panic(err)
+			}
+
+			if err := checkQuotas(template); err != nil {
+				panic(err)
+			}
+
+			if !noLint {
+				if err := lintTemplate(template, dc.Params, strictLint); err != nil {
+					panic(err)
+				}
+			}
+
+			// Process metadata Rain Content before (Run build scripts before deployment, once the checks have passed)
+			if !changeset {
+				err := processMetadataBefore(cft.Template{Node: templateNode},
+					stackName, filepath.Dir(fn))
+				if err != nil {
+					panic(err)
+				}
+			}
+
 			// Figure out how long we think the stack will take to execute
 			//totalSeconds := forecast.PredictTotalEstimate(template, stackExists)
 			// TODO - Wait until the forecast command is GA and add this to output
@@ -340,4 +366,8 @@ func init() {
 	Cmd.Flags().BoolVar(&experimental, "experimental", false, "Acknowledge that you want to deploy with an experimental feature")
 	Cmd.Flags().BoolVar(&includeNested, "nested-change-set", true, "Whether or not to include nested stacks in the change set")
 	Cmd.Flags().BoolVar(&cftpkg.NoAnalytics, "no-analytics", false, "Do not write analytics to Metadata")
+	Cmd.Flags().StringVar(&cftpkg.ImageBuilder, "image-builder", "docker", "The command that builds and pushes images for !Rain::Image: docker, podman or buildah")
+	Cmd.Flags().BoolVar(&cftpkg.NoCache, "no-cache", false, "Build and upload every asset, instead of reusing assets that have not changed")
+	Cmd.Flags().BoolVar(&noLint, "no-lint", false, "Do not check the template against resource schemas before deploying")
+	Cmd.Flags().BoolVar(&strictLint, "strict-lint", false, "Stop the deployment on any lint error, not only invalid parameter values")
 }
@@ -0,0 +1,101 @@
+package deploy
+
+import (
+	"fmt"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/graph"
+	"github.com/aws-cloudformation/rain/cft/lint"
+	"github.com/aws-cloudformation/rain/cft/stats"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"github.com/aws-cloudformation/rain/internal/console"
+	"github.com/aws-cloudformation/rain/internal/console/spinner"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// lintTemplate checks the packaged template and parameter values against
+// the registry schemas, so that mistakes are caught before we wait for
+// a change set to fail. Returns an error if any parameter values are not
+// valid. Other errors only block the deployment if strict is true, since
+// the embedded schemas can be older than the registry.
+func lintTemplate(template cft.Template, params []types.Parameter, strict bool) error {
+	values := make(map[string]string)
+	for _, p := range params {
+		// Previous values are not known until the change set is created
+		if p.ParameterKey != nil && p.ParameterValue != nil {
+			values[*p.ParameterKey] = *p.ParameterValue
+		}
+	}
+
+	spinner.Push("Checking template")
+	// Cycles are reported by checkCycles
+	findings, err := lint.Template(template, lint.Options{Params: values, Ignore: []string{lint.L0013}})
+	spinner.Pop()
+	if err != nil {
+		// Don't block a deployment if the linter can't handle the template
+		config.Debugf("unable to lint template: %v", err)
+		return nil
+	}
+
+	errors := 0
+	for _, f := range findings {
+		if f.Severity == lint.Error && (strict || blocksDeploy(f)) {
+			fmt.Println(console.Red(f.String()))
+			errors++
+		} else {
+			fmt.Println(console.Yellow(f.String()))
+		}
+	}
+
+	if errors > 0 {
+		return fmt.Errorf("the template has %d errors; fix them or deploy with --no-lint", errors)
+	}
+	return nil
+}
+
+// blocksDeploy returns true for findings about the parameter values
+// being deployed, which would make the change set fail
+func blocksDeploy(f lint.Finding) bool {
+	switch f.Code {
+	case lint.L0011, lint.L0012:
+		return true
+	}
+	return false
+}
+
+// checkCycles reports circular dependencies in the template, with the
+// chain of references that forms each one. CloudFormation rejects
+// templates with cycles, so this returns an error if there are any.
+func checkCycles(template cft.Template) error {
+	cycles := graph.New(template).Cycles()
+	for _, c := range cycles {
+		fmt.Println(console.Red(fmt.Sprintf("Circular dependency: %s", c)))
+		fmt.Println(console.Yellow(fmt.Sprintf("  To fix this, %s", c.Suggestion())))
+	}
+	if len(cycles) > 0 {
+		return fmt.Errorf("the template has %d circular dependencies", len(cycles))
+	}
+	return nil
+}
+
+// checkQuotas warns when the template is close to the quotas that
+// CloudFormation enforces, and returns an error if it can't be deployed
+func checkQuotas(template cft.Template) error {
+	exceeded := 0
+	for _, m := range stats.Template(template) {
+		switch {
+		case m.Exceeded() && m.Blocking:
+			fmt.Println(console.Red(m.String()))
+			exceeded++
+		case m.Near(quotaThreshold) && m.Blocking:
+			fmt.Println(console.Yellow(m.String()))
+		}
+	}
+	if exceeded > 0 {
+		return fmt.Errorf("the template exceeds %d CloudFormation quotas; see rain stats", exceeded)
+	}
+	return nil
+}
+
+// quotaThreshold is the percentage of a quota that deploy warns about
+const quotaThreshold = 90
@@ -0,0 +1,40 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+)
+
+func TestLintTemplate(t *testing.T) {
+	template, err := parse.String(`
+Parameters:
+  Env:
+    Type: String
+    AllowedValues: [dev, prod]
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      NotAProperty: !Ref Env
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev := []types.Parameter{{ParameterKey: ptr.String("Env"), ParameterValue: ptr.String("dev")}}
+	test := []types.Parameter{{ParameterKey: ptr.String("Env"), ParameterValue: ptr.String("test")}}
+
+	// The unknown property is only a warning, since the schema may be stale
+	if err := lintTemplate(template, dev, false); err != nil {
+		t.Errorf("expected schema errors not to block the deployment: %v", err)
+	}
+	if err := lintTemplate(template, dev, true); err == nil {
+		t.Error("expected --strict-lint to block on schema errors")
+	}
+	if err := lintTemplate(template, test, false); err == nil {
+		t.Error("expected an invalid parameter value to block the deployment")
+	}
+}
@@ -2,9 +2,11 @@ package deploy
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/diff"
 	"github.com/aws-cloudformation/rain/cft/pkg"
 	"github.com/aws-cloudformation/rain/internal/aws/cfn"
 	"github.com/aws-cloudformation/rain/internal/aws/s3"
@@ -42,7 +44,15 @@ func formatChangeSet(stackName, changeSetName string) string {
 		case types.ChangeAction("Add"):
 			out.WriteString(console.Green("  + " + line))
 		case types.ChangeAction("Modify"):
-			out.WriteString(console.Blue("  > " + line))
+			note := replacementNote(change.ResourceChange)
+			switch {
+			case note == "":
+				out.WriteString(console.Blue("  > " + line))
+			case diff.IsStateful(ptr.ToString(change.ResourceChange.ResourceType)):
+				out.WriteString(console.Red("  > " + line + note + " - data will be lost!"))
+			default:
+				out.WriteString(console.Yellow("  > " + line + note))
+			}
 		case types.ChangeAction("Remove"):
 			out.WriteString(console.Red("  - " + line))
 		}
@@ -81,6 +91,42 @@ func formatChangeSet(stackName, changeSetName string) string {
 	return strings.TrimSpace(out.String())
 }
 
+// replacementNote returns a description of why a modified resource will
+// be replaced, or an empty string if it won't be. The properties listed are
+// the ones that CloudFormation says require recreation, along with any
+// createOnlyProperties from the resource schema.
+func replacementNote(rc *types.ResourceChange) string {
+	if rc.Replacement != types.ReplacementTrue && rc.Replacement != types.ReplacementConditional {
+		return ""
+	}
+
+	createOnly := cfn.CreateOnlyProperties(ptr.ToString(rc.ResourceType))
+
+	names := make([]string, 0)
+	for _, detail := range rc.Details {
+		t := detail.Target
+		if t == nil || t.Attribute != types.ResourceAttributeProperties {
+			continue
+		}
+		name := ptr.ToString(t.Name)
+		if t.RequiresRecreation != types.RequiresRecreationNever ||
+			slices.Contains(createOnly, "/properties/"+name) {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	what := "will be replaced"
+	if rc.Replacement == types.ReplacementConditional {
+		what = "might be replaced"
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf(" (%s)", what)
+	}
+	return fmt.Sprintf(" (%s: %s)", what, strings.Join(names, ", "))
+}
+
 func PackageTemplate(fn string, yes bool) cft.Template {
 	// Call RainBucket for side-effects in case we want to force bucket creation
 	s3.RainBucket(yes)
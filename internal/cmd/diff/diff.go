@@ -1,41 +1,216 @@
 package diff
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/aws-cloudformation/rain/internal/aws/cfn"
+	"github.com/aws-cloudformation/rain/internal/console"
 	"github.com/aws-cloudformation/rain/internal/ui"
+	"github.com/aws-cloudformation/rain/plugins/deployconfig"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+	"gopkg.in/yaml.v3"
 
+	"github.com/aws-cloudformation/rain/cft"
 	"github.com/aws-cloudformation/rain/cft/diff"
+	"github.com/aws-cloudformation/rain/cft/langext"
 	"github.com/aws-cloudformation/rain/cft/parse"
 	"github.com/spf13/cobra"
 )
 
 var longDiff = false
+var jsonFlag = false
+var yamlFlag = false
+var semantic = false
+var stackName string
+var params []string
+var configFilePath string
+var expandExtensions bool
 
 // Cmd is the diff command's entrypoint
 var Cmd = &cobra.Command{
-	Use:                   "diff <from> <to>",
-	Short:                 "Compare CloudFormation templates",
-	Long:                  "Outputs a summary of the changes necessary to transform the CloudFormation template named <from> into the template named <to>.",
-	Args:                  cobra.ExactArgs(2),
+	Use:   "diff <from> [<to>]",
+	Short: "Compare CloudFormation templates",
+	Long: `Outputs a summary of the changes necessary to transform the CloudFormation template named <from> into the template named <to>.
+Use rain diff <template> --stack <stack> to compare a local template with a deployed stack.
+
+With --json or --yaml, the output is a list of changes instead, which is easier for other tools to read.
+Each change has a JSON pointer path, a mode of added, removed or changed, and the old and new values.
+
+Updates that change a property that can only be set when a resource is created, according to the
+resource schema's createOnlyProperties, force CloudFormation to replace the resource. These are listed
+after the diff, and are marked with replacement: true in --json and --yaml output. Replacing a
+resource that holds data, like an S3 bucket, RDS database or DynamoDB table, is highlighted.
+
+With --semantic, differences that don't change what CloudFormation would deploy are ignored.
+Renamed resources are matched by type and properties, and shown as moved (~) rather than removed
+and added, along with the references to them. Lists that the resource schema marks as unordered,
+like security group rules, are compared as sets.
+
+With --stack, the template deployed to the stack is compared with the local <template> instead,
+which is packaged the same way that rain deploy packages it. The stack's current parameter values
+are also compared with the values that rain deploy would use, from --params and --config, keeping
+previous values for parameters that aren't supplied. In --json and --yaml output, parameter value
+changes have paths like /Parameters/Name/Value.
+
+With --expand-language-extensions, Fn::ForEach loops and the other functions of the
+AWS::LanguageExtensions transform are expanded in both templates before they are compared,
+so that the diff shows the resources that the loops make. Loops over parameters use the
+parameter's Default, or with --stack, the stack's parameter values.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if stackName != "" {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
-		leftFn, rightFn := args[0], args[1]
+		if jsonFlag && yamlFlag {
+			panic("--json and --yaml can't be used together")
+		}
+
+		var left, right cft.Template
+		paramChanges := make([]diff.Change, 0)
+		if stackName != "" {
+			var old map[string]string
+			left, right, old = stackTemplates(args[0])
+
+			var err error
+			supplied := suppliedParams()
+			paramChanges, err = compareParams(right, old, supplied)
+			if err != nil {
+				panic(ui.Errorf(err, "unable to compare parameters"))
+			}
+
+			if expandExtensions {
+				values := make(map[string]string)
+				for k, v := range old {
+					values[k] = v
+				}
+				for k, v := range supplied {
+					values[k] = v
+				}
+				left = expand(left, old, "the deployed template")
+				right = expand(right, values, args[0])
+			}
+		} else {
+			leftFn, rightFn := args[0], args[1]
 
-		left, err := parse.File(leftFn)
-		if err != nil {
-			panic(ui.Errorf(err, "unable to parse template '%s'", leftFn))
+			var err error
+			left, err = parse.File(leftFn)
+			if err != nil {
+				panic(ui.Errorf(err, "unable to parse template '%s'", leftFn))
+			}
+
+			right, err = parse.File(rightFn)
+			if err != nil {
+				panic(ui.Errorf(err, "unable to parse template '%s'", rightFn))
+			}
+
+			if expandExtensions {
+				left = expand(left, nil, leftFn)
+				right = expand(right, nil, rightFn)
+			}
 		}
 
-		right, err := parse.File(rightFn)
-		if err != nil {
-			panic(ui.Errorf(err, "unable to parse template '%s'", leftFn))
+		var d diff.Diff
+		if semantic {
+			d = diff.Semantic(left, right, diff.SemanticOptions{Unordered: cfn.IsUnorderedList})
+		} else {
+			d = diff.New(left, right)
 		}
 
-		fmt.Print(ui.ColouriseDiff(diff.New(left, right), longDiff))
+		replacements := diff.GetReplacements(d, cfn.CreateOnlyProperties)
+
+		changes := diff.Changes(d)
+		diff.MarkReplacements(changes, replacements)
+		changes = append(changes, paramChanges...)
+
+		switch {
+		case jsonFlag:
+			out, err := json.MarshalIndent(changes, "", "  ")
+			if err != nil {
+				panic(ui.Errorf(err, "unable to format changes as JSON"))
+			}
+			fmt.Println(string(out))
+		case yamlFlag:
+			fmt.Print(formatYaml(changes))
+		default:
+			fmt.Print(ui.ColouriseDiff(d, longDiff))
+			fmt.Print(formatReplacements(replacements))
+			fmt.Print(formatParams(paramChanges))
+		}
 	},
 }
 
+// formatReplacements summarizes the resources that will be replaced,
+// with a warning for resources that hold data
+func formatReplacements(replacements []diff.Replacement) string {
+	if len(replacements) == 0 {
+		return ""
+	}
+
+	out := strings.Builder{}
+	out.WriteString("\n")
+	out.WriteString(console.Yellow("Resources that will be replaced:"))
+	out.WriteString("\n")
+	for _, r := range replacements {
+		reason := strings.Join(r.Paths, ", ")
+		if r.MovedFrom != "" {
+			reason = fmt.Sprintf("renamed from %s", r.MovedFrom)
+		}
+		line := fmt.Sprintf("  %s %s (%s)", r.TypeName, r.LogicalId, reason)
+		if r.Stateful {
+			out.WriteString(console.Red(line + " - data will be lost!"))
+		} else {
+			out.WriteString(console.Yellow(line))
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// expand expands the AWS::LanguageExtensions transform in a template,
+// using the parameter values that are supplied
+func expand(t cft.Template, values map[string]string, name string) cft.Template {
+	if !langext.Uses(t) {
+		return t
+	}
+
+	dc := &deployconfig.DeployConfig{}
+	for k, v := range values {
+		dc.Params = append(dc.Params, types.Parameter{
+			ParameterKey:   ptr.String(k),
+			ParameterValue: ptr.String(v),
+		})
+	}
+
+	expanded, err := langext.Expand(t, langext.Options{Config: dc})
+	if err != nil {
+		panic(ui.Errorf(err, "unable to expand language extensions in %s", name))
+	}
+	return expanded
+}
+
+func formatYaml(changes []diff.Change) string {
+	buf := strings.Builder{}
+	e := yaml.NewEncoder(&buf)
+	e.SetIndent(2)
+	if err := e.Encode(changes); err != nil {
+		panic(ui.Errorf(err, "unable to format changes as YAML"))
+	}
+	return buf.String()
+}
+
 func init() {
 	Cmd.Flags().BoolVarP(&longDiff, "long", "l", false, "Include unchanged elements in diff output")
+	Cmd.Flags().BoolVarP(&jsonFlag, "json", "j", false, "Output a list of changes as JSON")
+	Cmd.Flags().BoolVar(&yamlFlag, "yaml", false, "Output a list of changes as YAML")
+	Cmd.Flags().StringVar(&stackName, "stack", "", "Compare a local template with the template deployed to this stack")
+	Cmd.Flags().StringSliceVar(&params, "params", []string{}, "set parameter values with --stack; use the format key1=value1,key2=value2")
+	Cmd.Flags().StringVarP(&configFilePath, "config", "c", "", "YAML or JSON file to set parameters with --stack")
+	Cmd.Flags().BoolVar(&expandExtensions, "expand-language-extensions", false, "Expand Fn::ForEach and the other AWS::LanguageExtensions functions before comparing")
+	Cmd.Flags().BoolVarP(&semantic, "semantic", "s", false, "Ignore differences that don't change the deployment, and detect renamed resources")
 }
@@ -28,4 +28,45 @@ func Example_diff() {
 	// (+)       BucketName:
 	// (+)         Ref: Bucket1
 	// (+)     Type: AWS::S3::Bucket
+	//
+	// Resources that will be replaced:
+	//   AWS::S3::Bucket Bucket1 (/Properties) - data will be lost!
+}
+
+func Example_diff_yaml() {
+	os.Args = []string{
+		os.Args[0],
+		"--yaml",
+		"../../../test/templates/success.template",
+		"../../../test/templates/failure.template",
+	}
+	defer diff.Cmd.Flags().Set("yaml", "false")
+
+	diff.Cmd.Execute()
+	// Output:
+	// - path: /Description
+	//   mode: changed
+	//   old: This template succeeds
+	//   new: This template fails
+	// - path: /Parameters
+	//   mode: removed
+	//   old:
+	//     BucketName:
+	//       Type: String
+	//   new: null
+	// - path: /Resources/Bucket1/Properties
+	//   mode: removed
+	//   old:
+	//     BucketName:
+	//       Ref: BucketName
+	//   new: null
+	//   replacement: true
+	// - path: /Resources/Bucket2
+	//   mode: added
+	//   old: null
+	//   new:
+	//     Properties:
+	//       BucketName:
+	//         Ref: Bucket1
+	//     Type: AWS::S3::Bucket
 }
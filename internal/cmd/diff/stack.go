@@ -0,0 +1,158 @@
+package diff
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/diff"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/cft/pkg"
+	"github.com/aws-cloudformation/rain/internal/aws/cfn"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"github.com/aws-cloudformation/rain/internal/console"
+	"github.com/aws-cloudformation/rain/internal/console/spinner"
+	"github.com/aws-cloudformation/rain/internal/dc"
+	"github.com/aws-cloudformation/rain/internal/ui"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+)
+
+// stackTemplates returns the template deployed to the stack and the
+// local template, packaged the same way rain deploy would package it,
+// along with the stack's current parameter values
+func stackTemplates(fn string) (cft.Template, cft.Template, map[string]string) {
+	spinner.Push(fmt.Sprintf("Fetching template for stack '%s'", stackName))
+	stack, err := cfn.GetStack(stackName)
+	if err != nil {
+		panic(ui.Errorf(err, "unable to find stack '%s'", stackName))
+	}
+
+	source, err := cfn.GetStackTemplate(stackName, false)
+	if err != nil {
+		panic(ui.Errorf(err, "unable to get template for stack '%s'", stackName))
+	}
+	spinner.Pop()
+
+	deployed, err := parse.String(source)
+	if err != nil {
+		panic(ui.Errorf(err, "unable to parse template for stack '%s'", stackName))
+	}
+
+	spinner.Push(fmt.Sprintf("Packaging template '%s'", fn))
+	pkg.Params = suppliedParams()
+	local, err := parse.File(fn)
+	if err != nil {
+		panic(ui.Errorf(err, "unable to parse template '%s'", fn))
+	}
+
+	local, err = pkg.Template(local, filepath.Dir(fn), nil)
+	if err != nil {
+		panic(ui.Errorf(err, "error packaging template '%s'", fn))
+	}
+	spinner.Pop()
+
+	return deployed, local, stackParams(stack)
+}
+
+// stackParams returns the stack's current parameter values
+func stackParams(stack types.Stack) map[string]string {
+	retval := make(map[string]string)
+	for _, p := range stack.Parameters {
+		retval[ptr.ToString(p.ParameterKey)] = ptr.ToString(p.ParameterValue)
+	}
+	return retval
+}
+
+// compareParams returns the changes to the stack's parameter values
+// that deploying t with the supplied values would make. Like rain deploy,
+// parameters that aren't supplied keep their previous value, and new
+// parameters get their Default. Changes have paths like /Parameters/Name/Value.
+func compareParams(t cft.Template, old, supplied map[string]string) ([]diff.Change, error) {
+	model, err := t.Model()
+	if err != nil {
+		return nil, err
+	}
+
+	retval := make([]diff.Change, 0)
+
+	for _, name := range model.Names(cft.Parameters) {
+		p := model.Parameters[name]
+		path := diff.Pointer(string(cft.Parameters), name, "Value")
+
+		prev, exists := old[name]
+		value, ok := supplied[name]
+		if !ok {
+			if exists {
+				// rain deploy uses the previous value
+				continue
+			}
+			d := p.Default()
+			if d == nil {
+				// rain deploy will ask for a value
+				config.Debugf("no value for new parameter %s", name)
+				continue
+			}
+			value = d.Value
+		}
+
+		switch {
+		case !exists:
+			retval = append(retval, diff.Change{Path: path, Mode: diff.ChangeAdded, New: value})
+		case p.GetString("NoEcho") == "true":
+			// The stack only returns ****
+			config.Debugf("unable to compare NoEcho parameter %s", name)
+		case prev != value:
+			retval = append(retval, diff.Change{Path: path, Mode: diff.ChangeChanged, Old: prev, New: value})
+		}
+	}
+
+	removed := make([]string, 0)
+	for name := range old {
+		if _, ok := model.Parameters[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		retval = append(retval, diff.Change{
+			Path: diff.Pointer(string(cft.Parameters), name, "Value"),
+			Mode: diff.ChangeRemoved,
+			Old:  old[name],
+		})
+	}
+
+	return retval, nil
+}
+
+// formatParams summarizes changes to parameter values
+func formatParams(changes []diff.Change) string {
+	if len(changes) == 0 {
+		return ""
+	}
+
+	out := strings.Builder{}
+	out.WriteString("\n")
+	out.WriteString("Parameter values:\n")
+	for _, c := range changes {
+		name := strings.Split(c.Path, "/")[2]
+		switch c.Mode {
+		case diff.ChangeAdded:
+			out.WriteString(console.Green(fmt.Sprintf("%s %s: %v", diff.Added, name, c.New)))
+		case diff.ChangeRemoved:
+			out.WriteString(console.Red(fmt.Sprintf("%s %s: %v", diff.Removed, name, c.Old)))
+		default:
+			out.WriteString(console.Blue(fmt.Sprintf("%s %s: %v -> %v", diff.Changed, name, c.Old, c.New)))
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// suppliedParams returns the parameter values from --params and --config
+func suppliedParams() map[string]string {
+	_, params := dc.CombineConfig(nil, params, configFilePath)
+	return params
+}
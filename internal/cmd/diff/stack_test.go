@@ -0,0 +1,62 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/diff"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompareParams(t *testing.T) {
+	source := `
+Parameters:
+  Env:
+    Type: String
+  Size:
+    Type: Number
+    Default: 10
+  Name:
+    Type: String
+  Password:
+    Type: String
+    NoEcho: true
+  Extra:
+    Type: String
+    Default: x
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+`
+	template, err := parse.String(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := map[string]string{
+		"Env":      "dev",
+		"Size":     "10",
+		"Name":     "a",
+		"Password": "****",
+		"Old":      "y",
+	}
+	supplied := map[string]string{
+		"Env":      "prod",
+		"Password": "secret",
+	}
+
+	changes, err := compareParams(template, old, supplied)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []diff.Change{
+		{Path: "/Parameters/Env/Value", Mode: diff.ChangeChanged, Old: "dev", New: "prod"},
+		{Path: "/Parameters/Extra/Value", Mode: diff.ChangeAdded, New: "x"},
+		{Path: "/Parameters/Old/Value", Mode: diff.ChangeRemoved, Old: "y"},
+	}
+
+	if d := cmp.Diff(expected, changes); d != "" {
+		t.Error(d)
+	}
+}
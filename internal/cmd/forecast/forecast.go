@@ -7,10 +7,12 @@ import (
 	"os"
 	"path/filepath"
 	"plugin"
+	"slices"
 	"strings"
 
 	"github.com/aws-cloudformation/rain/cft"
 	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/langext"
 	"github.com/aws-cloudformation/rain/cft/parse"
 	"github.com/aws-cloudformation/rain/cft/pkg"
 	"github.com/aws-cloudformation/rain/internal/aws"
@@ -63,6 +65,12 @@ var pluginPath string
 // Only run predictions from the plugin, don't run any of the built in checks
 var pluginOnly bool
 
+// The format for the results (--output-format)
+var outputFormat string
+
+// The template path to include in machine readable output
+var templatePath string
+
 const (
 	ALL    = "all"
 	CREATE = "create"
@@ -212,36 +220,32 @@ func Predict(source cft.Template, stackName string, stack types.Stack, stackExis
 	emptyInput.Ignore = fc.Ignore
 	forecast := fc.MakeForecast(emptyInput)
 
-	rootMap := source.Node.Content[0]
-
 	// Add the --debug arg to see a json version of the yaml node data model for the template
-	//config.Debugf("node: %v", toJson(rootMap))
+	//config.Debugf("node: %v", toJson(source.Node.Content[0]))
 
-	// Iterate over each resource
-
-	_, resources, _ := s11n.GetMapValue(rootMap, "Resources")
-	if resources == nil {
+	if _, err := source.GetSection(cft.Resources); err != nil {
 		panic("Expected to find a Resources section in the template")
 	}
+	model, err := source.Model()
+	if err != nil {
+		panic(err)
+	}
 
-	for i, r := range resources.Content {
+	// Iterate over each resource
 
-		if i%2 != 0 {
-			continue
-		}
-		logicalId := r.Value
+	for _, logicalId := range model.Names(cft.Resources) {
 		config.Debugf("logicalId: %v", logicalId)
 
-		resource := resources.Content[i+1]
-		_, typeNode, _ := s11n.GetMapValue(resource, "Type")
-		if typeNode == nil {
+		r := model.Resource(logicalId)
+		resource := r.Node
+		typeName := r.Type() // Should be something like AWS::S3::Bucket
+		if typeName == "" {
 			panic(fmt.Sprintf("Expected %v to have a Type", logicalId))
 		}
 
 		// Check the type and call functions that make checks
 		// on that type of resource.
 
-		typeName := typeNode.Value // Should be something like AWS::S3::Bucket
 		config.Debugf("typeName: %v", typeName)
 
 		spinner.Push(fmt.Sprintf("Checking %s: %s", typeName, logicalId))
@@ -282,6 +286,14 @@ func Predict(source cft.Template, stackName string, stack types.Stack, stackExis
 	totalSeconds := PredictTotalEstimate(source, stackExists)
 	config.Debugf("totalSeconds: %d", totalSeconds)
 
+	if outputFormat != TEXT {
+		err := writeReport(os.Stdout, outputFormat, templatePath, forecast, all)
+		if err != nil {
+			panic(err)
+		}
+		return forecast.GetNumFailed() == 0
+	}
+
 	if forecast.GetNumFailed() > 0 {
 		fmt.Println(console.Red("Stormy weather ahead! 🌪")) // 🌩️⛈
 		fmt.Println()
@@ -343,6 +355,11 @@ template has been checked to make sure it has a valid syntax.
 
 This command checks for some common issues across all resources, and 
 resource-specific checks. See the README for more details.
+
+Use --output-format to write the results as json, sarif, or junit, so that 
+CI systems can annotate the lines in the template that have problems. 
+Passed checks are only included with --all, except in junit output, which 
+reports every check as a test case.
 `,
 	Args:                  cobra.RangeArgs(1, 2),
 	DisableFlagsInUseLine: true,
@@ -350,6 +367,11 @@ resource-specific checks. See the README for more details.
 		fn := args[0]
 		base := filepath.Base(fn)
 		var suppliedStackName string
+		templatePath = fn
+
+		if !slices.Contains(outputFormats, outputFormat) {
+			panic(fmt.Sprintf("--output-format must be one of %s", strings.Join(outputFormats, ", ")))
+		}
 
 		if len(args) == 2 {
 			suppliedStackName = args[1]
@@ -371,16 +393,15 @@ resource-specific checks. See the README for more details.
 		if err != nil {
 			panic(err)
 		}
-		resources, err := tForLines.GetSection(cft.Resources)
+		modelForLines, err := tForLines.Model()
 		if err != nil {
 			panic(err)
 		}
-		for i := 0; i < len(resources.Content); i += 2 {
-			logicalId := resources.Content[i].Value
-			lineNum := resources.Content[i].Line
-			lineNums[logicalId] = lineNum
+		for logicalId, r := range modelForLines.Resources {
+			lineNums[logicalId] = r.Line()
 		}
 
+		_, pkg.Params = dc.CombineConfig(nil, params, configFilePath)
 		source, err := pkg.File(fn)
 		if err != nil {
 			panic(err)
@@ -425,6 +446,14 @@ resource-specific checks. See the README for more details.
 			panic(err)
 		}
 
+		// Expand Fn::ForEach so that each resource it makes is checked
+		if langext.Uses(source) {
+			source, err = langext.Expand(source, langext.Options{Config: dc})
+			if err != nil {
+				panic(err)
+			}
+		}
+
 		// Load the plugin if a path was provided
 		if pluginPath != "" {
 			config.Debugf("pluginPath: %s", pluginPath)
@@ -465,6 +494,7 @@ func init() {
 	Cmd.Flags().StringSliceVar(&fc.Ignore, "ignore", []string{}, "Resource types and specific codes to ignore, separated by commas, for example, AWS::S3::Bucket,F0002")
 	Cmd.Flags().StringVar(&pluginPath, "plugin", "", "Path to a forecast plugin .so")
 	Cmd.Flags().BoolVar(&pluginOnly, "plugin-only", false, "If set, none of the built in prediction functions will be run")
+	Cmd.Flags().StringVar(&outputFormat, "output-format", TEXT, "The format for the results: text, json, sarif, or junit")
 
 	// If you want to add a prediction for a type that is not already covered, add it here
 	// The function must return a Forecast struct
@@ -0,0 +1,282 @@
+package forecast
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+
+	"github.com/aws-cloudformation/rain/internal/config"
+	fc "github.com/aws-cloudformation/rain/plugins/forecast"
+)
+
+// Output formats for --output-format
+const (
+	TEXT  = "text"
+	JSON  = "json"
+	SARIF = "sarif"
+	JUNIT = "junit"
+)
+
+var outputFormats = []string{TEXT, JSON, SARIF, JUNIT}
+
+// Severities for checks in machine readable output.
+// Forecast checks are either failures, or passes that are
+// only written when --all is set.
+const (
+	severityError = "error"
+	severityPass  = "none"
+)
+
+func severity(c fc.Check) string {
+	if c.Pass {
+		return severityPass
+	}
+	return severityError
+}
+
+// writeReport writes the forecast for the template at path in the requested format.
+// Passed checks are only included if includePassed is true, except for JUnit,
+// which always reports every check as a test case.
+func writeReport(w io.Writer, format string, path string,
+	forecast fc.Forecast, includePassed bool) error {
+
+	path = filepath.ToSlash(path)
+
+	checks := slices.Clone(forecast.Failed)
+	if includePassed || format == JUNIT {
+		checks = append(checks, forecast.Passed...)
+	}
+
+	switch format {
+	case JSON:
+		return writeJSON(w, path, forecast, checks)
+	case SARIF:
+		return writeSARIF(w, path, checks)
+	case JUNIT:
+		return writeJUnit(w, path, forecast, checks)
+	}
+	return fmt.Errorf("unknown output format %s, expected one of %v", format, outputFormats)
+}
+
+type jsonCheck struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Code      string `json:"code"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+	TypeName  string `json:"typeName"`
+	LogicalId string `json:"logicalId"`
+	Pass      bool   `json:"pass"`
+}
+
+type jsonReport struct {
+	File    string      `json:"file"`
+	Checked int         `json:"checked"`
+	Failed  int         `json:"failed"`
+	Checks  []jsonCheck `json:"checks"`
+}
+
+func writeJSON(w io.Writer, path string, forecast fc.Forecast, checks []fc.Check) error {
+	report := jsonReport{
+		File:    path,
+		Checked: forecast.GetNumChecked(),
+		Failed:  forecast.GetNumFailed(),
+		Checks:  make([]jsonCheck, 0),
+	}
+	for _, c := range checks {
+		report.Checks = append(report.Checks, jsonCheck{
+			File:      path,
+			Line:      c.Line,
+			Code:      c.Code,
+			Severity:  severity(c),
+			Message:   c.Detail,
+			TypeName:  c.TypeName,
+			LogicalId: c.LogicalId,
+			Pass:      c.Pass,
+		})
+	}
+	return encodeJSON(w, report)
+}
+
+// The SARIF types below only cover the parts of the 2.1.0 spec that we use.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id string `json:"id"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleId    string          `json:"ruleId"`
+	Kind      string          `json:"kind"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+func writeSARIF(w io.Writer, path string, checks []fc.Check) error {
+	rules := make([]sarifRule, 0)
+	results := make([]sarifResult, 0)
+	for _, c := range checks {
+		if !slices.ContainsFunc(rules, func(r sarifRule) bool { return r.Id == c.Code }) {
+			rules = append(rules, sarifRule{Id: c.Code})
+		}
+
+		kind := "fail"
+		if c.Pass {
+			kind = "pass"
+		}
+
+		location := sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{Uri: path},
+		}
+		// SARIF lines start at 1, so leave the region out if we don't know it
+		if c.Line > 0 {
+			location.Region = &sarifRegion{StartLine: c.Line}
+		}
+
+		results = append(results, sarifResult{
+			RuleId:    c.Code,
+			Kind:      kind,
+			Level:     severity(c),
+			Message:   sarifMessage{Text: fmt.Sprintf("%s %s - %s", c.TypeName, c.LogicalId, c.Detail)},
+			Locations: []sarifLocation{{PhysicalLocation: location}},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "rain forecast",
+				Version:        config.VERSION,
+				InformationUri: "https://github.com/aws-cloudformation/rain",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+	return encodeJSON(w, log)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func writeJUnit(w io.Writer, path string, forecast fc.Forecast, checks []fc.Check) error {
+	suite := junitTestSuite{
+		Name:      path,
+		Tests:     forecast.GetNumChecked(),
+		Failures:  forecast.GetNumFailed(),
+		TestCases: make([]junitTestCase, 0),
+	}
+	for _, c := range checks {
+		tc := junitTestCase{
+			Name:      fmt.Sprintf("%s %s", c.Code, c.LogicalId),
+			Classname: c.TypeName,
+			File:      path,
+			Line:      c.Line,
+		}
+		if !c.Pass {
+			tc.Failure = &junitFailure{
+				Message: c.Detail,
+				Type:    c.Code,
+				Text:    fmt.Sprintf("%s:%d: %s %s - %s", path, c.Line, c.TypeName, c.LogicalId, c.Detail),
+			}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	suites := junitTestSuites{
+		Suites:   []junitTestSuite{suite},
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func encodeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
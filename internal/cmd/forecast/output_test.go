@@ -0,0 +1,102 @@
+package forecast
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	fc "github.com/aws-cloudformation/rain/plugins/forecast"
+)
+
+func testForecast() fc.Forecast {
+	input := fc.PredictionInput{}
+	input.TypeName = "AWS::S3::Bucket"
+	input.LogicalId = "MyBucket"
+	forecast := fc.MakeForecast(&input)
+	forecast.Add(FG001, false, "Resource with this name already exists", 12)
+	forecast.Add(F0002, true, "Bucket policy is valid", 12)
+	return forecast
+}
+
+func TestOutputJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeReport(&buf, JSON, "dir/t.yaml", testForecast(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	var report jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked != 2 || report.Failed != 1 || len(report.Checks) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	c := report.Checks[0]
+	if c.File != "dir/t.yaml" || c.Line != 12 || c.Code != FG001 || c.Severity != "error" ||
+		c.Message != "Resource with this name already exists" || c.LogicalId != "MyBucket" {
+		t.Errorf("unexpected check: %+v", c)
+	}
+}
+
+func TestOutputSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeReport(&buf, SARIF, "t.yaml", testForecast(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected log: %+v", log)
+	}
+	results := log.Runs[0].Results
+	if len(results) != 2 || len(log.Runs[0].Tool.Driver.Rules) != 2 {
+		t.Fatalf("expected 2 results and rules, got %+v", log.Runs[0])
+	}
+	r := results[0]
+	if r.RuleId != FG001 || r.Level != "error" || r.Kind != "fail" ||
+		r.Locations[0].PhysicalLocation.Region.StartLine != 12 ||
+		r.Locations[0].PhysicalLocation.ArtifactLocation.Uri != "t.yaml" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if results[1].Kind != "pass" || results[1].Level != "none" {
+		t.Errorf("unexpected pass result: %+v", results[1])
+	}
+}
+
+func TestOutputJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeReport(&buf, JUNIT, "t.yaml", testForecast(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(buf.String(), "<?xml") {
+		t.Errorf("expected an xml header")
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatal(err)
+	}
+	if suites.Tests != 2 || suites.Failures != 1 || len(suites.Suites[0].TestCases) != 2 {
+		t.Fatalf("unexpected suites: %+v", suites)
+	}
+	tc := suites.Suites[0].TestCases[0]
+	if tc.Failure == nil || tc.Failure.Type != FG001 || tc.Line != 12 {
+		t.Errorf("unexpected test case: %+v", tc)
+	}
+	if suites.Suites[0].TestCases[1].Failure != nil {
+		t.Errorf("passed check should not have a failure")
+	}
+}
+
+func TestOutputUnknown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeReport(&buf, "csv", "t.yaml", testForecast(), false); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
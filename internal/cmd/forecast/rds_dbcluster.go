@@ -157,7 +157,7 @@ func CheckRDSDBCluster(input fc.PredictionInput) fc.Forecast {
 							config.Debugf("instanceVersion: %s", node.ToSJson(evNode))
 
 							// Resolve refs first
-							resolveParamRefs(propName, evNode, input.Dc, instanceProps)
+							resolveNode(evNode, input)
 
 							config.Debugf("instanceVersion after: %s", node.ToSJson(evNode))
 
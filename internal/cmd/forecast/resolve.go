@@ -3,65 +3,79 @@ package forecast
 import (
 	"strings"
 
+	"github.com/aws-cloudformation/rain/cft/eval"
 	"github.com/aws-cloudformation/rain/internal/aws/ssm"
 	"github.com/aws-cloudformation/rain/internal/config"
 	"github.com/aws-cloudformation/rain/internal/s11n"
 	"github.com/aws-cloudformation/rain/plugins/deployconfig"
 	fc "github.com/aws-cloudformation/rain/plugins/forecast"
+	"github.com/aws/smithy-go/ptr"
 	"gopkg.in/yaml.v3"
 )
 
-// recurse over properties to resolve Refs
-func resolveParamRefs(name string, prop *yaml.Node, dc *deployconfig.DeployConfig, parent *yaml.Node) {
-	if name == "Ref" && prop.Kind == yaml.ScalarNode {
-
-		for _, param := range dc.Params {
-			if *param.ParameterKey == prop.Value {
-				if parent.Kind == yaml.MappingNode {
-
-					var val string
-
-					// Resolve SSM types like AWS::SSM::Parameter::Value<AWS::EC2::Image::Id>
-					if param.ResolvedValue != nil {
-						// Will this ever not be nil? Maybe for updates?
-						val = *param.ResolvedValue
-					} else {
-						val = *param.ParameterValue
-						// We don't have the param type here...
-						if strings.HasPrefix(val, "/aws/service/") {
-							// Assume this is an SSM parameter
-							resolved, err := ssm.GetParameter(val)
-							if err != nil {
-								config.Debugf("could not get SSM parameter: %v", err)
-							} else {
-								val = resolved
-							}
-						}
-					}
-
-					// Replace the parent Mapping node
-					*parent = yaml.Node{Kind: yaml.ScalarNode, Value: val}
+// resolveSSMParams returns a copy of dc with SSM parameter values resolved,
+// for types like AWS::SSM::Parameter::Value<AWS::EC2::Image::Id>
+func resolveSSMParams(dc *deployconfig.DeployConfig) *deployconfig.DeployConfig {
+	if dc == nil {
+		return nil
+	}
+	retval := &deployconfig.DeployConfig{Tags: dc.Tags}
+	for _, param := range dc.Params {
+		// Will ResolvedValue ever not be nil? Maybe for updates?
+		if param.ResolvedValue == nil && param.ParameterValue != nil {
+			val := *param.ParameterValue
+			// We don't have the param type here...
+			if strings.HasPrefix(val, "/aws/service/") {
+				// Assume this is an SSM parameter
+				resolved, err := ssm.GetParameter(val)
+				if err != nil {
+					config.Debugf("could not get SSM parameter: %v", err)
+				} else {
+					param.ResolvedValue = ptr.String(resolved)
 				}
-				// would it be any other Kind?
 			}
 		}
+		retval.Params = append(retval.Params, param)
+	}
+	return retval
+}
 
-	} else if prop.Kind == yaml.MappingNode {
-		for i := 0; i < len(prop.Content); i += 2 {
-			resolveParamRefs(prop.Content[i].Value, prop.Content[i+1], dc, prop)
-		}
-	} else if prop.Kind == yaml.SequenceNode {
-		for _, p := range prop.Content {
-			resolveParamRefs("", p, dc, prop)
+// resolveNode evaluates intrinsic functions in n, in place, as far as
+// they can be resolved without any deployed resources
+func resolveNode(n *yaml.Node, input fc.PredictionInput) {
+	pseudo := make(map[string]string)
+	for k, v := range map[string]string{
+		"AWS::Partition": input.Env.Partition,
+		"AWS::Region":    input.Env.Region,
+		"AWS::AccountId": input.Env.Account,
+		"AWS::StackName": input.StackName,
+	} {
+		if v != "" {
+			pseudo[k] = v
 		}
 	}
+
+	e, err := eval.New(input.Source, eval.Options{
+		Config: resolveSSMParams(input.Dc),
+		Pseudo: pseudo,
+	})
+	if err != nil {
+		config.Debugf("unable to create evaluator: %v", err)
+		return
+	}
+	resolved, err := e.Node(n)
+	if err != nil {
+		config.Debugf("unable to resolve %s: %v", input.LogicalId, err)
+		return
+	}
+	if resolved != nil {
+		*n = *resolved
+	}
 }
 
 func resolveRefs(input fc.PredictionInput) {
 	_, props, _ := s11n.GetMapValue(input.Resource, "Properties")
 	if props != nil {
-		for i := 0; i < len(props.Content); i += 2 {
-			resolveParamRefs(props.Content[i].Value, props.Content[i+1], input.Dc, props)
-		}
+		resolveNode(props, input)
 	}
 }
@@ -0,0 +1,101 @@
+// Package lint implements the rain lint command, which checks a template
+// offline against the resource schemas that are embedded in rain
+package lint
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aws-cloudformation/rain/cft/lint"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"github.com/aws-cloudformation/rain/internal/console"
+	"github.com/aws-cloudformation/rain/internal/dc"
+	"github.com/aws-cloudformation/rain/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var ignore []string
+var params []string
+var configFilePath string
+
+// Cmd is the lint command's entrypoint
+var Cmd = &cobra.Command{
+	Use:   "lint <template>",
+	Short: "Check a template for errors without calling AWS",
+	Long: `Checks a template for errors that would cause a deployment to fail.
+
+The lint command does not make any calls to AWS. Resource properties are checked
+against the registry schemas that are embedded in rain, so some recently released
+properties might be reported as unknown.
+
+Each finding has a rule code that can be passed to --ignore:
+
+  L0001  WARNING  The resource type has no schema
+  L0002  ERROR    A required property is missing
+  L0003  ERROR    A property is not defined in the schema
+  L0004  ERROR    A value is not one of the allowed values
+  L0005  ERROR    A value does not match the pattern in the schema
+  L0006  ERROR    A value is the wrong type
+  L0007  ERROR    A Ref, GetAtt, Sub or Condition refers to something that is not in the template
+  L0008  ERROR    A read-only property is set
+  L0009  ERROR    A value is too short or too long
+  L0010  ERROR    A parameter's type does not match the property it is used for
+  L0011  ERROR    A parameter's allowed or default value is not valid for a property
+  L0012  ERROR    A parameter value is not allowed by the parameter's AllowedValues or AllowedPattern
+  L0013  ERROR    Resources depend on each other in a cycle, through Ref, GetAtt, Sub or DependsOn
+
+When a property is set with Ref to a parameter, the parameter's type, AllowedValues,
+Default, and the value supplied with --params or --config are checked against the
+property. Other intrinsic functions are not checked. The template is not packaged,
+so run rain pkg first if it uses modules.
+
+The command exits with a non-zero status if there are any errors.
+`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		fn := args[0]
+
+		t, err := parse.File(fn)
+		if err != nil {
+			panic(ui.Errorf(err, "unable to parse template '%s'", fn))
+		}
+
+		_, combinedParameters := dc.CombineConfig([]string{}, params, configFilePath)
+
+		findings, err := lint.Template(t, lint.Options{
+			Ignore: ignore,
+			Params: combinedParameters,
+		})
+		if err != nil {
+			panic(ui.Errorf(err, "unable to lint template '%s'", fn))
+		}
+
+		for _, f := range findings {
+			if f.Severity == lint.Error {
+				fmt.Println(console.Red(f.String()))
+			} else {
+				fmt.Println(console.Yellow(f.String()))
+			}
+		}
+
+		errors := lint.Errors(findings)
+		if errors > 0 {
+			fmt.Println()
+			fmt.Println(console.Red(fmt.Sprintf("%s has %d errors", fn, errors)))
+			os.Exit(1)
+		}
+
+		if len(findings) == 0 {
+			fmt.Println(console.Green(fmt.Sprintf("%s looks good", fn)))
+		}
+	},
+}
+
+func init() {
+	Cmd.Flags().BoolVar(&config.Debug, "debug", false, "Output debugging information")
+	Cmd.Flags().StringSliceVar(&params, "params", []string{}, "set parameter values; use the format key1=value1,key2=value2")
+	Cmd.Flags().StringVarP(&configFilePath, "config", "c", "", "YAML or JSON file to set parameters")
+	Cmd.Flags().StringSliceVar(&ignore, "ignore", []string{}, "Resource types and specific codes to ignore, separated by commas, for example, AWS::S3::Bucket,L0003")
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/aws-cloudformation/rain/cft/visitor"
 	"github.com/aws-cloudformation/rain/internal/config"
 	"github.com/aws-cloudformation/rain/internal/node"
-	"github.com/aws-cloudformation/rain/internal/s11n"
 	"gopkg.in/yaml.v3"
 )
 
@@ -15,26 +14,25 @@ import (
 // If there are any, it replaces the Fn::ImportValue nodes with the value.
 // Otherwise the template is returned as is
 func mergeOutputImports(t cft.Template) (cft.Template, error) {
-	outputs, err := t.GetSection(cft.Outputs)
-	if err != nil {
+	if _, err := t.GetSection(cft.Outputs); err != nil {
 		// This is expected if the template has no Outputs
 		config.Debugf("mergeOutputImports has no outputs: %v", err)
 		return t, nil
 	}
+	model, err := t.Model()
+	if err != nil {
+		return t, err
+	}
 	exportMap := make(map[string]*yaml.Node)
-	for i := 0; i < len(outputs.Content); i += 2 {
-		name := outputs.Content[i].Value
-		val := outputs.Content[i+1]
+	for _, name := range model.Names(cft.Outputs) {
+		output := model.Outputs[name]
 
-		config.Debugf("Checking %s: %s", name, node.ToSJson(val))
+		config.Debugf("Checking %s: %s", name, node.ToSJson(output.Node))
 
-		_, exp, _ := s11n.GetMapValue(val, "Export")
-		if exp != nil {
-			_, exportName, _ := s11n.GetMapValue(exp, "Name")
-			if exportName != nil {
+		if output.Get("Export") != nil {
+			if exportName := output.Export(); exportName != nil {
 				// We found an export. Store the value for later when we go look for Fn::ImportValue
-				_, exportVal, _ := s11n.GetMapValue(val, "Value")
-				if exportVal != nil {
+				if exportVal := output.Value(); exportVal != nil {
 					exportMap[exportName.Value] = exportVal
 				} else {
 					config.Debugf("Unexpected: %s does not have an Export Value", name)
@@ -8,6 +8,7 @@ import (
 	cftpkg "github.com/aws-cloudformation/rain/cft/pkg"
 	"github.com/aws-cloudformation/rain/internal/config"
 	"github.com/aws-cloudformation/rain/internal/console/spinner"
+	"github.com/aws-cloudformation/rain/internal/dc"
 	"github.com/aws-cloudformation/rain/internal/node"
 	"github.com/aws-cloudformation/rain/internal/ui"
 	"github.com/spf13/cobra"
@@ -15,6 +16,12 @@ import (
 
 var outFn = ""
 var dataModel bool
+var params []string
+var configFilePath string
+var valuesFile string
+var reveal bool
+var imageRegistry string
+var dryRun bool
 
 // Experimental is an optional argument that enables experimental features
 var Experimental bool
@@ -48,6 +55,9 @@ You may use the following, rain-specific directives in templates packaged with "
     Format: Uri|Http           Specify which format rain pkg should return the S3 location as.
                                Do not specify this property if you supply BucketProperty and KeyProperty.
                                The default Format is "Uri".
+    Run: <script>              a script to run before uploading, which builds <path>
+    Source: <dir>              the directory that Run builds <path> from. Without Source,
+                               Run is run every time you package the template.
 
   !Rain::Module <url>          Supply a URL to a rain module, which is similar to a CloudFormation module, 
                                but allows for type inheritance. One of the resources in the module yaml file 
@@ -56,6 +66,63 @@ You may use the following, rain-specific directives in templates packaged with "
                                of the module can be used to define additional properties for the extension.
                                This is an experimental directive that must be enabled by adding the 
                                --experimental arg on the command line.
+
+  !Rain::Template <path>       Renders the file at <path> with Go text/template, and inserts the output
+                               into the template as YAML. The file can use {{ .Constants.Name }} for
+                               Rain Constants, and {{ .Params.Name }} for parameter values from
+                               --params and --config. Use the json and join functions to write lists.
+
+  !Rain::Ssm <name>            Reads the SSM parameter <name> and inserts its value into the template as a string
+
+  !Rain::Secret <id>           Reads the Secrets Manager secret <id> and inserts its value into the template
+                               as a string. Secret values are shown as **** unless you set --reveal.
+                               Other commands, like rain deploy, insert a {{resolve:secretsmanager:<id>}}
+                               dynamic reference instead, so the value is not stored with the stack.
+
+  !Rain::Secret <object>       supply an object with the following properties:
+    SecretId: <id>             the secret to read
+    Key: <key>                 insert the value of one key of a secret that is a JSON object
+
+  !Rain::Image <path>          Builds a container image from the directory at <path>, tags it with a hash of
+                               the directory, pushes it to the ECR repository that rain bootstrap creates,
+                               and embeds the image URI into the template as a string. Images that are
+                               already in the repository are not built again, unless you set --no-cache.
+
+  !Rain::Image <object>        supply an object with the following properties:
+    Path: <path>               the directory to build
+    Dockerfile: <file>         the Dockerfile to use, relative to <path>
+    Platform: <platform>       the platform to build for, like linux/arm64
+    BuildArgs: <map>           build arguments
+
+  !Rain::Template <object>     supply an object with the following properties:
+    Path: <path>               the file to render
+    Data: <map>|<path>         values for {{ .Data }}, or the path to a YAML or JSON file of values
+
+Assets are recorded in a cache in .rain/cache/assets next to the template, keyed by a hash of
+their files and their Run script. Assets that have not changed since they were last uploaded
+are not uploaded again. Run scripts are only skipped if you set Source, since rain can't
+tell which files a script reads. The hash is also the end of the S3 key, so keys only
+change when the asset changes. Use --no-cache to build and upload every asset.
+
+With --dry-run, rain pkg does not make any calls to AWS. Assets are zipped and written to
+--dry-run-dir instead of being uploaded, and the template gets placeholder S3 URIs like
+s3://rain-artifacts-dry-run/<hash>, which only change when the asset changes. Images are
+tagged with their hash but not built, and !Rain::Ssm and !Rain::Secret become dynamic
+references like {{resolve:ssm:<name>}}, unless you set --values.
+
+With --expand-language-extensions, Fn::ForEach loops and the Fn::Length, Fn::ToJsonString
+and Fn::FindInMap DefaultValue functions of the AWS::LanguageExtensions transform are
+expanded in the packaged template, so you can see the resources that they make. Anything
+that depends on a parameter is only expanded if you supply its value with --params or
+--config. Otherwise it is left for the transform, which is kept in the template.
+
+With --values <file>, !Rain::Ssm and !Rain::Secret read values from a local YAML or JSON file
+instead of your AWS account, which is useful for tests and offline builds:
+
+  Parameters:
+    /app/vpc-id: vpc-123
+  Secrets:
+    app/db: '{"password": "example"}'
 `,
 	Args:                  cobra.ExactArgs(1),
 	Aliases:               []string{"package"},
@@ -64,6 +131,21 @@ You may use the following, rain-specific directives in templates packaged with "
 		fn := args[0]
 
 		cftpkg.Experimental = Experimental
+		cftpkg.DryRun = dryRun
+		cftpkg.ResolveSecrets = true
+		_, cftpkg.Params = dc.CombineConfig(nil, params, configFilePath)
+
+		if imageRegistry != "" {
+			cftpkg.Images = &cftpkg.RegistryPusher{Repository: imageRegistry}
+		}
+
+		if valuesFile != "" {
+			values, err := cftpkg.NewFileValues(valuesFile)
+			if err != nil {
+				panic(ui.Errorf(err, "unable to read values file '%s'", valuesFile))
+			}
+			cftpkg.Values = values
+		}
 
 		spinner.Push(fmt.Sprintf("Packaging template '%s'", fn))
 		packaged, err := cftpkg.File(fn)
@@ -72,6 +154,10 @@ You may use the following, rain-specific directives in templates packaged with "
 		}
 		spinner.Pop()
 
+		if !reveal {
+			cftpkg.Redact(packaged.Node)
+		}
+
 		var out string
 		if dataModel {
 			out = node.ToJson(packaged.Node)
@@ -94,4 +180,14 @@ func init() {
 	Cmd.Flags().BoolVar(&dataModel, "datamodel", false, "Output the go yaml data model")
 	Cmd.Flags().StringVar(&format.NodeStyle, "node-style", "", format.NodeStyleDocs)
 	Cmd.Flags().BoolVar(&cftpkg.NoAnalytics, "no-analytics", false, "Do not include analytics in Metadata")
+	Cmd.Flags().StringSliceVar(&params, "params", []string{}, "set parameter values for !Rain::Template; use the format key1=value1,key2=value2")
+	Cmd.Flags().StringVarP(&configFilePath, "config", "c", "", "YAML or JSON file to set parameters for !Rain::Template")
+	Cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Package without calling AWS, writing assets to --dry-run-dir and using placeholder S3 URIs")
+	Cmd.Flags().StringVar(&cftpkg.DryRunDir, "dry-run-dir", "", "The directory that --dry-run writes assets to (default is .rain/dry-run next to the template)")
+	Cmd.Flags().BoolVar(&cftpkg.NoCache, "no-cache", false, "Build and upload every asset and image, instead of reusing ones that have not changed")
+	Cmd.Flags().StringVar(&cftpkg.ImageBuilder, "image-builder", "docker", "The command that builds and pushes images for !Rain::Image: docker, podman or buildah")
+	Cmd.Flags().StringVar(&imageRegistry, "image-registry", "", "Push images to this repository, like localhost:5000/rain, instead of ECR")
+	Cmd.Flags().StringVar(&valuesFile, "values", "", "YAML or JSON file of values for !Rain::Ssm and !Rain::Secret, instead of reading them from AWS")
+	Cmd.Flags().BoolVar(&reveal, "reveal", false, "Show the values of secrets from !Rain::Secret instead of ****")
+	Cmd.Flags().BoolVar(&cftpkg.ExpandLanguageExtensions, "expand-language-extensions", false, "Expand Fn::ForEach and the other AWS::LanguageExtensions functions")
 }
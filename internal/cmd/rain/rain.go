@@ -18,13 +18,18 @@ import (
 	rainfmt "github.com/aws-cloudformation/rain/internal/cmd/fmt"
 	"github.com/aws-cloudformation/rain/internal/cmd/forecast"
 	"github.com/aws-cloudformation/rain/internal/cmd/info"
+	"github.com/aws-cloudformation/rain/internal/cmd/lint"
 	"github.com/aws-cloudformation/rain/internal/cmd/logs"
 	"github.com/aws-cloudformation/rain/internal/cmd/ls"
 	"github.com/aws-cloudformation/rain/internal/cmd/merge"
 	"github.com/aws-cloudformation/rain/internal/cmd/module"
 	"github.com/aws-cloudformation/rain/internal/cmd/pkg"
+	"github.com/aws-cloudformation/rain/internal/cmd/refactor"
+	"github.com/aws-cloudformation/rain/internal/cmd/render"
 	"github.com/aws-cloudformation/rain/internal/cmd/rm"
+	"github.com/aws-cloudformation/rain/internal/cmd/split"
 	"github.com/aws-cloudformation/rain/internal/cmd/stackset"
+	"github.com/aws-cloudformation/rain/internal/cmd/stats"
 	"github.com/aws-cloudformation/rain/internal/cmd/tree"
 	"github.com/aws-cloudformation/rain/internal/cmd/watch"
 	"github.com/aws-cloudformation/rain/internal/console"
@@ -96,11 +101,16 @@ func init() {
 	// Template commands
 	addCommand(templateGroup, true, true, bootstrap.Cmd)
 	addCommand(templateGroup, true, false, build.Cmd)
-	addCommand(templateGroup, false, false, diff.Cmd)
+	addCommand(templateGroup, true, true, diff.Cmd)
 	addCommand(templateGroup, false, false, rainfmt.Cmd)
+	addCommand(templateGroup, false, false, lint.Cmd)
 	addCommand(templateGroup, false, false, merge.Cmd)
+	addCommand(templateGroup, false, false, refactor.Cmd)
+	addCommand(templateGroup, false, false, split.Cmd)
+	addCommand(templateGroup, true, true, stats.Cmd)
 	addCommand(templateGroup, true, true, pkg.Cmd)
-	addCommand(templateGroup, false, false, tree.Cmd)
+	addCommand(templateGroup, false, false, render.Cmd)
+	addCommand(templateGroup, true, false, tree.Cmd)
 	addCommand(templateGroup, true, false, forecast.Cmd)
 	addCommand(templateGroup, true, false, module.Cmd)
 
@@ -0,0 +1,132 @@
+package refactor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/cft/refactor"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"github.com/aws-cloudformation/rain/internal/console"
+	"github.com/aws-cloudformation/rain/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var moduleName string
+
+var notAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// defaultModuleName makes a logical id from the module's file name,
+// so storage-buckets.yaml is StorageBuckets
+func defaultModuleName(fileName string) string {
+	base := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	name := ""
+	for _, word := range notAlphanumeric.Split(base, -1) {
+		if word != "" {
+			name += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return name
+}
+
+// ExtractCmd is the extract command's entrypoint
+var ExtractCmd = &cobra.Command{
+	Use:   "extract <template> <module> <resource>...",
+	Short: "Move resources from a template into a new Rain module",
+	Long: `Moves the named resources from a template into a new Rain module file, and replaces
+them with a single resource with Type: !Rain::Module. This makes it possible to break up
+a large template a few resources at a time.
+
+The module resource's logical id is set with --name, or made from the module's file name.
+When the template is packaged, the module's resources are named with the module resource's
+logical id followed by their name in the module, so other resources in the template refer
+to them with Ref and GetAtt in the same way as before. If all of the resources already start
+with the module resource's logical id, the packaged template has the same logical ids as the
+original. Otherwise, the resources are renamed, and would be replaced in a deployed stack
+unless you use a stack refactor. See rain refactor rename --help.
+
+References from the extracted resources to parameters and other resources in the template
+become module Parameters, which are set in the module resource's Properties. GetAtts become
+parameters like RoleArn for Role.Arn. DependsOn on resources that stay in the template moves
+to the module resource's Overrides. Resources that use Fn::Sub with a list of variables
+can't be extracted yet, since rain pkg does not resolve those inside modules.
+
+The module file must not already exist. The template is written to stdout, or back to the
+file with --write. Packaging a template with a module requires rain pkg -x.`,
+	Args:                  cobra.MinimumNArgs(3),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		fileName, moduleFile, names := args[0], args[1], args[2:]
+
+		if moduleName == "" {
+			moduleName = defaultModuleName(moduleFile)
+		}
+
+		if _, err := os.Stat(moduleFile); err == nil {
+			panic(fmt.Errorf("'%s' already exists", moduleFile))
+		}
+
+		source, err := os.ReadFile(fileName)
+		if err != nil {
+			panic(ui.Errorf(err, "unable to read '%s'", fileName))
+		}
+
+		t, err := parse.String(string(source))
+		if err != nil {
+			panic(ui.Errorf(err, "unable to parse template '%s'", fileName))
+		}
+
+		// The module's location, relative to the template
+		rel, err := filepath.Rel(filepath.Dir(fileName), moduleFile)
+		if err != nil {
+			panic(ui.Errorf(err, "unable to find '%s' from '%s'", moduleFile, fileName))
+		}
+		rel = filepath.ToSlash(rel)
+		if !strings.HasPrefix(rel, ".") {
+			rel = "./" + rel
+		}
+
+		module, renamed, err := refactor.Extract(t, names, moduleName, rel)
+		if err != nil {
+			panic(ui.Errorf(err, "unable to extract resources from '%s'", fileName))
+		}
+
+		jsonFlag := strings.HasPrefix(strings.TrimSpace(string(source)), "{")
+
+		output := format.String(module, format.Options{JSON: jsonFlag, Unsorted: true})
+		if err := os.WriteFile(moduleFile, []byte(output), 0644); err != nil {
+			panic(ui.Errorf(err, "unable to write '%s'", moduleFile))
+		}
+
+		old := make([]string, 0)
+		for name := range renamed {
+			old = append(old, name)
+		}
+		sort.Strings(old)
+		for _, name := range old {
+			fmt.Fprintln(os.Stderr, console.Yellow(fmt.Sprintf(
+				"%s will be %s when the template is packaged", name, renamed[name])))
+		}
+
+		output = format.String(t, format.Options{JSON: jsonFlag, Unsorted: true})
+		if writeFlag {
+			if err := os.WriteFile(fileName, []byte(output), 0644); err != nil {
+				panic(ui.Errorf(err, "unable to write '%s'", fileName))
+			}
+			fmt.Println(console.Green(fmt.Sprintf("Moved %d resources from '%s' to '%s'", len(names), fileName, moduleFile)))
+		} else {
+			fmt.Print(output)
+		}
+	},
+}
+
+func init() {
+	ExtractCmd.Flags().StringVar(&moduleName, "name", "", "The logical id of the module resource")
+	ExtractCmd.Flags().BoolVarP(&writeFlag, "write", "w", false, "Write the template back to the file rather than to stdout")
+	ExtractCmd.Flags().BoolVar(&config.Debug, "debug", false, "Output debugging information")
+}
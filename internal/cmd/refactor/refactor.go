@@ -0,0 +1,18 @@
+package refactor
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// Cmd is the refactor command's entrypoint
+var Cmd = &cobra.Command{
+	Use:   "refactor <command>",
+	Short: "Make structural changes to a template",
+	Long: `The rain refactor commands change a template while keeping every reference
+to the elements that change intact.`,
+}
+
+func init() {
+	Cmd.AddCommand(RenameCmd)
+	Cmd.AddCommand(ExtractCmd)
+}
@@ -0,0 +1,113 @@
+package refactor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/refactor"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"github.com/aws-cloudformation/rain/internal/console"
+	"github.com/aws-cloudformation/rain/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var writeFlag bool
+var stackName string
+var mappingFile string
+
+// resourceLocation and resourceMapping are the format of the
+// --resource-mappings argument to aws cloudformation create-stack-refactor
+type resourceLocation struct {
+	StackName         string
+	LogicalResourceId string
+}
+
+type resourceMapping struct {
+	Source      resourceLocation
+	Destination resourceLocation
+}
+
+// RenameCmd is the rename command's entrypoint
+var RenameCmd = &cobra.Command{
+	Use:   "rename <template> <old> <new>",
+	Short: "Rename a resource, parameter, condition or mapping",
+	Long: `Renames the logical id of a resource, parameter, condition or mapping in a template,
+and rewrites every reference to it: Ref, Fn::GetAtt in both forms, variables in Fn::Sub,
+DependsOn, Condition, Fn::If, Fn::FindInMap and the parameter groups and labels in
+AWS::CloudFormation::Interface. Only the renamed names change, so comments, quotes,
+indentation and the rest of the template's formatting are kept.
+
+The renamed template is written to stdout, or back to the file with --write.
+
+Renaming a deployed resource would normally replace it. With --stack and --mapping <file>,
+rain also writes the resource mapping for a CloudFormation stack refactor, which renames
+the resource in the stack without replacing it:
+
+  aws cloudformation create-stack-refactor \
+    --stack-definitions StackName=<stack>,TemplateBody@=file://<template> \
+    --resource-mappings file://<file>`,
+	Args:                  cobra.ExactArgs(3),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		fileName, oldName, newName := args[0], args[1], args[2]
+
+		if (stackName == "") != (mappingFile == "") {
+			panic("--stack and --mapping must be used together")
+		}
+
+		source, err := os.ReadFile(fileName)
+		if err != nil {
+			panic(ui.Errorf(err, "unable to read '%s'", fileName))
+		}
+
+		output, section, err := refactor.RenameSource(string(source), oldName, newName)
+		if err != nil {
+			panic(ui.Errorf(err, "unable to rename '%s' in '%s'", oldName, fileName))
+		}
+		config.Debugf("renamed %s in %s", oldName, section)
+
+		if mappingFile != "" {
+			if section != cft.Resources {
+				panic(fmt.Errorf("'%s' is not a resource, so it does not need a stack refactor", oldName))
+			}
+			writeMapping(oldName, newName)
+		}
+
+		if writeFlag {
+			if err := os.WriteFile(fileName, []byte(output), 0644); err != nil {
+				panic(ui.Errorf(err, "unable to write '%s'", fileName))
+			}
+			fmt.Println(console.Green(fmt.Sprintf("Renamed %s to %s in '%s'", oldName, newName, fileName)))
+		} else {
+			fmt.Print(output)
+		}
+	},
+}
+
+// writeMapping writes the stack refactor mapping for a renamed resource
+func writeMapping(oldName, newName string) {
+	mappings := []resourceMapping{
+		{
+			Source:      resourceLocation{StackName: stackName, LogicalResourceId: oldName},
+			Destination: resourceLocation{StackName: stackName, LogicalResourceId: newName},
+		},
+	}
+
+	out, err := json.MarshalIndent(mappings, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	if err := os.WriteFile(mappingFile, append(out, '\n'), 0644); err != nil {
+		panic(ui.Errorf(err, "unable to write '%s'", mappingFile))
+	}
+}
+
+func init() {
+	RenameCmd.Flags().BoolVarP(&writeFlag, "write", "w", false, "Write the output back to the file rather than to stdout")
+	RenameCmd.Flags().StringVar(&stackName, "stack", "", "The name of the deployed stack, for the stack refactor mapping")
+	RenameCmd.Flags().StringVar(&mappingFile, "mapping", "", "Write the stack refactor resource mapping to this file")
+	RenameCmd.Flags().BoolVar(&config.Debug, "debug", false, "Output debugging information")
+}
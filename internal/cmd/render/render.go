@@ -0,0 +1,144 @@
+// Package render implements the rain render command, which evaluates
+// a template locally to show what CloudFormation would see
+package render
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/eval"
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/internal/console"
+	"github.com/aws-cloudformation/rain/internal/dc"
+	"github.com/aws-cloudformation/rain/internal/ui"
+	"github.com/aws-cloudformation/rain/plugins/deployconfig"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go/ptr"
+	"github.com/spf13/cobra"
+)
+
+var params []string
+var configFilePath string
+var pseudoParams []string
+var region string
+var strict bool
+var jsonFlag bool
+var unsortedFlag bool
+
+// Cmd is the render command's entrypoint
+var Cmd = &cobra.Command{
+	Use:   "render <template>",
+	Short: "Show a template as CloudFormation would see it",
+	Long: `Evaluates a template locally, without making any calls to AWS, and prints the result.
+
+Conditions are evaluated, and resources and outputs with a false condition are removed.
+Refs to parameters are replaced with their values, and Fn::If, Fn::Sub, Fn::Join, 
+Fn::FindInMap and other intrinsic functions are evaluated where possible. Anything 
+that depends on a deployed resource, like Fn::GetAtt, is left in place.
+
+Parameter values are read from --params and --config in the same way as rain deploy.
+Parameters that are not supplied use their default values.
+
+The template is not packaged. Run rain pkg first if it uses modules or other Rain directives.
+`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		fn := args[0]
+
+		t, err := parse.File(fn)
+		if err != nil {
+			panic(ui.Errorf(err, "unable to parse template '%s'", fn))
+		}
+
+		_, combinedParameters := dc.CombineConfig([]string{}, params, configFilePath)
+
+		pseudo := PseudoParameters(region, dc.ListToMap("pseudo", pseudoParams))
+
+		rendered, err := Render(t, combinedParameters, pseudo, strict)
+		if err != nil {
+			panic(ui.Errorf(err, "unable to render template '%s'", fn))
+		}
+
+		fmt.Print(format.String(rendered, format.Options{
+			JSON:     jsonFlag,
+			Unsorted: unsortedFlag,
+		}))
+	},
+}
+
+// PseudoParameters returns pseudo parameter values based on the region
+// and any values supplied by the user. Names can be supplied with or
+// without the AWS:: prefix.
+func PseudoParameters(region string, supplied map[string]string) map[string]string {
+	retval := make(map[string]string)
+	if region != "" {
+		retval["AWS::Region"] = region
+		retval["AWS::URLSuffix"] = "amazonaws.com"
+		switch {
+		case strings.HasPrefix(region, "us-gov"):
+			retval["AWS::Partition"] = "aws-us-gov"
+		case strings.HasPrefix(region, "cn-"):
+			retval["AWS::Partition"] = "aws-cn"
+			retval["AWS::URLSuffix"] = "amazonaws.com.cn"
+		default:
+			retval["AWS::Partition"] = "aws"
+		}
+	}
+	for k, v := range supplied {
+		if !strings.HasPrefix(k, "AWS::") {
+			k = "AWS::" + k
+		}
+		retval[k] = v
+	}
+	return retval
+}
+
+// Render evaluates the template with the supplied parameter values
+func Render(t cft.Template, params map[string]string,
+	pseudo map[string]string, strict bool) (cft.Template, error) {
+
+	model, err := t.Model()
+	if err != nil {
+		return t, err
+	}
+
+	// Sort so that parameters are in a predictable order
+	keys := make([]string, 0)
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	config := &deployconfig.DeployConfig{}
+	for _, k := range keys {
+		if _, ok := model.Parameters[k]; !ok {
+			fmt.Fprintln(os.Stderr, console.Yellow(fmt.Sprintf("unknown parameter: %s", k)))
+			continue
+		}
+		config.Params = append(config.Params, types.Parameter{
+			ParameterKey:   ptr.String(k),
+			ParameterValue: ptr.String(params[k]),
+		})
+	}
+
+	return eval.Template(t, eval.Options{
+		Config: config,
+		Pseudo: pseudo,
+		Strict: strict,
+	})
+}
+
+func init() {
+	Cmd.Flags().StringSliceVar(&params, "params", []string{}, "set parameter values; use the format key1=value1,key2=value2")
+	Cmd.Flags().StringVarP(&configFilePath, "config", "c", "", "YAML or JSON file to set parameters")
+	Cmd.Flags().StringVarP(&region, "region", "r", "", "Region to use for AWS::Region, AWS::Partition and Fn::GetAZs")
+	Cmd.Flags().StringSliceVar(&pseudoParams, "pseudo", []string{}, "set pseudo parameter values; use the format AccountId=123456789012,StackName=foo")
+	Cmd.Flags().BoolVar(&strict, "strict", false, "Fail if anything in the template cannot be resolved")
+	Cmd.Flags().BoolVarP(&jsonFlag, "json", "j", false, "Output the template as JSON (default format: YAML)")
+	Cmd.Flags().BoolVarP(&unsortedFlag, "unsorted", "u", false, "Do not sort the template's properties")
+}
@@ -0,0 +1,59 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+)
+
+func TestRender(t *testing.T) {
+	source := `
+Parameters:
+  Env:
+    Type: String
+    AllowedValues: [dev, prod]
+Conditions:
+  IsProd: !Equals [!Ref Env, prod]
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Sub ${Env}-${AWS::Region}
+  Alarm:
+    Type: AWS::CloudWatch::Alarm
+    Condition: IsProd
+`
+	tmpl, err := parse.String(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pseudo := PseudoParameters("us-west-2", map[string]string{"AccountId": "123"})
+	if pseudo["AWS::AccountId"] != "123" || pseudo["AWS::Partition"] != "aws" {
+		t.Fatalf("unexpected pseudo parameters %v", pseudo)
+	}
+
+	dev, err := Render(tmpl, map[string]string{"Env": "dev"}, pseudo, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := format.String(dev, format.Options{})
+	if !strings.Contains(out, "BucketName: dev-us-west-2") || strings.Contains(out, "Alarm") {
+		t.Errorf("unexpected dev output:\n%s", out)
+	}
+
+	prod, err := Render(tmpl, map[string]string{"Env": "prod"}, pseudo, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = format.String(prod, format.Options{})
+	if !strings.Contains(out, "Alarm:") {
+		t.Errorf("unexpected prod output:\n%s", out)
+	}
+
+	if _, err := Render(tmpl, map[string]string{}, map[string]string{}, true); err == nil {
+		t.Errorf("expected an error in strict mode with no parameter value")
+	}
+}
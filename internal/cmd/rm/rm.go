@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/aws-cloudformation/rain/cft/parse"
 	"github.com/aws-cloudformation/rain/internal/aws/cfn"
@@ -23,6 +24,7 @@ var detach bool
 var roleArn string
 var changeset bool
 var experimental bool
+var ignoreImports bool
 
 // processMetadata looks for the EmptyOnDelete Rain Metadata command
 // and if it is set to true, deletes the contents of the bucket before
@@ -88,6 +90,34 @@ func processMetadata(stackName string, yes bool) error {
 	return nil
 }
 
+// checkImports returns an error if other stacks import any of the stack's
+// exports, since CloudFormation can't delete the stack until they stop
+func checkImports(stack types.Stack) error {
+	spinner.Push("Checking for stacks that import this stack's exports")
+	defer spinner.Pop()
+
+	used := make([]string, 0)
+	for _, o := range stack.Outputs {
+		if o.ExportName == nil {
+			continue
+		}
+		imports, err := cfn.ListImports(*o.ExportName)
+		if err != nil {
+			return ui.Errorf(err, "unable to list imports of export '%s'", *o.ExportName)
+		}
+		if len(imports) > 0 {
+			used = append(used, fmt.Sprintf("  - %s is imported by %s", *o.ExportName, strings.Join(imports, ", ")))
+		}
+	}
+
+	if len(used) > 0 {
+		return fmt.Errorf("stack '%s' can't be deleted while other stacks import its exports:\n%s",
+			*stack.StackName, strings.Join(used, "\n"))
+	}
+
+	return nil
+}
+
 func DeleteChangeSet(stack *types.Stack, changeSetName string) error {
 	if !yes {
 		spinner.Push("Fetching changeset details")
@@ -112,9 +142,13 @@ func DeleteChangeSet(stack *types.Stack, changeSetName string) error {
 
 // Cmd is the rm command's entrypoint
 var Cmd = &cobra.Command{
-	Use:                   "rm <stack> [changeset]",
-	Short:                 "Delete a CloudFormation stack or changeset",
-	Long:                  "Deletes the CloudFormation stack named <stack> and waits for the action to complete. With -c, deletes a changeset named [changeset].",
+	Use:   "rm <stack> [changeset]",
+	Short: "Delete a CloudFormation stack or changeset",
+	Long: `Deletes the CloudFormation stack named <stack> and waits for the action to complete. With -c, deletes a changeset named [changeset].
+
+If other stacks import any of the stack's exports with Fn::ImportValue, rain rm lists them and
+does not delete the stack, since CloudFormation would fail to delete it. Use --ignore-imports
+to skip the check.`,
 	Args:                  cobra.MaximumNArgs(2),
 	Aliases:               []string{"remove", "del", "delete"},
 	DisableFlagsInUseLine: true,
@@ -141,6 +175,12 @@ var Cmd = &cobra.Command{
 			return
 		}
 
+		if !ignoreImports {
+			if err := checkImports(stack); err != nil {
+				panic(err)
+			}
+		}
+
 		if !yes {
 			output, _ := cfn.GetStackOutput(stack)
 
@@ -203,5 +243,6 @@ func init() {
 	Cmd.Flags().BoolVarP(&yes, "yes", "y", false, "don't ask questions; just delete")
 	Cmd.Flags().StringVar(&roleArn, "role-arn", "", "ARN of an IAM role that CloudFormation should assume to remove the stack")
 	Cmd.Flags().BoolVarP(&changeset, "changeset", "c", false, "delete a changeset")
+	Cmd.Flags().BoolVar(&ignoreImports, "ignore-imports", false, "don't check whether other stacks import the stack's exports before deleting it")
 	Cmd.Flags().BoolVar(&experimental, "experimental", false, "Acknowledge that you want to deploy with an experimental feature")
 }
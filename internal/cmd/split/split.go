@@ -0,0 +1,276 @@
+package split
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/format"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/cft/refactor"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"github.com/aws-cloudformation/rain/internal/console"
+	"github.com/aws-cloudformation/rain/internal/ui"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var parts int
+var maxResources int
+var exports bool
+var exportPrefix string
+var configFile string
+var outputDir string
+var writeFlag bool
+
+// Cmd is the split command's entrypoint
+var Cmd = &cobra.Command{
+	Use:   "split <template>",
+	Short: "Split a template into several smaller stacks",
+	Long: `Splits a template into several templates, which is the opposite of rain merge.
+This is useful when a template is too big for CloudFormation, or has too many resources.
+
+Resources are divided into stacks of about the same size, with resources that refer to each
+other kept together, so that there are few references between stacks. The number of stacks
+is set with --parts, or by default is enough to keep each stack under --max-resources.
+Stacks are in order, and resources only refer to resources in the same stack or an earlier one.
+
+By default, the stacks are nested stacks. The stack templates are written to --output-dir,
+and the parent template is written to stdout, or back to the file with --write. The parent
+has the Parameters, Conditions, Rules and Outputs of the original template, and passes
+parameters and the outputs of earlier stacks into each stack. Package the parent with
+rain pkg to upload the stack templates.
+
+With --exports, the stacks are independent, and each stack exports the values that later
+stacks import with Fn::ImportValue. Export names start with --export-prefix. Deploy the
+stacks in the order that is printed. Values of resources with a Condition are only exported
+when the condition is true.
+
+A config file set with --config puts resources in named stacks. Named stacks come before
+the rest, in the order they are listed, and the resources that their resources refer to go
+in the same stack or an earlier one:
+
+  Stacks:
+    Network:
+      - Vpc
+      - Subnet
+    Storage:
+      - Bucket
+
+Resources that move to a different stack are replaced if the template is already deployed,
+unless you move them with a stack refactor.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		fileName := args[0]
+
+		source, err := os.ReadFile(fileName)
+		if err != nil {
+			panic(ui.Errorf(err, "unable to read '%s'", fileName))
+		}
+
+		t, err := parse.String(string(source))
+		if err != nil {
+			panic(ui.Errorf(err, "unable to parse template '%s'", fileName))
+		}
+
+		named, pins, err := readConfig(configFile)
+		if err != nil {
+			panic(ui.Errorf(err, "unable to read config file '%s'", configFile))
+		}
+
+		count := parts
+		if count == 0 {
+			count = defaultParts(t, maxResources)
+		}
+		count = max(count, len(named))
+
+		partitions, err := refactor.Partition(t, count, pins)
+		if err != nil {
+			panic(ui.Errorf(err, "unable to split '%s'", fileName))
+		}
+
+		stacks := makeStacks(partitions, named)
+		config.Debugf("stacks: %v", stacks)
+
+		base := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+		if exportPrefix == "" {
+			exportPrefix = base
+		}
+
+		ext := filepath.Ext(fileName)
+		if ext == "" {
+			ext = ".yaml"
+		}
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			panic(ui.Errorf(err, "unable to create '%s'", outputDir))
+		}
+
+		files := make(map[string]string)
+		for _, stack := range stacks {
+			files[stack.Name] = filepath.Join(outputDir, base+"-"+stack.Name+ext)
+			if _, err := os.Stat(files[stack.Name]); err == nil {
+				panic(fmt.Errorf("'%s' already exists", files[stack.Name]))
+			}
+		}
+
+		parent, err := refactor.Split(t, stacks, refactor.SplitOptions{
+			Exports:      exports,
+			ExportPrefix: exportPrefix,
+			TemplateURL: func(stack refactor.Stack) string {
+				return relativePath(fileName, files[stack.Name])
+			},
+		})
+		if err != nil {
+			panic(ui.Errorf(err, "unable to split '%s'", fileName))
+		}
+
+		jsonFlag := strings.HasPrefix(strings.TrimSpace(string(source)), "{")
+
+		if exports {
+			fmt.Fprintln(os.Stderr, console.Green("Deploy the stacks in this order:"))
+		}
+		for i, stack := range stacks {
+			output := format.String(stack.Template, format.Options{JSON: jsonFlag, Unsorted: true})
+			if err := os.WriteFile(files[stack.Name], []byte(output), 0644); err != nil {
+				panic(ui.Errorf(err, "unable to write '%s'", files[stack.Name]))
+			}
+			fmt.Fprintf(os.Stderr, "%d. %s: %d resources in %s\n",
+				i+1, console.Yellow(stack.Name), len(stack.Resources), files[stack.Name])
+		}
+
+		if exports {
+			return
+		}
+
+		output := format.String(parent, format.Options{JSON: jsonFlag, Unsorted: true})
+		if writeFlag {
+			if err := os.WriteFile(fileName, []byte(output), 0644); err != nil {
+				panic(ui.Errorf(err, "unable to write '%s'", fileName))
+			}
+			fmt.Println(console.Green(fmt.Sprintf("Split '%s' into %d nested stacks", fileName, len(stacks))))
+		} else {
+			fmt.Print(output)
+		}
+	},
+}
+
+// defaultParts returns the number of stacks needed to keep each
+// under the maximum number of resources, and at least 2
+func defaultParts(t cft.Template, maxResources int) int {
+	resources, err := t.GetSection(cft.Resources)
+	if err != nil || maxResources < 1 {
+		return 2
+	}
+	n := len(resources.Content) / 2
+	return max(2, (n+maxResources-1)/maxResources)
+}
+
+// readConfig reads the named stacks from a config file, and returns
+// the names in order, and the stack that each resource is pinned to
+func readConfig(fileName string) ([]string, map[string]int, error) {
+	names := make([]string, 0)
+	pins := make(map[string]int)
+	if fileName == "" {
+		return names, pins, nil
+	}
+
+	source, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Use a node rather than a map to keep the order of the stacks
+	var doc struct {
+		Stacks yaml.Node `yaml:"Stacks"`
+	}
+	if err := yaml.Unmarshal(source, &doc); err != nil {
+		return nil, nil, err
+	}
+	if doc.Stacks.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("expected Stacks to be a map of stack names to lists of resources")
+	}
+
+	for i := 0; i < len(doc.Stacks.Content); i += 2 {
+		name := doc.Stacks.Content[i].Value
+		names = append(names, name)
+		var resources []string
+		if err := doc.Stacks.Content[i+1].Decode(&resources); err != nil {
+			return nil, nil, fmt.Errorf("expected a list of resources for stack '%s': %w", name, err)
+		}
+		for _, r := range resources {
+			if p, ok := pins[r]; ok {
+				return nil, nil, fmt.Errorf("'%s' is in both '%s' and '%s'", r, names[p], name)
+			}
+			pins[r] = len(names) - 1
+		}
+	}
+
+	return names, pins, nil
+}
+
+// makeStacks names the partitions, using the names from the config file
+// first, and leaves out empty partitions
+func makeStacks(partitions [][]string, named []string) []refactor.Stack {
+	stacks := make([]refactor.Stack, 0)
+	used := make(map[string]bool)
+	for _, name := range named {
+		used[name] = true
+	}
+
+	n := 0
+	for i, resources := range partitions {
+		if len(resources) == 0 {
+			continue
+		}
+		var name string
+		if i < len(named) {
+			name = named[i]
+		} else {
+			for {
+				n++
+				name = fmt.Sprintf("Part%d", n)
+				if !used[name] {
+					break
+				}
+			}
+		}
+		stacks = append(stacks, refactor.Stack{Name: name, Resources: resources})
+	}
+	return stacks
+}
+
+// relativePath returns the location of a file relative to the template
+func relativePath(fileName, path string) string {
+	// Rel fails if one path is absolute and the other is not
+	dir, err := filepath.Abs(filepath.Dir(fileName))
+	if err != nil {
+		dir = filepath.Dir(fileName)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, ".") && !filepath.IsAbs(rel) {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+func init() {
+	Cmd.Flags().IntVarP(&parts, "parts", "n", 0, "The number of stacks to split the template into")
+	Cmd.Flags().IntVar(&maxResources, "max-resources", 400, "The most resources to put in each stack, when --parts is not set")
+	Cmd.Flags().BoolVar(&exports, "exports", false, "Make independent stacks that use Export and Fn::ImportValue, instead of nested stacks")
+	Cmd.Flags().StringVar(&exportPrefix, "export-prefix", "", "The start of each export name with --exports (default is the template's file name)")
+	Cmd.Flags().StringVarP(&configFile, "config", "c", "", "A YAML file that puts resources in named stacks")
+	Cmd.Flags().StringVarP(&outputDir, "output-dir", "o", ".", "The directory to write the stack templates to")
+	Cmd.Flags().BoolVarP(&writeFlag, "write", "w", false, "Write the parent template back to the file rather than to stdout")
+	Cmd.Flags().BoolVar(&config.Debug, "debug", false, "Output debugging information")
+}
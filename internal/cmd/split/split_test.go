@@ -0,0 +1,83 @@
+package split
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws-cloudformation/rain/cft/refactor"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadConfig(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "split.yaml")
+	source := `
+Stacks:
+  Network: [Vpc, Subnet]
+  Storage:
+    - Bucket
+`
+	if err := os.WriteFile(fn, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	names, pins, err := readConfig(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]string{"Network", "Storage"}, names); d != "" {
+		t.Error(d)
+	}
+	if d := cmp.Diff(map[string]int{"Vpc": 0, "Subnet": 0, "Bucket": 1}, pins); d != "" {
+		t.Error(d)
+	}
+
+	if err := os.WriteFile(fn, []byte("Stacks:\n  A: [Vpc]\n  B: [Vpc]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := readConfig(fn); err == nil {
+		t.Error("expected an error for a resource in two stacks")
+	}
+}
+
+func TestMakeStacks(t *testing.T) {
+	stacks := makeStacks([][]string{{"Vpc"}, {}, {"Bucket"}, {"Role"}}, []string{"Part2"})
+	expected := []refactor.Stack{
+		{Name: "Part2", Resources: []string{"Vpc"}},
+		{Name: "Part1", Resources: []string{"Bucket"}},
+		{Name: "Part3", Resources: []string{"Role"}},
+	}
+	if d := cmp.Diff(expected, stacks, cmp.AllowUnexported(refactor.Stack{})); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+
+	cases := []struct {
+		fileName, path, expected string
+	}{
+		{"graph.yaml", "graph-Part1.yaml", "./graph-Part1.yaml"},
+		{"templates/graph.yaml", "templates/out/graph-Part1.yaml", "./out/graph-Part1.yaml"},
+		{"templates/graph.yaml", "out/graph-Part1.yaml", "../out/graph-Part1.yaml"},
+	}
+	for _, c := range cases {
+		if rel := relativePath(c.fileName, c.path); rel != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, rel)
+		}
+	}
+
+	// An absolute output directory with a relative template
+	rel := relativePath("graph.yaml", filepath.Join(out, "graph-Part1.yaml"))
+	if filepath.IsAbs(rel) {
+		t.Errorf("expected a relative path, got %s", rel)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if abs := filepath.Join(wd, filepath.FromSlash(rel)); abs != filepath.Join(out, "graph-Part1.yaml") {
+		t.Errorf("expected %s to point to the output directory, got %s", rel, abs)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/aws-cloudformation/rain/cft"
 	"github.com/aws-cloudformation/rain/cft/format"
+	cftpkg "github.com/aws-cloudformation/rain/cft/pkg"
 	"github.com/aws-cloudformation/rain/internal/aws/cfn"
 	"github.com/aws-cloudformation/rain/internal/cmd/deploy"
 	"github.com/aws-cloudformation/rain/internal/config"
@@ -109,6 +110,10 @@ Account(s) and region(s) provided as flags OVERRIDE values from configuration fi
 		configData.StackSetInstances.StackSetName = stackSetName
 
 		spinner.Push(fmt.Sprintf("Preparing template '%s'", templateFilePath))
+		cftpkg.Params = configData.Parameters
+		if cftpkg.Params == nil {
+			cftpkg.Params = make(map[string]string)
+		}
 		configData.StackSet.Template = deploy.PackageTemplate(templateFilePath, yes)
 		spinner.Pop()
 
@@ -0,0 +1,101 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+
+	cftpkg "github.com/aws-cloudformation/rain/cft/pkg"
+	"github.com/aws-cloudformation/rain/cft/stats"
+	"github.com/aws-cloudformation/rain/internal/config"
+	"github.com/aws-cloudformation/rain/internal/console"
+	"github.com/aws-cloudformation/rain/internal/console/spinner"
+	"github.com/aws-cloudformation/rain/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var experimental bool
+var threshold int
+
+// Cmd is the stats command's entrypoint
+var Cmd = &cobra.Command{
+	Use:   "stats <template>",
+	Short: "Measure a template against CloudFormation quotas",
+	Long: `Packages a template in the same way as rain pkg, and measures the result against the
+quotas that CloudFormation enforces on templates, like the number of resources, outputs and
+parameters, the size of the template, and the length of each Fn::Sub string.
+
+Quotas that are at least --threshold percent used are shown in yellow, and quotas that are
+exceeded are shown in red. For quotas on each item, like the length of a Fn::Sub, the
+largest item is shown. rain stats fails if the template can't be deployed.
+
+Templates over 51,200 bytes can't be sent to CloudFormation directly, so rain deploy
+uploads them to S3, where the limit is 1MB. Transforms like AWS::Serverless-2016-10-31
+can add resources when the template is deployed, which are not counted.`,
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		fn := args[0]
+
+		cftpkg.Experimental = experimental
+
+		spinner.Push(fmt.Sprintf("Packaging template '%s'", fn))
+		packaged, err := cftpkg.File(fn)
+		if err != nil {
+			panic(ui.Errorf(err, "unable to package template '%s'", fn))
+		}
+		spinner.Pop()
+
+		metrics := stats.Template(packaged)
+		fmt.Print(format(metrics, threshold))
+
+		exceeded := 0
+		for _, m := range metrics {
+			if m.Blocking && m.Exceeded() {
+				exceeded++
+			}
+		}
+		if exceeded > 0 {
+			panic(fmt.Errorf("the template exceeds %d CloudFormation quotas", exceeded))
+		}
+	},
+}
+
+// format makes a table of the metrics, with a colour for the ones near
+// their limit, followed by where the largest items are
+func format(metrics []stats.Metric, threshold int) string {
+	width := 0
+	for _, m := range metrics {
+		width = max(width, len(m.Name))
+	}
+
+	out := strings.Builder{}
+	out.WriteString(console.Bold(fmt.Sprintf("%-*s %10s %10s %6s\n", width, "Quota", "Value", "Limit", "Used")))
+	for _, m := range metrics {
+		line := fmt.Sprintf("%-*s %10d %10d %5d%%", width, m.Name, m.Value, m.Limit, m.Percent())
+		if m.Where != "" && m.Value > 0 {
+			line += "  " + m.Where
+		}
+		switch {
+		case m.Exceeded() && m.Blocking:
+			line = console.Red(line)
+		case m.Near(threshold):
+			line = console.Yellow(line)
+		}
+		out.WriteString(line + "\n")
+	}
+
+	for _, m := range metrics {
+		if m.Exceeded() && !m.Blocking {
+			out.WriteString(fmt.Sprintf("%s is over %d %s, so rain deploy will upload it to S3\n", m.Name, m.Limit, m.Unit))
+		}
+	}
+
+	return out.String()
+}
+
+func init() {
+	Cmd.Flags().IntVar(&threshold, "threshold", 80, "Highlight quotas that are at least this percent used")
+	Cmd.Flags().BoolVarP(&experimental, "experimental", "x", false, "Enable experimental features")
+	Cmd.Flags().BoolVar(&cftpkg.NoAnalytics, "no-analytics", false, "Do not include analytics in Metadata")
+	Cmd.Flags().BoolVar(&config.Debug, "debug", false, "Output debugging information")
+}
@@ -0,0 +1,292 @@
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	_ "embed"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/graph"
+)
+
+//go:embed tree-template.html
+var htmlTemplate string
+
+// exportNode is a node in the JSON and HTML output
+type exportNode struct {
+	Id      string `json:"id"`
+	Section string `json:"section"`
+	Name    string `json:"name"`
+
+	// Type is the resource type, or the parameter type
+	Type string `json:"type,omitempty"`
+
+	// Service is the service part of a resource type, like S3
+	Service string `json:"service,omitempty"`
+
+	// Group is the section, or the module the resource comes from
+	Group string `json:"group"`
+
+	// Module is the source of a !Rain::Module resource
+	Module string `json:"module,omitempty"`
+
+	Colour string `json:"colour"`
+}
+
+// exportEdge is a dependency of From on To
+type exportEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Types are the kinds of references, like ref or dependsOn
+	Types []graph.EdgeType `json:"types,omitempty"`
+}
+
+func newExportEdge(e graph.Edge) exportEdge {
+	return exportEdge{From: e.From.String(), To: e.To.String(), Types: e.Types}
+}
+
+// exportCycle is a circular dependency
+type exportCycle struct {
+	Edges      []exportEdge `json:"edges"`
+	Suggestion string       `json:"suggestion"`
+}
+
+// export is the graph in a form that is easy to serialize
+type export struct {
+	Nodes  []exportNode  `json:"nodes"`
+	Edges  []exportEdge  `json:"edges"`
+	Cycles []exportCycle `json:"cycles,omitempty"`
+}
+
+// palette is used to colour nodes by service
+var palette = []string{
+	"#e6194b", "#3cb44b", "#ffe119", "#4363d8", "#f58231",
+	"#911eb4", "#46f0f0", "#f032e6", "#bcf60c", "#fabebe",
+	"#008080", "#e6beff", "#9a6324", "#fffac8", "#800000",
+	"#aaffc3", "#808000", "#ffd8b1", "#000075", "#808080",
+}
+
+var sectionColours = map[string]string{
+	"Parameters": "#dddddd",
+	"Conditions": "#eeeeee",
+	"Imports":    "#dddddd",
+	"Outputs":    "#ffffff",
+}
+
+// colourServices gives each service a different colour from the palette,
+// in alphabetical order, so the colours are the same every time
+func (e export) colourServices() {
+	services := make([]string, 0)
+	for _, n := range e.Nodes {
+		if n.Service != "" && !slices.Contains(services, n.Service) {
+			services = append(services, n.Service)
+		}
+	}
+	sort.Strings(services)
+	for i, n := range e.Nodes {
+		if n.Service != "" {
+			e.Nodes[i].Colour = palette[slices.Index(services, n.Service)%len(palette)]
+		}
+	}
+}
+
+// moduleSource returns the source of a resource with Type: !Rain::Module
+func moduleSource(typ interface{}) (string, bool) {
+	m, ok := typ.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	source, ok := m["Rain::Module"].(string)
+	return source, ok
+}
+
+// newExport collects the nodes and edges of g, with types from t.
+// Resources are grouped by the module they come from, and coloured by service.
+func newExport(t cft.Template, g graph.Graph) export {
+	sections := t.Map()
+
+	retval := export{
+		Nodes: make([]exportNode, 0),
+		Edges: make([]exportEdge, 0),
+	}
+
+	for _, n := range g.Nodes() {
+		en := exportNode{
+			Id:      n.String(),
+			Section: n.Type,
+			Name:    n.Name,
+			Group:   n.Type,
+			Colour:  sectionColours[n.Type],
+		}
+
+		section, _ := sections[n.Type].(map[string]interface{})
+		element, _ := section[n.Name].(map[string]interface{})
+
+		switch n.Type {
+		case "Parameters":
+			en.Type, _ = element["Type"].(string)
+			if strings.HasPrefix(n.Name, "AWS::") {
+				en.Type = "Pseudo"
+			}
+		case "Resources":
+			if source, ok := moduleSource(element["Type"]); ok {
+				en.Type = "Rain::Module"
+				en.Service = "Module"
+				en.Module = source
+				en.Group = "Module: " + source
+			} else {
+				en.Type, _ = element["Type"].(string)
+				// AWS::S3::Bucket is S3, Custom::Thing is Custom
+				if parts := strings.Split(en.Type, "::"); len(parts) == 3 {
+					en.Service = parts[1]
+				} else {
+					en.Service = parts[0]
+				}
+			}
+		}
+
+		retval.Nodes = append(retval.Nodes, en)
+
+		for _, e := range g.Edges(n) {
+			retval.Edges = append(retval.Edges, newExportEdge(e))
+		}
+	}
+
+	for _, c := range g.Cycles() {
+		ec := exportCycle{Edges: make([]exportEdge, 0), Suggestion: c.Suggestion()}
+		for _, e := range c {
+			ec.Edges = append(ec.Edges, newExportEdge(e))
+		}
+		retval.Cycles = append(retval.Cycles, ec)
+	}
+
+	retval.colourServices()
+
+	return retval
+}
+
+// groups returns the names of the groups in the order they should be shown,
+// with modules after Resources
+func (e export) groups() []string {
+	retval := []string{"Parameters", "Conditions", "Resources"}
+	seen := make(map[string]bool)
+	for _, s := range sections {
+		seen[s] = true
+	}
+	for _, n := range e.Nodes {
+		if !seen[n.Group] {
+			seen[n.Group] = true
+			retval = append(retval, n.Group)
+		}
+	}
+	return append(retval, "Imports", "Outputs")
+}
+
+func printJson(t cft.Template, g graph.Graph) {
+	out, err := json.MarshalIndent(newExport(t, g), "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(out))
+}
+
+var mermaidUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// mermaidShapes wrap node labels, like dotShapes
+var mermaidShapes = map[string][2]string{
+	"Parameters": {"{{", "}}"},
+	"Conditions": {"{", "}"},
+	"Resources":  {"(", ")"},
+	"Imports":    {"[/", "/]"},
+	"Outputs":    {"[", "]"},
+}
+
+// mermaidLabel escapes quotes, which end a label
+func mermaidLabel(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
+
+func printMermaid(t cft.Template, g graph.Graph) {
+	e := newExport(t, g)
+
+	ids := make(map[string]string)
+	for i, n := range e.Nodes {
+		ids[n.Id] = fmt.Sprintf("n%d", i)
+	}
+
+	out := strings.Builder{}
+	out.WriteString("flowchart LR\n")
+
+	i := 0
+	for _, group := range e.groups() {
+		nodes := make([]exportNode, 0)
+		for _, n := range e.Nodes {
+			if n.Group == group {
+				nodes = append(nodes, n)
+			}
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+
+		out.WriteString(fmt.Sprintf("    subgraph g%d [\"%s\"]\n", i, mermaidLabel(group)))
+		i++
+		for _, n := range nodes {
+			shape := mermaidShapes[n.Section]
+			out.WriteString(fmt.Sprintf("        %s%s\"%s\"%s\n", ids[n.Id], shape[0], mermaidLabel(n.Name), shape[1]))
+		}
+		out.WriteString("    end\n")
+	}
+
+	// Arrows point from a dependency to the node that depends on it, like DOT
+	for _, edge := range e.Edges {
+		arrow := "-->"
+		if dashed(graph.Edge{Types: edge.Types}) {
+			arrow = "-.->"
+		}
+		out.WriteString(fmt.Sprintf("    %s %s %s\n", ids[edge.To], arrow, ids[edge.From]))
+	}
+
+	classes := make([]string, 0)
+	members := make(map[string][]string)
+	for _, n := range e.Nodes {
+		if n.Service == "" {
+			continue
+		}
+		class := "svc_" + mermaidUnsafe.ReplaceAllString(n.Service, "_")
+		if _, ok := members[class]; !ok {
+			classes = append(classes, class)
+			out.WriteString(fmt.Sprintf("    classDef %s fill:%s\n", class, n.Colour))
+		}
+		members[class] = append(members[class], ids[n.Id])
+	}
+	for _, class := range classes {
+		out.WriteString(fmt.Sprintf("    class %s %s\n", strings.Join(members[class], ","), class))
+	}
+
+	fmt.Print(out.String())
+}
+
+func printHtml(t cft.Template, g graph.Graph, title string) {
+	e := newExport(t, g)
+
+	data, err := json.Marshal(struct {
+		export
+		Title  string   `json:"title"`
+		Groups []string `json:"groups"`
+	}{e, title, e.groups()})
+	if err != nil {
+		panic(err)
+	}
+
+	// Escape anything that could close the script tag
+	safe := strings.ReplaceAll(string(data), "</", "<\\/")
+
+	fmt.Println(strings.Replace(htmlTemplate, "__DATA__", safe, 1))
+}
@@ -0,0 +1,113 @@
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws-cloudformation/rain/cft"
+	"github.com/aws-cloudformation/rain/cft/diff"
+	"github.com/aws-cloudformation/rain/cft/graph"
+	"github.com/aws-cloudformation/rain/internal/aws/cfn"
+	"github.com/aws-cloudformation/rain/internal/console"
+)
+
+// impactReport is the JSON output of --impact
+type impactReport struct {
+	Target     string        `json:"target"`
+	Dependents []impactEntry `json:"dependents"`
+}
+
+type impactEntry struct {
+	Id       string   `json:"id"`
+	Type     string   `json:"type,omitempty"`
+	Distance int      `json:"distance"`
+	Replaced bool     `json:"replaced"`
+	Stateful bool     `json:"stateful,omitempty"`
+	Paths    []string `json:"paths,omitempty"`
+}
+
+// findNode returns the resource, parameter or condition with the name
+func findNode(t cft.Template, name string) (graph.Node, bool) {
+	sections := t.Map()
+	for _, section := range []cft.Section{cft.Resources, cft.Parameters, cft.Conditions} {
+		if elements, ok := sections[string(section)].(map[string]interface{}); ok {
+			if _, ok := elements[name]; ok {
+				return graph.Node{Type: string(section), Name: name}, true
+			}
+		}
+	}
+	return graph.Node{}, false
+}
+
+func printImpact(t cft.Template, g graph.Graph, name string) {
+	target, ok := findNode(t, name)
+	if !ok {
+		panic(fmt.Errorf("%s is not a resource, parameter or condition in the template", name))
+	}
+
+	impacts := g.Impact(t, target, cfn.CreateOnlyProperties)
+
+	if jsonGraph {
+		report := impactReport{Target: target.String(), Dependents: make([]impactEntry, 0)}
+		for _, i := range impacts {
+			report.Dependents = append(report.Dependents, impactEntry{
+				Id:       i.Node.String(),
+				Type:     i.TypeName,
+				Distance: i.Distance,
+				Replaced: i.Replaced,
+				Stateful: i.Replaced && diff.IsStateful(i.TypeName),
+				Paths:    i.Paths,
+			})
+		}
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
+	if len(impacts) == 0 {
+		fmt.Printf("Nothing depends on %s\n", target)
+		return
+	}
+
+	change := "is replaced"
+	if target.Type == string(cft.Parameters) {
+		change = "changes"
+	}
+	fmt.Printf("Elements that depend on %s:\n", console.Yellow(target.String()))
+
+	for _, section := range sections {
+		first := true
+		for _, i := range impacts {
+			if i.Node.Type != section {
+				continue
+			}
+			if first {
+				fmt.Printf("  %s:\n", section)
+				first = false
+			}
+
+			line := fmt.Sprintf("    - %s", i.Node.Name)
+			if i.TypeName != "" {
+				line += fmt.Sprintf(" (%s)", i.TypeName)
+			}
+			if i.Distance > 1 {
+				line += fmt.Sprintf(" via %d links", i.Distance)
+			}
+
+			switch {
+			case !i.Replaced:
+				fmt.Println(line)
+			case diff.IsStateful(i.TypeName):
+				fmt.Println(console.Red(fmt.Sprintf("%s will be replaced if %s %s (%s) - data will be lost!",
+					line, name, change, strings.Join(i.Paths, ", "))))
+			default:
+				fmt.Println(console.Yellow(fmt.Sprintf("%s will be replaced if %s %s (%s)",
+					line, name, change, strings.Join(i.Paths, ", "))))
+			}
+		}
+	}
+}
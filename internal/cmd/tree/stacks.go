@@ -0,0 +1,270 @@
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws-cloudformation/rain/cft/graph"
+	"github.com/aws-cloudformation/rain/cft/parse"
+	"github.com/aws-cloudformation/rain/internal/aws/cfn"
+	"github.com/aws-cloudformation/rain/internal/console"
+	"github.com/aws-cloudformation/rain/internal/console/spinner"
+	"github.com/aws-cloudformation/rain/internal/ui"
+	"github.com/aws/smithy-go/ptr"
+)
+
+// deployedStack is the exports and imports of a stack in the region
+type deployedStack struct {
+	Name string
+
+	// Exports are the Export names of the stack's outputs
+	Exports []string
+
+	// Imports are the export names used with Fn::ImportValue in the stack's template
+	Imports []string
+}
+
+// crossExport is an export and the stacks that use it
+type crossExport struct {
+	Name string `json:"name"`
+
+	// Stack exports the value, or is empty if no stack exports it
+	Stack string `json:"stack,omitempty"`
+
+	ImportedBy []string `json:"importedBy"`
+}
+
+// crossStack is the account-wide graph of which stack consumes which export
+type crossStack struct {
+	Stacks  []string      `json:"stacks"`
+	Exports []crossExport `json:"exports"`
+}
+
+// newCrossStack links the imports of each stack to the stack that exports them.
+// Everything is sorted so that the output is the same every time.
+func newCrossStack(stacks []deployedStack) crossStack {
+	retval := crossStack{
+		Stacks:  make([]string, 0),
+		Exports: make([]crossExport, 0),
+	}
+
+	exports := make(map[string]*crossExport)
+	get := func(name string) *crossExport {
+		if _, ok := exports[name]; !ok {
+			exports[name] = &crossExport{Name: name, ImportedBy: make([]string, 0)}
+		}
+		return exports[name]
+	}
+
+	for _, s := range stacks {
+		retval.Stacks = append(retval.Stacks, s.Name)
+		for _, name := range s.Exports {
+			get(name).Stack = s.Name
+		}
+		for _, name := range s.Imports {
+			e := get(name)
+			if !slices.Contains(e.ImportedBy, s.Name) {
+				e.ImportedBy = append(e.ImportedBy, s.Name)
+			}
+		}
+	}
+
+	sort.Strings(retval.Stacks)
+
+	names := make([]string, 0)
+	for name := range exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e := exports[name]
+		sort.Strings(e.ImportedBy)
+		retval.Exports = append(retval.Exports, *e)
+	}
+
+	return retval
+}
+
+// fetchConcurrency is how many stacks fetchStacks looks at in parallel
+const fetchConcurrency = 8
+
+// fetchStacks gets the exports and imports of every stack in the region.
+// Stacks that can't be read are reported and left out.
+func fetchStacks() []deployedStack {
+	spinner.Push("Listing stacks")
+	summaries, err := cfn.ListStacks()
+	if err != nil {
+		panic(ui.Errorf(err, "unable to list stacks"))
+	}
+	spinner.Pop()
+
+	spinner.Push(fmt.Sprintf("Fetching exports and imports of %d stacks", len(summaries)))
+	stacks := make([]*deployedStack, len(summaries))
+	errs := make([]error, len(summaries))
+	sem := make(chan struct{}, fetchConcurrency)
+	var wg sync.WaitGroup
+	for i, summary := range summaries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, name string) {
+			defer func() { <-sem; wg.Done() }()
+			stacks[i], errs[i] = fetchStack(name)
+		}(i, ptr.ToString(summary.StackName))
+	}
+	wg.Wait()
+	spinner.Pop()
+
+	retval := make([]deployedStack, 0)
+	for i, s := range stacks {
+		if errs[i] != nil {
+			fmt.Fprintln(os.Stderr, console.Yellow(fmt.Sprintf("Skipping stack '%s': %v",
+				ptr.ToString(summaries[i].StackName), errs[i])))
+			continue
+		}
+		retval = append(retval, *s)
+	}
+
+	return retval
+}
+
+// fetchStack gets the exports and imports of a single stack
+func fetchStack(name string) (*deployedStack, error) {
+	s := &deployedStack{Name: name, Exports: make([]string, 0), Imports: make([]string, 0)}
+
+	outputs, err := cfn.GetStackOutputs(name)
+	if err != nil {
+		return nil, ui.Errorf(err, "unable to get outputs")
+	}
+	for _, o := range outputs {
+		if o.ExportName != nil {
+			s.Exports = append(s.Exports, *o.ExportName)
+		}
+	}
+
+	source, err := cfn.GetStackTemplate(name, false)
+	if err != nil {
+		return nil, ui.Errorf(err, "unable to get template")
+	}
+	t, err := parse.String(source)
+	if err != nil {
+		return nil, ui.Errorf(err, "unable to parse template")
+	}
+	for _, n := range graph.New(t).Nodes() {
+		if n.Type == "Imports" {
+			s.Imports = append(s.Imports, n.Name)
+		}
+	}
+
+	return s, nil
+}
+
+func printStacks(c crossStack) {
+	out := strings.Builder{}
+
+	for _, stack := range c.Stacks {
+		exports := make([]string, 0)
+		imports := make([]string, 0)
+		for _, e := range c.Exports {
+			if e.Stack == stack {
+				if len(e.ImportedBy) == 0 {
+					exports = append(exports, fmt.Sprintf("%s (not imported)", e.Name))
+				} else {
+					exports = append(exports, fmt.Sprintf("%s (imported by %s)", e.Name, strings.Join(e.ImportedBy, ", ")))
+				}
+			}
+			if slices.Contains(e.ImportedBy, stack) {
+				if e.Stack == "" {
+					imports = append(imports, console.Red(fmt.Sprintf("%s (not exported by any stack)", e.Name)))
+				} else {
+					imports = append(imports, fmt.Sprintf("%s (from %s)", e.Name, e.Stack))
+				}
+			}
+		}
+
+		if len(exports) == 0 && len(imports) == 0 && !allLinks {
+			continue
+		}
+
+		out.WriteString(fmt.Sprintf("%s:\n", console.Yellow(stack)))
+		if len(exports) > 0 {
+			out.WriteString(fmt.Sprintf("  %s:\n", console.Blue("Exports")))
+			for _, e := range exports {
+				out.WriteString(fmt.Sprintf("    - %s\n", e))
+			}
+		}
+		if len(imports) > 0 {
+			out.WriteString(fmt.Sprintf("  %s:\n", console.Blue("Imports")))
+			for _, i := range imports {
+				out.WriteString(fmt.Sprintf("    - %s\n", i))
+			}
+		}
+	}
+
+	fmt.Print(out.String())
+}
+
+func printStacksJson(c crossStack) {
+	out, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(out))
+}
+
+// printStacksDot draws an arrow from each exporting stack to each stack that
+// imports from it, labelled with the export name
+func printStacksDot(c crossStack) {
+	out := strings.Builder{}
+
+	out.WriteString("digraph {\n")
+	out.WriteString("    rankdir=LR;\n")
+
+	for _, stack := range c.Stacks {
+		out.WriteString(fmt.Sprintf("    \"%s\" [shape=box];\n", stack))
+	}
+
+	for _, e := range c.Exports {
+		from := e.Stack
+		if from == "" {
+			from = "Missing: " + e.Name
+			out.WriteString(fmt.Sprintf("    \"%s\" [shape=parallelogram color=red];\n", from))
+		}
+		for _, to := range e.ImportedBy {
+			out.WriteString(fmt.Sprintf("    \"%s\" -> \"%s\" [label=\"%s\"];\n", from, to, e.Name))
+		}
+	}
+
+	out.WriteString("}")
+
+	fmt.Println(out.String())
+}
+
+func printStacksMermaid(c crossStack) {
+	ids := make(map[string]string)
+
+	out := strings.Builder{}
+	out.WriteString("flowchart LR\n")
+
+	for i, stack := range c.Stacks {
+		ids[stack] = fmt.Sprintf("s%d", i)
+		out.WriteString(fmt.Sprintf("    %s(\"%s\")\n", ids[stack], mermaidLabel(stack)))
+	}
+
+	for i, e := range c.Exports {
+		from := ids[e.Stack]
+		if e.Stack == "" {
+			from = fmt.Sprintf("m%d", i)
+			out.WriteString(fmt.Sprintf("    %s[/\"Missing: %s\"/]\n", from, mermaidLabel(e.Name)))
+		}
+		for _, to := range e.ImportedBy {
+			out.WriteString(fmt.Sprintf("    %s -->|\"%s\"| %s\n", from, mermaidLabel(e.Name), ids[to]))
+		}
+	}
+
+	fmt.Print(out.String())
+}
@@ -0,0 +1,29 @@
+package tree
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewCrossStack(t *testing.T) {
+	c := newCrossStack([]deployedStack{
+		{Name: "network", Exports: []string{"VpcId", "Unused"}, Imports: []string{}},
+		{Name: "db", Exports: []string{}, Imports: []string{"VpcId"}},
+		{Name: "app", Exports: []string{"AppUrl"}, Imports: []string{"VpcId", "Missing", "VpcId"}},
+	})
+
+	expected := crossStack{
+		Stacks: []string{"app", "db", "network"},
+		Exports: []crossExport{
+			{Name: "AppUrl", Stack: "app", ImportedBy: []string{}},
+			{Name: "Missing", ImportedBy: []string{"app"}},
+			{Name: "Unused", Stack: "network", ImportedBy: []string{}},
+			{Name: "VpcId", Stack: "network", ImportedBy: []string{"app", "db"}},
+		},
+	}
+
+	if d := cmp.Diff(expected, c); d != "" {
+		t.Error(d)
+	}
+}
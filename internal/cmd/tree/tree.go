@@ -4,6 +4,7 @@ import (
 	"github.com/aws-cloudformation/rain/internal/ui"
 
 	"github.com/aws-cloudformation/rain/cft/graph"
+	"github.com/aws-cloudformation/rain/cft/langext"
 	"github.com/aws-cloudformation/rain/cft/parse"
 	"github.com/spf13/cobra"
 )
@@ -11,16 +12,80 @@ import (
 var allLinks = false
 var dotGraph = false
 var twoWayTree = false
+var mermaid = false
+var jsonGraph = false
+var htmlGraph = false
+var impact string
+var stacks bool
 
 // Cmd is the tree command's entrypoint
 var Cmd = &cobra.Command{
-	Use:                   "tree [template]",
-	Short:                 "Find dependencies of Resources and Outputs in a local template",
-	Long:                  "Find and display the dependencies between Parameters, Resources, and Outputs in a CloudFormation template.",
-	Args:                  cobra.ExactArgs(1),
+	Use:   "tree <template> | --stacks",
+	Short: "Find dependencies of Resources and Outputs in a local template",
+	Long: `Find and display the dependencies between Parameters, Conditions, Resources, and Outputs in a CloudFormation template.
+Exports from other stacks that are used with Fn::ImportValue are shown as Imports.
+
+The graph can also be exported with --dot for GraphViz, --mermaid for Markdown and pull request
+descriptions, --json for other tools, or --html for a self-contained page that you can open in a
+browser to explore the graph. In the Mermaid, JSON and HTML output, resources that use a Rain
+module are grouped by the module's source, and resources are coloured by service.
+The JSON output includes the types of each edge: ref, getatt, dependsOn, condition or importValue.
+Edges that only come from DependsOn or Condition are drawn with dashed lines.
+
+With --impact <name>, rain tree lists every element that depends on the named resource or
+parameter, directly or indirectly. This shows what else is affected by a change. Assuming the
+resource is replaced, or the parameter value changes, resources that use it in a property that
+can only be set on creation, according to the resource schema's createOnlyProperties, will
+be replaced too. With --json, the impact is output as JSON.
+
+Fn::ForEach loops in templates with the AWS::LanguageExtensions transform are expanded,
+so that each resource that a loop makes is shown. Loops over parameters use the parameter's Default.
+
+Circular dependencies are reported with the chain of references that forms each cycle,
+and a suggestion for which DependsOn to remove if one of the references is a DependsOn.
+
+With --stacks, rain tree does not take a template. Instead, it reads the outputs and templates
+of every stack in the region, and shows which stacks import each export with Fn::ImportValue.
+Imports that no stack exports are shown in red. Export names that are built with other
+functions, like Fn::Sub, can't be resolved and are not shown. The account-wide graph can
+be output with --dot, --mermaid or --json.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if stacks {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	Aliases:               []string{"graph"},
 	DisableFlagsInUseLine: true,
 	Run: func(cmd *cobra.Command, args []string) {
+		formats := 0
+		for _, f := range []bool{dotGraph, mermaid, jsonGraph, htmlGraph} {
+			if f {
+				formats++
+			}
+		}
+		if formats > 1 {
+			panic("only one of --dot, --mermaid, --json and --html can be used")
+		}
+
+		if stacks {
+			if htmlGraph || impact != "" {
+				panic("--html and --impact can't be used with --stacks")
+			}
+			c := newCrossStack(fetchStacks())
+			switch {
+			case dotGraph:
+				printStacksDot(c)
+			case mermaid:
+				printStacksMermaid(c)
+			case jsonGraph:
+				printStacksJson(c)
+			default:
+				printStacks(c)
+			}
+			return
+		}
+
 		fileName := args[0]
 
 		t, err := parse.File(fileName)
@@ -28,14 +93,34 @@ var Cmd = &cobra.Command{
 			panic(ui.Errorf(err, "unable to parse template '%s'", fileName))
 		}
 
+		if langext.Uses(t) {
+			t, err = langext.Expand(t, langext.Options{})
+			if err != nil {
+				panic(ui.Errorf(err, "unable to expand language extensions in '%s'", fileName))
+			}
+		}
+
 		g := graph.New(t)
 
-		if dotGraph {
+		if impact != "" {
+			printImpact(t, g, impact)
+			return
+		}
+
+		switch {
+		case dotGraph:
 			printDot(g)
-		} else {
-			printGraph(g, "Parameters")
-			printGraph(g, "Resources")
-			printGraph(g, "Outputs")
+		case mermaid:
+			printMermaid(t, g)
+		case jsonGraph:
+			printJson(t, g)
+		case htmlGraph:
+			printHtml(t, g, fileName)
+		default:
+			for _, section := range sections {
+				printGraph(g, section)
+			}
+			printCycles(g)
 		}
 	},
 }
@@ -44,4 +129,9 @@ func init() {
 	Cmd.Flags().BoolVarP(&allLinks, "all", "a", false, "Display all elements, even those without any dependencies")
 	Cmd.Flags().BoolVarP(&twoWayTree, "both", "b", false, "For each element, display both its dependencies and its dependents")
 	Cmd.Flags().BoolVarP(&dotGraph, "dot", "d", false, "Output the graph in GraphViz DOT format")
+	Cmd.Flags().BoolVarP(&mermaid, "mermaid", "m", false, "Output the graph as a Mermaid flowchart")
+	Cmd.Flags().BoolVarP(&jsonGraph, "json", "j", false, "Output the nodes and edges of the graph as JSON")
+	Cmd.Flags().BoolVar(&htmlGraph, "html", false, "Output an interactive view of the graph as an html file")
+	Cmd.Flags().StringVar(&impact, "impact", "", "List everything that depends on a resource, parameter or condition, and what would be replaced")
+	Cmd.Flags().BoolVar(&stacks, "stacks", false, "Show which deployed stacks in the region import the exports of other stacks")
 }
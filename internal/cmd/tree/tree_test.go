@@ -47,3 +47,105 @@ func Example_sub() {
 	//       Resources:
 	//         - EfsFileSystem
 }
+
+func Example_mermaid() {
+	os.Args = []string{
+		os.Args[0],
+		"--mermaid",
+		"../../../test/templates/success.template",
+	}
+	defer tree.Cmd.Flags().Set("mermaid", "false")
+
+	tree.Cmd.Execute()
+	// Output:
+	// flowchart LR
+	//     subgraph g0 ["Parameters"]
+	//         n0{{"BucketName"}}
+	//     end
+	//     subgraph g1 ["Resources"]
+	//         n1("Bucket1")
+	//     end
+	//     n0 --> n1
+	//     classDef svc_S3 fill:#e6194b
+	//     class n1 svc_S3
+}
+
+func Example_json() {
+	os.Args = []string{
+		os.Args[0],
+		"--json",
+		"../../../test/templates/success.template",
+	}
+	defer tree.Cmd.Flags().Set("json", "false")
+
+	tree.Cmd.Execute()
+	// Output:
+	// {
+	//   "nodes": [
+	//     {
+	//       "id": "Parameters/BucketName",
+	//       "section": "Parameters",
+	//       "name": "BucketName",
+	//       "type": "String",
+	//       "group": "Parameters",
+	//       "colour": "#dddddd"
+	//     },
+	//     {
+	//       "id": "Resources/Bucket1",
+	//       "section": "Resources",
+	//       "name": "Bucket1",
+	//       "type": "AWS::S3::Bucket",
+	//       "service": "S3",
+	//       "group": "Resources",
+	//       "colour": "#e6194b"
+	//     }
+	//   ],
+	//   "edges": [
+	//     {
+	//       "from": "Resources/Bucket1",
+	//       "to": "Parameters/BucketName",
+	//       "types": [
+	//         "ref"
+	//       ]
+	//     }
+	//   ]
+	// }
+}
+
+func Example_mermaid_modules() {
+	os.Args = []string{
+		os.Args[0],
+		"--mermaid",
+		"../../../test/templates/ext-ref-module.yaml",
+	}
+	defer tree.Cmd.Flags().Set("mermaid", "false")
+
+	tree.Cmd.Execute()
+	// Output:
+	// flowchart LR
+	//     subgraph g0 ["Parameters"]
+	//         n0{{"ThePolicy"}}
+	//     end
+	//     subgraph g1 ["Module: file://../../modules/ext-ref.yaml"]
+	//         n1("ModuleExample")
+	//     end
+	//     n0 --> n1
+	//     classDef svc_Module fill:#e6194b
+	//     class n1 svc_Module
+}
+
+func Example_impact() {
+	os.Args = []string{
+		os.Args[0],
+		"--impact",
+		"BucketName",
+		"../../../test/templates/success.template",
+	}
+	defer tree.Cmd.Flags().Set("impact", "")
+
+	tree.Cmd.Execute()
+	// Output:
+	// Elements that depend on Parameters/BucketName:
+	//   Resources:
+	//     - Bucket1 (AWS::S3::Bucket) will be replaced if BucketName changes (/Properties/BucketName) - data will be lost!
+}
@@ -69,7 +69,9 @@ func printGraph(g graph.Graph, typeFilter string) {
 			} else {
 				fmt.Println("    DependsOn:")
 				printLinks(fromLinks[el], "Parameters")
+				printLinks(fromLinks[el], "Conditions")
 				printLinks(fromLinks[el], "Resources")
+				printLinks(fromLinks[el], "Imports")
 				printLinks(fromLinks[el], "Outputs")
 			}
 		}
@@ -80,19 +82,48 @@ func printGraph(g graph.Graph, typeFilter string) {
 			} else {
 				fmt.Println("    UsedBy:")
 				printLinks(toLinks[el], "Parameters")
+				printLinks(toLinks[el], "Conditions")
 				printLinks(toLinks[el], "Resources")
+				printLinks(toLinks[el], "Imports")
 				printLinks(toLinks[el], "Outputs")
 			}
 		}
 	}
 }
 
+// printCycles reports circular dependencies, which CloudFormation rejects
+func printCycles(g graph.Graph) {
+	for _, c := range g.Cycles() {
+		fmt.Println(console.Red(fmt.Sprintf("Circular dependency: %s", c)))
+		for _, e := range c {
+			fmt.Printf("  %s\n", e)
+		}
+		fmt.Println(console.Yellow(fmt.Sprintf("  To fix this, %s", c.Suggestion())))
+	}
+}
+
 var dotShapes = map[string]string{
 	"Parameters": "diamond",
+	"Conditions": "hexagon",
 	"Resources":  "Mrecord",
+	"Imports":    "parallelogram",
 	"Outputs":    "rectangle",
 }
 
+// sections are the types of nodes, in the order they are shown
+var sections = []string{"Parameters", "Conditions", "Resources", "Imports", "Outputs"}
+
+// dashed returns true for edges that don't pass a value,
+// which are drawn with a dashed line
+func dashed(e graph.Edge) bool {
+	for _, t := range e.Types {
+		if t != graph.DependsOn && t != graph.Condition {
+			return false
+		}
+	}
+	return len(e.Types) > 0
+}
+
 func printDot(graph graph.Graph) {
 	out := strings.Builder{}
 
@@ -115,17 +146,31 @@ func printDot(graph graph.Graph) {
 		out.WriteString("\n")
 	}
 
-	doGroup("Parameters")
-	doGroup("Resources")
-	doGroup("Outputs")
+	hasNodes := make(map[string]bool)
+	for _, el := range graph.Nodes() {
+		hasNodes[el.Type] = true
+	}
+	for _, section := range sections {
+		// Conditions and Imports are only shown if the template uses them
+		if section == "Conditions" || section == "Imports" {
+			if !hasNodes[section] {
+				continue
+			}
+		}
+		doGroup(section)
+	}
 
 	for _, from := range graph.Nodes() {
 		fromStr := fmt.Sprintf("%s: %s", from.Type, from.Name)
 
-		for _, to := range graph.Get(from) {
-			toStr := fmt.Sprintf("%s: %s", to.Type, to.Name)
+		for _, e := range graph.Edges(from) {
+			toStr := fmt.Sprintf("%s: %s", e.To.Type, e.To.Name)
 
-			out.WriteString(fmt.Sprintf("    \"%s\" -> \"%s\";\n", toStr, fromStr))
+			style := ""
+			if dashed(e) {
+				style = " [style=dashed]"
+			}
+			out.WriteString(fmt.Sprintf("    \"%s\" -> \"%s\"%s;\n", toStr, fromStr, style))
 		}
 	}
 
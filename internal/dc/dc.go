@@ -197,19 +197,10 @@ func ConfigFromStack(stack types.Stack) (string, error) {
 	return string(configFileContent), err
 }
 
-// GetDeployConfig populates an instance of DeployConfig based on user-supplied values
-func GetDeployConfig(
-	tags []string,
-	params []string,
-	configFilePath string,
-	base string,
-	template cft.Template,
-	stack types.Stack,
-	stackExists bool,
-	yes bool,
-	ignoreUnknownParams bool) (*deployconfig.DeployConfig, error) {
-
-	dc := &deployconfig.DeployConfig{}
+// CombineConfig merges the tags and parameters from the config file
+// with the values supplied in --tags and --params. Flags override
+// values in the file.
+func CombineConfig(tags []string, params []string, configFilePath string) (map[string]string, map[string]string) {
 
 	// Parse tags
 	parsedTagFlag := ListToMap("tag", tags)
@@ -238,21 +229,27 @@ func GetDeployConfig(
 		if len(combinedTags) == 0 && len(configFile.LowerTags) > 0 {
 			combinedTags = configFile.LowerTags
 		}
+		if combinedTags == nil {
+			combinedTags = make(map[string]string)
+		}
 		combinedParameters = configFile.Parameters
 		if len(combinedParameters) == 0 && len(configFile.LowerParameters) > 0 {
 			combinedParameters = configFile.LowerParameters
 		}
+		if combinedParameters == nil {
+			combinedParameters = make(map[string]string)
+		}
 
 		for k, v := range parsedTagFlag {
 			if _, ok := combinedTags[k]; ok {
-				fmt.Println(console.Yellow(fmt.Sprintf("tags flag overrides tag in config file: %s", k)))
+				fmt.Fprintln(os.Stderr, console.Yellow(fmt.Sprintf("tags flag overrides tag in config file: %s", k)))
 			}
 			combinedTags[k] = v
 		}
 
 		for k, v := range parsedParamFlag {
 			if _, ok := combinedParameters[k]; ok {
-				fmt.Println(console.Yellow(fmt.Sprintf("params flag overrides parameter in config file: %s", k)))
+				fmt.Fprintln(os.Stderr, console.Yellow(fmt.Sprintf("params flag overrides parameter in config file: %s", k)))
 			}
 			combinedParameters[k] = v
 		}
@@ -261,6 +258,25 @@ func GetDeployConfig(
 		combinedParameters = parsedParamFlag
 	}
 
+	return combinedTags, combinedParameters
+}
+
+// GetDeployConfig populates an instance of DeployConfig based on user-supplied values
+func GetDeployConfig(
+	tags []string,
+	params []string,
+	configFilePath string,
+	base string,
+	template cft.Template,
+	stack types.Stack,
+	stackExists bool,
+	yes bool,
+	ignoreUnknownParams bool) (*deployconfig.DeployConfig, error) {
+
+	dc := &deployconfig.DeployConfig{}
+
+	combinedTags, combinedParameters := CombineConfig(tags, params, configFilePath)
+
 	dc.Tags = combinedTags
 
 	// Parse params
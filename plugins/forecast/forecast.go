@@ -70,6 +70,14 @@ type Check struct {
 	Pass    bool
 	Code    string
 	Message string
+
+	// The fields below are recorded by Forecast.Add so that
+	// results can be written in machine readable formats.
+	// Message already includes them for terminal output.
+	TypeName  string
+	LogicalId string
+	Line      int
+	Detail    string
 }
 
 func (f *Forecast) GetNumChecked() int {
@@ -93,9 +101,13 @@ func (f *Forecast) Append(forecast Forecast) {
 func (f *Forecast) Add(code string, passed bool, message string, lineNumber int) {
 	msg := fmt.Sprintf("%v: %v %v - %v", lineNumber, f.TypeName, f.LogicalId, message)
 	check := Check{
-		Pass:    passed,
-		Code:    code,
-		Message: msg,
+		Pass:      passed,
+		Code:      code,
+		Message:   msg,
+		TypeName:  f.TypeName,
+		LogicalId: f.LogicalId,
+		Line:      lineNumber,
+		Detail:    message,
 	}
 
 	// If we are ignoring this check, don't add it to the forecast